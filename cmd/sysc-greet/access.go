@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Nomadcxx/sysc-greet/internal/access"
+	"github.com/Nomadcxx/sysc-greet/internal/audit"
+	"github.com/Nomadcxx/sysc-greet/internal/secret"
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// access.go - [access] login policy (see internal/access)
+// Users the policy rejects are stopped at the username field, before greetd is
+// contacted, so no PAM conversation is started and no failed attempt is counted
+
+// accessPolicy builds the login policy from the [access] and [users] sections
+func accessPolicy(config Config) access.Policy {
+	a := config.System.Access
+	return access.Policy{
+		Allow:      a.Allow,
+		Deny:       a.Deny,
+		Groups:     a.Groups,
+		MinUID:     a.MinUID,
+		PasswdPath: config.System.Users.Passwd,
+	}
+}
+
+// checkAccess returns the message to show when username may not log in, or ""
+func (m model) checkAccess(username string) string {
+	policy := accessPolicy(m.config)
+	if policy.Empty() {
+		return ""
+	}
+	err := policy.Check(username)
+	if err == nil {
+		return ""
+	}
+
+	e := audit.Event{Event: audit.Failure, User: username, Session: m.auditSessionName(), ErrorType: "access_denied"}
+	var denial access.Denial
+	if errors.As(err, &denial) {
+		greeterLog.Info("Login refused by access policy", "user", username, "reason", denial.Reason)
+		e.Error = denial.Reason
+		m.recordAudit(e)
+		return denial.Error()
+	}
+	// The policy cannot be enforced, so nobody gets in until it can
+	greeterLog.Error("Failed to check access policy", "user", username, "error", err)
+	e.Error = err.Error()
+	m.recordAudit(e)
+	return "Login is unavailable, see the greeter log"
+}
+
+// denyLogin returns to the username field showing msg
+// password is wiped; it never leaves the greeter
+func (m model) denyLogin(msg string, password *secret.Buffer) (model, tea.Cmd) {
+	password.Destroy()
+	m.autologinAt = time.Time{}
+	m.errorMessage = msg
+	m.mode = ModeLogin
+	m.focusState = FocusUsername
+	m.passwordInput.Reset()
+	m.passwordInput.Blur()
+	m.usernameInput.Focus()
+	return m, textinput.Blink
+}
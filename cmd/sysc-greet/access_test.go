@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Nomadcxx/sysc-greet/internal/audit"
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+)
+
+// withAccessPolicy restricts m to accounts with UID 1000 and up, except guest
+func withAccessPolicy(t *testing.T, m model) model {
+	t.Helper()
+	passwd := filepath.Join(t.TempDir(), "passwd")
+	data := "root:x:0:0:root:/root:/bin/bash\n" +
+		"alice:x:1000:1000::/home/alice:/bin/bash\n" +
+		"guest:x:1001:1001::/home/guest:/bin/bash\n"
+	if err := os.WriteFile(passwd, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.config.System.Users.Passwd = passwd
+	m.config.System.Access.MinUID = 1000
+	m.config.System.Access.Deny = []string{"guest"}
+	return m
+}
+
+func TestAccessPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		user         string
+		usernameOnly bool
+		want         string
+	}{
+		{"system account", "root", false, "System accounts cannot log in here"},
+		{"denied user", "guest", false, "guest is not allowed to log in here"},
+		{"unknown user", "mallory", false, "mallory is not allowed to log in here"},
+		{"username-only login", "root", true, "System accounts cannot log in here"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := greetdtest.NewServer(t)
+			m, auditPath := newAuditedModel(t, srv)
+			m = withAccessPolicy(t, m)
+			m.config.UsernameOnly = tt.usernameOnly
+
+			m.usernameInput.SetValue(tt.user)
+			m, _ = update(t, m, keyEnter)
+			if m.mode != ModeLogin || m.focusState != FocusUsername {
+				t.Fatalf("expected to stay at the username field, got mode %s focus %v", m.mode, m.focusState)
+			}
+			if m.errorMessage != tt.want {
+				t.Fatalf("errorMessage = %q, want %q", m.errorMessage, tt.want)
+			}
+			if reqs := srv.Requests(); len(reqs) != 0 {
+				t.Fatalf("expected greetd not to be contacted, got %d requests", len(reqs))
+			}
+			if m.failedAttempts != 0 {
+				t.Fatal("expected a refused login not to count as a failed attempt")
+			}
+
+			events, err := audit.Tail(auditPath, 0)
+			if err != nil {
+				t.Fatalf("audit.Tail: %v", err)
+			}
+			if len(events) != 1 || events[0].Event != audit.Failure || events[0].ErrorType != "access_denied" {
+				t.Fatalf("expected one access_denied failure, got %+v", events)
+			}
+		})
+	}
+}
+
+func TestAccessPolicyAllows(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	m := withAccessPolicy(t, newTestModel(t, srv))
+
+	m, cmd := login(t, m, "alice", "hunter2")
+	m, _ = update(t, m, awaitAuth(t, cmd))
+	if m.errorMessage != "" {
+		t.Fatalf("expected alice to log in, got %q", m.errorMessage)
+	}
+}
+
+func TestAccessPolicyAutologin(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	m := withAccessPolicy(t, newTestModel(t, srv))
+
+	m, _ = m.submitLogin("root", nil)
+	if m.mode != ModeLogin || m.authActive || m.errorMessage != "System accounts cannot log in here" {
+		t.Fatalf("expected autologin of root to be refused, got mode %s error %q", m.mode, m.errorMessage)
+	}
+	if reqs := srv.Requests(); len(reqs) != 0 {
+		t.Fatalf("expected greetd not to be contacted, got %d requests", len(reqs))
+	}
+}
+
+func TestUserPickerHonoursPolicy(t *testing.T) {
+	m := withAccessPolicy(t, newTestModel(t, greetdtest.NewServer(t)))
+	m.config.UserList = true
+
+	list := loadUserList(m.config)
+	if len(list) != 1 || list[0].Name != "alice" {
+		t.Fatalf("expected only alice in the picker, got %+v", list)
+	}
+}
+
+func TestBrokenConfigAllowsLogins(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	m := newTestModel(t, srv)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"config.toml": "[access]\nallow = [\"alice\"]\n\n[greeter]\ndebug = tru\n"})
+	m.config.ConfigPath = filepath.Join(dir, "config.toml")
+	system, _ := sysconfig.Load(m.config.ConfigPath)
+	m.config.System = system
+
+	if got := m.brokenConfigWarning(); got != "Config not applied: config.toml, see the greeter log" {
+		t.Fatalf("expected the broken file to be named in the form, got %q", got)
+	}
+
+	m, cmd := m.submitLogin("alice", nil)
+	if m.mode != ModeLoading || !m.authActive || m.errorMessage != "" {
+		t.Fatalf("expected the login to go ahead, got mode %s error %q", m.mode, m.errorMessage)
+	}
+	awaitAuth(t, cmd)
+	if reqs := srv.Requests(); len(reqs) == 0 || reqs[0].Type != ipc.CreateSessionRequest {
+		t.Fatalf("expected greetd to be contacted, got %+v", reqs)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Nomadcxx/sysc-greet/internal/animations"
 	"github.com/charmbracelet/lipgloss/v2"
@@ -213,7 +214,7 @@ func (m model) getSessionASCII() string {
 		visibleLines := m.printEffect.GetVisibleLines()
 		currentASCII = strings.Join(visibleLines, "\n")
 		if m.config.Debug && len(visibleLines) > 0 {
-			logDebug("Print effect rendering %d lines (complete: %v)", len(visibleLines), m.printEffect.IsComplete())
+			animationLog.Debug("Print effect rendering", "lines", len(visibleLines), "complete", m.printEffect.IsComplete())
 		}
 	}
 
@@ -930,3 +931,106 @@ func getCustomRoastsForSession(sessionName string) string {
 	}
 	return ""
 }
+
+// initASCIIEffects creates the ticker/print/beams/pour effect for the selected
+// session and background (at startup and when a user's preferences are restored)
+func (m *model) initASCIIEffects() {
+	if m.selectedSession == nil {
+		return
+	}
+
+	sessionName := strings.ToLower(strings.Fields(m.selectedSession.Name)[0])
+	var configFileName string
+	switch sessionName {
+	case "gnome":
+		configFileName = "gnome_desktop"
+	case "i3":
+		configFileName = "i3wm"
+	case "bspwm":
+		configFileName = "bspwm_manager"
+	case "plasma":
+		configFileName = "kde"
+	case "xmonad":
+		configFileName = "xmonad"
+	default:
+		configFileName = sessionName
+	}
+	configPath := fmt.Sprintf("%s/ascii_configs/%s.conf", dataDir, configFileName)
+
+	switch m.selectedBackground {
+	case "ticker":
+		customRoasts := ""
+		if asciiConfig, err := loadASCIIConfig(configPath); err == nil {
+			customRoasts = asciiConfig.Roasts
+		}
+		m.typewriterTicker = animations.NewTypewriterTicker(m.selectedSession.Name, customRoasts)
+	case "print":
+		if asciiConfig, err := loadASCIIConfig(configPath); err == nil && len(asciiConfig.ASCIIVariants) > 0 {
+			variantIndex := m.asciiArtIndex
+			if variantIndex >= len(asciiConfig.ASCIIVariants) {
+				variantIndex = 0
+			}
+			ascii := asciiConfig.ASCIIVariants[variantIndex]
+			m.printEffect = animations.NewPrintEffect(ascii, time.Millisecond*3)
+		}
+	case "beams":
+		if asciiConfig, err := loadASCIIConfig(configPath); err == nil && len(asciiConfig.ASCIIVariants) > 0 {
+			variantIndex := m.asciiArtIndex
+			if variantIndex >= len(asciiConfig.ASCIIVariants) {
+				variantIndex = 0
+			}
+			ascii := asciiConfig.ASCIIVariants[variantIndex]
+			beamColors, finalColors := getThemeColorsForBeams(m.currentTheme)
+			lines := strings.Split(ascii, "\n")
+			asciiHeight := len(lines)
+			asciiWidth := 0
+			for _, line := range lines {
+				if len([]rune(line)) > asciiWidth {
+					asciiWidth = len([]rune(line))
+				}
+			}
+			m.beamsEffect = animations.NewBeamsTextEffect(animations.BeamsTextConfig{
+				Width:              asciiWidth,
+				Height:             asciiHeight,
+				Text:               ascii,
+				BeamGradientStops:  beamColors,
+				FinalGradientStops: finalColors,
+			})
+		}
+	case "pour":
+		if asciiConfig, err := loadASCIIConfig(configPath); err == nil && len(asciiConfig.ASCIIVariants) > 0 {
+			variantIndex := m.asciiArtIndex
+			if variantIndex >= len(asciiConfig.ASCIIVariants) {
+				variantIndex = 0
+			}
+			ascii := asciiConfig.ASCIIVariants[variantIndex]
+			pourColors := getThemeColorsForPour(m.currentTheme)
+			lines := strings.Split(ascii, "\n")
+			asciiHeight := len(lines)
+			asciiWidth := 0
+			for _, line := range lines {
+				if len([]rune(line)) > asciiWidth {
+					asciiWidth = len([]rune(line))
+				}
+			}
+			m.pourEffect = animations.NewPourEffect(animations.PourConfig{
+				Width:                  asciiWidth,
+				Height:                 asciiHeight,
+				Text:                   ascii,
+				PourDirection:          "down",
+				PourSpeed:              1,
+				MovementSpeed:          0.05,
+				Gap:                    2,
+				StartingColor:          "#ffffff",
+				FinalGradientStops:     pourColors,
+				FinalGradientSteps:     12,
+				FinalGradientFrames:    5,
+				FinalGradientDirection: "horizontal",
+			})
+		}
+	case "aquarium":
+		// Leave m.aquariumEffect = nil, will initialize lazily in tick
+	default:
+		// For gslapper wallpapers, don't launch yet - wait for compositor in WindowSizeMsg
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Nomadcxx/sysc-greet/internal/audit"
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc"
+)
+
+// audit.go - login audit trail (see internal/audit) and the "audit" subcommand
+
+// openAudit opens the audit log configured in the [audit] section
+// Returns nil when auditing is disabled
+func openAudit(config Config) (*audit.Logger, error) {
+	a := config.System.Audit
+	if !a.Enabled || config.TestMode {
+		return nil, nil
+	}
+	return audit.Open(audit.Options{
+		Path:          a.Path,
+		HashUsernames: a.HashUsernames,
+		Syslog:        a.Syslog,
+	})
+}
+
+// auditSessionName is the chosen session recorded with an event
+func (m model) auditSessionName() string {
+	if m.selectedSession == nil {
+		return ""
+	}
+	return m.selectedSession.Name
+}
+
+// auditAttempt records that username was submitted to greetd
+func (m model) auditAttempt(username string) {
+	m.recordAudit(audit.Event{Event: audit.Attempt, User: username, Session: m.auditSessionName()})
+}
+
+// auditSuccess records that the session of the user in the form was started
+func (m model) auditSuccess() {
+	m.recordAudit(audit.Event{Event: audit.Success, User: m.usernameInput.Value(), Session: m.auditSessionName()})
+}
+
+// auditFailure records a failed conversation, with greetd's error type when it sent one
+func (m model) auditFailure(err error) {
+	e := audit.Event{Event: audit.Failure, User: m.usernameInput.Value(), Session: m.auditSessionName()}
+	var ae authError
+	var ie ipc.Error
+	switch {
+	case errors.As(err, &ae):
+		e.ErrorType, e.Error = ae.ErrorType, ae.Description
+	case errors.As(err, &ie):
+		e.ErrorType, e.Error = ie.ErrorType, ie.Description
+	default:
+		e.Error = err.Error()
+	}
+	m.recordAudit(e)
+}
+
+// auditCancel records that the running conversation was abandoned
+func (m model) auditCancel() {
+	if !m.authActive {
+		return
+	}
+	m.recordAudit(audit.Event{Event: audit.Cancel, User: m.authUser, Session: m.auditSessionName()})
+}
+
+// recordAudit writes e to the audit log
+// A failing audit log is reported but never blocks the login
+func (m model) recordAudit(e audit.Event) {
+	if err := m.config.Audit.Record(e); err != nil {
+		logWarn("Failed to record audit event: %v", err)
+	}
+}
+
+// runAuditCommand implements "sysc-greet audit tail [-n N] [-f] [-json]"
+func runAuditCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "tail" {
+		fmt.Fprintf(stderr, "Usage: sysc-greet audit tail [-n N] [-f] [-json] [-file PATH]\n")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("audit tail", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	n := fs.Int("n", 20, "Number of events to show (0 shows all)")
+	follow := fs.Bool("f", false, "Keep printing new events as they are recorded")
+	asJSON := fs.Bool("json", false, "Print events as JSON lines")
+	configPath := fs.String("config", sysconfig.DefaultPath, "System configuration file")
+	path := fs.String("file", "", "Audit log (default from the [audit] section)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if *path == "" {
+		system, err := sysconfig.Load(*configPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Warning: %v\n", err)
+		}
+		*path = system.Audit.Path
+		if *path == "" {
+			*path = audit.DefaultPath
+		}
+	}
+
+	// CHANGED 2026-10-16 - Read the log once and then only what is appended to it
+	follower, err := audit.Follow(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer follower.Close()
+
+	events, err := follower.Next()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *n > 0 && len(events) > *n {
+		events = events[len(events)-*n:]
+	}
+	printAuditEvents(stdout, events, *asJSON)
+	if !*follow {
+		return 0
+	}
+
+	for {
+		time.Sleep(time.Second)
+		events, err := follower.Next()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		printAuditEvents(stdout, events, *asJSON)
+	}
+}
+
+// printAuditEvents writes events one per line
+func printAuditEvents(w io.Writer, events []audit.Event, asJSON bool) {
+	for _, e := range events {
+		if asJSON {
+			line, _ := json.Marshal(e)
+			fmt.Fprintf(w, "%s\n", line)
+			continue
+		}
+		fmt.Fprintln(w, e)
+	}
+}
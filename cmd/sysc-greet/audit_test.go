@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nomadcxx/sysc-greet/internal/audit"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// newAuditedModel builds a test model that records to a temporary audit log
+func newAuditedModel(t *testing.T, srv *greetdtest.Server) (model, string) {
+	t.Helper()
+	m := newTestModel(t, srv)
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := audit.Open(audit.Options{Path: path})
+	if err != nil {
+		t.Fatalf("audit.Open: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	m.config.Audit = l
+	return m, path
+}
+
+func TestAuditTrail(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		failExec bool
+		want     []audit.Event
+	}{
+		{
+			name:     "success",
+			password: "hunter2",
+			want: []audit.Event{
+				{Event: audit.Attempt, User: "alice", Session: "Sway"},
+				{Event: audit.Success, User: "alice", Session: "Sway"},
+			},
+		},
+		{
+			name:     "wrong password",
+			password: "wrong",
+			want: []audit.Event{
+				{Event: audit.Attempt, User: "alice", Session: "Sway"},
+				{Event: audit.Failure, User: "alice", Session: "Sway", ErrorType: "auth_error", Error: "Authentication failure"},
+			},
+		},
+		{
+			name:     "session fails to start",
+			password: "hunter2",
+			failExec: true,
+			want: []audit.Event{
+				{Event: audit.Attempt, User: "alice", Session: "Sway"},
+				{Event: audit.Failure, User: "alice", Session: "Sway", ErrorType: "error", Error: "exec failed: sway not found"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := greetdtest.NewServer(t)
+			srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+			if tt.failExec {
+				srv.FailStartSession("error", "exec failed: sway not found")
+			}
+			m, path := newAuditedModel(t, srv)
+
+			m, cmd := login(t, m, "alice", tt.password)
+			update(t, m, awaitAuth(t, cmd))
+
+			events, err := audit.Tail(path, 0)
+			if err != nil {
+				t.Fatalf("Tail: %v", err)
+			}
+			if len(events) != len(tt.want) {
+				t.Fatalf("expected %d events, got %+v", len(tt.want), events)
+			}
+			for i, e := range events {
+				if e.Time.IsZero() {
+					t.Errorf("event %d has no timestamp", i)
+				}
+				e.Time = tt.want[i].Time
+				if e != tt.want[i] {
+					t.Errorf("event %d: expected %+v, got %+v", i, tt.want[i], e)
+				}
+			}
+		})
+	}
+}
+
+func TestAuditCancel(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel func(m *model)
+	}{
+		{"esc", func(m *model) {
+			newModel, _ := m.Update(tea.KeyPressMsg{Code: tea.KeyEsc})
+			*m = newModel.(model)
+		}},
+		{"screensaver", func(m *model) {
+			m.config.System.Idle.ForgetUsername = true
+			m.resetForScreensaver()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := greetdtest.NewServer(t)
+			srv.AddUser("bob",
+				greetdtest.Secret("Password:", "hunter2"),
+				greetdtest.Visible("Verification code:", "123456"),
+			)
+			m, path := newAuditedModel(t, srv)
+
+			m, cmd := login(t, m, "bob", "hunter2")
+			m, _ = update(t, m, awaitAuth(t, cmd))
+			tt.cancel(&m)
+			if m.authActive {
+				t.Fatal("expected the conversation to be abandoned")
+			}
+
+			events, err := audit.Tail(path, 0)
+			if err != nil {
+				t.Fatalf("Tail: %v", err)
+			}
+			want := audit.Event{Event: audit.Cancel, User: "bob", Session: "Sway"}
+			if len(events) != 2 || events[1].Event != want.Event || events[1].User != want.User || events[1].Session != want.Session {
+				t.Fatalf("expected an attempt and %+v, got %+v", want, events)
+			}
+		})
+	}
+}
+
+func TestAuditTailCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := audit.Open(audit.Options{Path: path})
+	if err != nil {
+		t.Fatalf("audit.Open: %v", err)
+	}
+	l.Record(audit.Event{Event: audit.Attempt, User: "alice", Session: "Sway"})
+	l.Record(audit.Event{Event: audit.Failure, User: "alice", Session: "Sway", ErrorType: "auth_error", Error: "Authentication failed"})
+	l.Record(audit.Event{Event: audit.Attempt, User: "bob", Session: "Hyprland"})
+	l.Close()
+
+	var stdout, stderr bytes.Buffer
+	if code := runAuditCommand([]string{"tail", "-n", "2", "-file", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", stdout.String())
+	}
+	if !strings.Contains(lines[0], "failure") || !strings.Contains(lines[0], "error_type=auth_error") {
+		t.Errorf("unexpected line: %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "bob") || !strings.Contains(lines[1], "session=Hyprland") {
+		t.Errorf("unexpected line: %q", lines[1])
+	}
+
+	stdout.Reset()
+	if code := runAuditCommand([]string{"tail", "-json", "-file", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	events, _ := audit.Read(&stdout)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 JSON events, got %d", len(events))
+	}
+
+	if code := runAuditCommand([]string{"head"}, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected usage error, got %d", code)
+	}
+}
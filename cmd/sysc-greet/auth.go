@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc"
+	"github.com/Nomadcxx/sysc-greet/internal/secret"
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// auth.go - greetd authentication conversation
+// Drives the full PAM conversation (password, OTP, U2F notices, expired passwords)
+// instead of assuming a single password prompt followed by Success
+
+// authMessageMsg carries a greetd auth message that needs the UI (a prompt or a notice)
+type authMessageMsg ipc.AuthMessage
+
+// authCancelledMsg reports that the in-flight greetd request was cancelled by the user
+type authCancelledMsg struct{}
+
+// authNoticeMsg is an info or error message that arrived before the first
+// secret prompt (a MOTD, "password expires in N days"). It carries the password
+// typed into the form on to that prompt
+type authNoticeMsg struct {
+	notice   ipc.AuthMessage
+	password *secret.Buffer
+}
+
+// authNotice is an info or error message shown in the form during the conversation
+type authNotice struct {
+	Text    string
+	IsError bool
+}
+
+// submitLogin switches to the loading view and starts authenticating username
+// password is nil for username-only logins and autologin, leaving every PAM prompt to the UI
+func (m model) submitLogin(username string, password *secret.Buffer) (model, tea.Cmd) {
+	if m.config.Debug {
+		// SECURITY: Never log passwords - the username attribute is redacted by the logger
+		greeterLog.Debug("Authentication attempt", "user", username)
+	}
+	if msg := m.checkAccess(username); msg != "" {
+		return m.denyLogin(msg, password)
+	}
+	if m.config.TestMode {
+		greeterLog.Info("Test mode: auth successful")
+		password.Destroy()
+		return m, tea.Quit
+	}
+	if m.ipcClient == nil {
+		greeterLog.Error("No IPC client available")
+		password.Destroy()
+		return m, tea.Quit
+	}
+	m.autologinAt = time.Time{}
+	m.authNotices = nil
+	m.mode = ModeLoading
+
+	// One context per conversation so Esc can abort a request greetd never answers
+	m.authCtx, m.authCancel = context.WithCancel(context.Background())
+	m.authActive = true
+	m.authUser = username
+	return m, m.authenticate(username, password)
+}
+
+// authenticate creates a greetd session for username and runs the conversation
+// A non-nil password answers the first secret prompt; any further prompts are
+// handed back to the UI as authMessageMsg. If greetd answers CreateSession with
+// Success (pam_permit, fingerprint, passwordless kiosk users) the session starts directly
+// The conversation owns password and wipes it however it ends (see converse)
+func (m model) authenticate(username string, password *secret.Buffer) tea.Cmd {
+	return func() tea.Msg {
+		// CHANGED 2025-10-05 - Add nil check for IPC client
+		if m.ipcClient == nil {
+			password.Destroy()
+			return fmt.Errorf("IPC client not initialized - greeter must be run by greetd")
+		}
+
+		m.auditAttempt(username)
+
+		// Create session
+		resp, err := m.ipcClient.CreateSessionContext(m.authContext(), username)
+		if err != nil {
+			password.Destroy()
+			// The request may have reached greetd before the failure
+			m.ipcClient.CancelSession()
+			return m.authFailure(err)
+		}
+
+		return m.converse(resp, password)
+	}
+}
+
+// respondToAuthMessage posts the answer to the current prompt and continues the conversation
+// The response is wiped once sent
+func (m model) respondToAuthMessage(response *secret.Buffer) tea.Cmd {
+	return func() tea.Msg {
+		defer response.Destroy()
+		if m.ipcClient == nil {
+			return fmt.Errorf("IPC client not initialized - greeter must be run by greetd")
+		}
+
+		resp, err := m.ipcClient.PostSecretResponseContext(m.authContext(), response.Bytes())
+		response.Wipe()
+		if err != nil {
+			// Cancel session on error
+			m.ipcClient.CancelSession()
+			return m.authFailure(err)
+		}
+
+		return m.converse(resp, nil)
+	}
+}
+
+// acknowledgeAuthMessage acknowledges an info or error message and continues
+// the conversation. password, when non-nil, still answers the first secret
+// prompt; like authenticate, the conversation owns it
+func (m model) acknowledgeAuthMessage(password *secret.Buffer) tea.Cmd {
+	return func() tea.Msg {
+		if m.ipcClient == nil {
+			password.Destroy()
+			return fmt.Errorf("IPC client not initialized - greeter must be run by greetd")
+		}
+
+		resp, err := m.ipcClient.PostAuthMessageResponseContext(m.authContext(), nil)
+		if err != nil {
+			password.Destroy()
+			m.ipcClient.CancelSession()
+			return m.authFailure(err)
+		}
+
+		return m.converse(resp, password)
+	}
+}
+
+// cancelAuth cancels the greetd session of an abandoned conversation
+func (m model) cancelAuth() tea.Cmd {
+	client := m.ipcClient
+	return func() tea.Msg {
+		if client != nil {
+			if err := client.CancelSession(); err != nil {
+				logWarn("Failed to cancel session: %v", err)
+			}
+		}
+		return nil
+	}
+}
+
+// converse follows greetd's responses, starting with resp, until the conversation
+// needs the user or finishes
+// password, when non-nil, is used to answer the first secret prompt without asking
+// and is wiped right after it is sent. Notices before that prompt hand it back
+// to the UI with the notice; any other end of the conversation destroys it
+func (m model) converse(resp interface{}, password *secret.Buffer) tea.Msg {
+	defer func() { password.Destroy() }()
+	for {
+		switch r := resp.(type) {
+		case ipc.Error:
+			// CHANGED 2025-10-05 - Handle Error response (wrong password)
+			m.ipcClient.CancelSession()
+			return authError{ErrorType: r.ErrorType, Description: r.Description}
+
+		case ipc.Success:
+			// PAM conversation complete
+			return m.startSession()
+
+		case ipc.AuthMessage:
+			if m.config.Debug {
+				logDebug(" Received auth message (%s)", r.AuthMessageType)
+			}
+			if r.AuthMessageType == ipc.AuthMessageSecret && password != nil {
+				// Answer with the password already typed into the form
+				var err error
+				resp, err = m.ipcClient.PostSecretResponseContext(m.authContext(), password.Bytes())
+				password.Destroy()
+				password = nil
+				if err != nil {
+					// Cancel session on error (the client re-dials if the connection was dropped)
+					m.ipcClient.CancelSession()
+					return m.authFailure(err)
+				}
+				continue
+			}
+			// FIXED 2026-10-16 - A notice before the password prompt no longer
+			// drops the password the user typed; the UI shows the notice and
+			// acknowledges it with the password (see handleAuthMessage)
+			if password != nil && (r.AuthMessageType == ipc.AuthMessageInfo || r.AuthMessageType == ipc.AuthMessageError) {
+				msg := authNoticeMsg{notice: r, password: password}
+				password = nil
+				return msg
+			}
+			return authMessageMsg(r)
+
+		default:
+			// Cancel session on unexpected response
+			m.ipcClient.CancelSession()
+			return fmt.Errorf("unexpected response from greetd: %T", resp)
+		}
+	}
+}
+
+// startSession asks greetd to start the selected session once authentication succeeded
+func (m model) startSession() tea.Msg {
+	if m.selectedSession == nil {
+		// Cancel session if no session selected
+		m.ipcClient.CancelSession()
+		return fmt.Errorf("no session selected")
+	}
+
+	// CHANGED 2026-10-16 - The sway --unsupported-gpu fix (2026-01-17) is now a
+	// default of [session_args] applied when the NVIDIA driver is loaded, and X11
+	// sessions get an X server (see launch.go)
+	cmd, err := m.launchCommand()
+	if err != nil {
+		m.ipcClient.CancelSession()
+		return err
+	}
+	env := m.sessionEnvironment()
+	if err := m.ipcClient.StartSessionContext(m.authContext(), cmd, env); err != nil {
+		// Cancel session on StartSession failure
+		m.ipcClient.CancelSession()
+		return m.authFailure(err)
+	}
+	return "success"
+}
+
+// sessionEnvironment returns the environment for the selected session: the XDG
+// variables derived from its desktop file plus the admin's overrides from config
+func (m model) sessionEnvironment() []string {
+	session := m.selectedSession
+	overrides := m.config.System.SessionEnvironment(session.ID(), session.Name)
+	env := sysconfig.MergeEnv(session.Environment(), overrides)
+	if m.config.Debug {
+		// FIXED 2026-10-16 - Names only: values can hold tokens set in [session_env]
+		greeterLog.Debug("Session environment", slog.Any("vars", envNames(env)))
+	}
+	return env
+}
+
+// envNames returns the variable names of env, without their values
+func envNames(env []string) []string {
+	names := make([]string, len(env))
+	for i, kv := range env {
+		names[i], _, _ = strings.Cut(kv, "=")
+	}
+	return names
+}
+
+// authContext returns the context of the running conversation
+func (m model) authContext() context.Context {
+	if m.authCtx == nil {
+		return context.Background()
+	}
+	return m.authCtx
+}
+
+// authFailure turns an IPC error into the message sent to the UI
+// User cancellation is reported separately so it is not shown as a failed login
+func (m model) authFailure(err error) tea.Msg {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return authCancelledMsg{}
+	case errors.Is(err, ipc.ErrTimeout):
+		return fmt.Errorf("greetd did not respond in time - press Enter to retry")
+	case errors.Is(err, ipc.ErrConnectionLost):
+		return fmt.Errorf("lost connection to greetd - reconnecting on next attempt")
+	}
+	return err
+}
+
+// handleAuthMessage updates the form for a message received mid-conversation
+// password is the one typed into the form when it has not been sent yet; it
+// is passed on with the acknowledgement of a notice
+func (m model) handleAuthMessage(msg ipc.AuthMessage, password *secret.Buffer) (model, tea.Cmd) {
+	m.authActive = true
+
+	switch msg.AuthMessageType {
+	case ipc.AuthMessageInfo, ipc.AuthMessageError:
+		// Show the notice and acknowledge it - greetd waits for the ack before PAM continues
+		// (e.g. pam_u2f shows "touch your device" and then blocks until touched)
+		text := strings.TrimSpace(msg.AuthMessage)
+		if text != "" {
+			m.noteLockout(text)
+			m.notePasswordExpiry(text)
+			m.authNotices = append(m.authNotices, authNotice{
+				Text:    text,
+				IsError: msg.AuthMessageType == ipc.AuthMessageError,
+			})
+		}
+		m.mode = ModeLoading
+		return m, m.acknowledgeAuthMessage(password)
+
+	default:
+		// Only notices come with a password (see converse)
+		password.Destroy()
+
+		// Expired passwords are changed in their own view
+		prompt := strings.TrimSpace(msg.AuthMessage)
+		if msg.AuthMessageType == ipc.AuthMessageSecret && m.isPasswordChangePrompt(prompt) {
+			return m.handlePasswordChangePrompt(prompt)
+		}
+
+		// Visible and secret prompts are answered through the password field
+		m.authPrompt = prompt
+		m.passwordInput.Echo = msg.AuthMessageType == ipc.AuthMessageVisible
+		m.passwordInput.Reset()
+		m.mode = ModePassword
+		m.focusState = FocusPassword
+		m.usernameInput.Blur()
+		m.passwordInput.Focus()
+		return m, textinput.Blink
+	}
+}
+
+// resetAuthState clears conversation state after success, failure or cancellation
+func (m *model) resetAuthState() {
+	if m.authCancel != nil {
+		m.authCancel()
+	}
+	m.authCtx = nil
+	m.authCancel = nil
+	m.authActive = false
+	m.authUser = ""
+	m.authPrompt = ""
+	m.authNotices = nil
+	m.lockout = nil
+	m.resetPasswordChange()
+	m.passwordInput.Echo = false
+}
+
+// cancelLoading aborts the in-flight greetd request and returns to the form
+// The conversation goroutine then cancels the greetd session itself
+func (m model) cancelLoading() (model, tea.Cmd) {
+	logDebug("Authentication cancelled while waiting for greetd")
+	m.auditCancel()
+	m.resetAuthState()
+	m.errorMessage = "Authentication cancelled"
+	m.mode = ModeLogin
+	m.focusState = FocusUsername
+	m.passwordInput.Reset()
+	m.usernameInput.Focus()
+	m.passwordInput.Blur()
+	return m, textinput.Blink
+}
+
+// abortAuth abandons an in-progress conversation and cancels the greetd session
+// CHANGED 2026-10-16 - Abandoned conversations are recorded in the audit trail
+func (m model) abortAuth() (model, tea.Cmd) {
+	if !m.authActive {
+		return m, nil
+	}
+	m.auditCancel()
+	cmd := m.cancelAuth()
+	m.resetAuthState()
+	return m, cmd
+}
+
+// authPromptLabel returns the label for the password field
+// PAM prompts replace the default "Password:" label while a conversation is running
+func (m model) authPromptLabel() string {
+	if m.authPrompt == "" {
+		return "Password:"
+	}
+	label := strings.TrimSpace(m.authPrompt)
+	if !strings.HasSuffix(label, ":") {
+		label += ":"
+	}
+	return label
+}
+
+// startAutologin arms the autologin countdown for the configured user
+func (m *model) startAutologin() {
+	if m.config.AutologinUser == "" {
+		return
+	}
+	m.usernameInput.SetValue(m.config.AutologinUser)
+	m.mode = ModeLogin
+	m.focusState = FocusUsername
+	m.usernameInput.Focus()
+	m.passwordInput.Blur()
+	m.autologinAt = time.Now().Add(time.Duration(m.config.AutologinDelay) * time.Second)
+	greeterLog.Debug("Autologin armed", "user", m.config.AutologinUser, "delay", m.config.AutologinDelay)
+}
+
+// autologinDue reports whether the autologin countdown has expired
+func (m model) autologinDue(now time.Time) bool {
+	return !m.autologinAt.IsZero() && !now.Before(m.autologinAt) && m.mode == ModeLogin
+}
+
+// autologin starts the login the expired countdown was armed for
+func (m model) autologin() (model, tea.Cmd) {
+	if m.guestSelected() {
+		return m.startGuest()
+	}
+	return m.submitLogin(m.config.AutologinUser, nil)
+}
+
+// autologinRemaining returns the whole seconds left on the autologin countdown
+func (m model) autologinRemaining() int {
+	remaining := time.Until(m.autologinAt)
+	if remaining < 0 {
+		return 0
+	}
+	return int((remaining + time.Second - 1) / time.Second)
+}
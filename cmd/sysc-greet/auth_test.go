@@ -0,0 +1,502 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nomadcxx/sysc-greet/internal/cache"
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+var keyEnter = tea.KeyPressMsg{Code: tea.KeyEnter}
+
+// newTestModel builds a greeter model connected to srv
+// The model is created in test mode (no wallpapers, no cache) and then switched to
+// production mode so authentication goes through greetd
+func newTestModel(t *testing.T, srv *greetdtest.Server) model {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GREETD_SOCK", srv.SocketPath())
+
+	m := initialModel(Config{TestMode: true}, false)
+	client, err := ipc.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	m.ipcClient = client
+	m.config.TestMode = false
+	m.selectedSession = &sessions.Session{Name: "Sway", Exec: "sway", Type: "Wayland"}
+	return m
+}
+
+// update feeds msg to the model
+func update(t *testing.T, m model, msg tea.Msg) (model, tea.Cmd) {
+	t.Helper()
+	newModel, cmd := m.Update(msg)
+	return newModel.(model), cmd
+}
+
+// awaitAuth runs cmd (expanding batches) and returns the first message produced
+// by the authentication flow: an authMessageMsg, authNoticeMsg, authCancelledMsg,
+// "success" or an error
+func awaitAuth(t *testing.T, cmd tea.Cmd) tea.Msg {
+	t.Helper()
+
+	msgs := make(chan tea.Msg, 64)
+	var run func(tea.Cmd)
+	run = func(c tea.Cmd) {
+		if c == nil {
+			return
+		}
+		go func() {
+			msg := c()
+			if batch, ok := msg.(tea.BatchMsg); ok {
+				for _, c := range batch {
+					run(c)
+				}
+				return
+			}
+			select {
+			case msgs <- msg:
+			default:
+			}
+		}()
+	}
+	run(cmd)
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-msgs:
+			switch msg.(type) {
+			case authMessageMsg, authNoticeMsg, authCancelledMsg, string, error:
+				return msg
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for authentication result")
+			return nil
+		}
+	}
+}
+
+// login types username and password into the form and submits it
+func login(t *testing.T, m model, username, password string) (model, tea.Cmd) {
+	t.Helper()
+	m.usernameInput.SetValue(username)
+	m, _ = update(t, m, keyEnter)
+	if m.mode != ModePassword {
+		t.Fatalf("expected password mode after username, got %s", m.mode)
+	}
+	setSecret(m.passwordInput, password)
+	m, cmd := update(t, m, keyEnter)
+	if m.mode != ModeLoading {
+		t.Fatalf("expected loading mode after password, got %s", m.mode)
+	}
+	return m, cmd
+}
+
+// setSecret replaces the text of a password field
+func setSecret(input secretInput, text string) {
+	input.Reset()
+	input.insert(text)
+}
+
+func lastRequest(t *testing.T, srv *greetdtest.Server) greetdtest.Request {
+	t.Helper()
+	reqs := srv.Requests()
+	if len(reqs) == 0 {
+		t.Fatal("no requests received")
+	}
+	return reqs[len(reqs)-1]
+}
+
+func TestLoginSuccess(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	m := newTestModel(t, srv)
+
+	m, cmd := login(t, m, "alice", "hunter2")
+	msg := awaitAuth(t, cmd)
+	if msg != "success" {
+		t.Fatalf("expected success, got %v", msg)
+	}
+
+	req := lastRequest(t, srv)
+	if req.Type != ipc.StartSessionRequest {
+		t.Fatalf("expected start_session, got %s", req.Type)
+	}
+	if len(req.Cmd) == 0 || req.Cmd[0] != "sway" {
+		t.Fatalf("unexpected session command: %v", req.Cmd)
+	}
+
+	m, _ = update(t, m, msg)
+	if m.failedAttempts != 0 {
+		t.Fatalf("expected failed attempts to be reset, got %d", m.failedAttempts)
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	m := newTestModel(t, srv)
+
+	m, cmd := login(t, m, "alice", "wrong")
+	msg := awaitAuth(t, cmd)
+	if _, ok := msg.(error); !ok {
+		t.Fatalf("expected error, got %v", msg)
+	}
+
+	m, _ = update(t, m, msg)
+	if m.mode != ModePassword {
+		t.Fatalf("expected password mode after failure, got %s", m.mode)
+	}
+	if m.failedAttempts != 1 {
+		t.Fatalf("expected 1 failed attempt, got %d", m.failedAttempts)
+	}
+	if m.errorMessage != "Login incorrect" {
+		t.Fatalf("unexpected error message: %q", m.errorMessage)
+	}
+	if m.usernameInput.Value() != "alice" {
+		t.Fatalf("expected username to be kept, got %q", m.usernameInput.Value())
+	}
+	if m.passwordInput.Len() != 0 {
+		t.Fatal("expected password to be cleared")
+	}
+	if req := lastRequest(t, srv); req.Type != ipc.CancelSessionRequest {
+		t.Fatalf("expected cancel_session after failure, got %s", req.Type)
+	}
+}
+
+func TestMultiPromptConversation(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("bob",
+		greetdtest.Secret("Password:", "hunter2"),
+		greetdtest.Info("Open your authenticator app"),
+		greetdtest.Visible("Verification code:", "123456"),
+	)
+	m := newTestModel(t, srv)
+
+	m, cmd := login(t, m, "bob", "hunter2")
+
+	// Info notice is shown and acknowledged automatically
+	msg := awaitAuth(t, cmd)
+	if am, ok := msg.(authMessageMsg); !ok || am.AuthMessageType != ipc.AuthMessageInfo {
+		t.Fatalf("expected info message, got %v", msg)
+	}
+	m, cmd = update(t, m, msg)
+	if m.mode != ModeLoading {
+		t.Fatalf("expected loading mode while acknowledging info, got %s", m.mode)
+	}
+	if len(m.authNotices) != 1 || m.authNotices[0].Text != "Open your authenticator app" {
+		t.Fatalf("unexpected notices: %+v", m.authNotices)
+	}
+
+	// Visible prompt takes over the password field with echo enabled
+	msg = awaitAuth(t, cmd)
+	if am, ok := msg.(authMessageMsg); !ok || am.AuthMessageType != ipc.AuthMessageVisible {
+		t.Fatalf("expected visible prompt, got %v", msg)
+	}
+	m, _ = update(t, m, msg)
+	if m.mode != ModePassword {
+		t.Fatalf("expected password mode for prompt, got %s", m.mode)
+	}
+	if m.authPromptLabel() != "Verification code:" {
+		t.Fatalf("unexpected prompt label: %q", m.authPromptLabel())
+	}
+	if !m.passwordInput.Echo {
+		t.Fatal("expected visible prompt to echo input")
+	}
+
+	setSecret(m.passwordInput, "123456")
+	m, cmd = update(t, m, keyEnter)
+	msg = awaitAuth(t, cmd)
+	if msg != "success" {
+		t.Fatalf("expected success, got %v", msg)
+	}
+
+	m, _ = update(t, m, msg)
+	if m.authActive || m.passwordInput.Echo {
+		t.Fatal("expected conversation state to be reset")
+	}
+}
+
+func TestNoticeBeforePasswordPrompt(t *testing.T) {
+	tests := []struct {
+		name   string
+		notice greetdtest.Step
+	}{
+		{"motd", greetdtest.Info("Welcome to the lab")},
+		{"expiry warning", greetdtest.ErrorMessage("Warning: your password will expire in 3 days")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := greetdtest.NewServer(t)
+			srv.AddUser("alice", tt.notice, greetdtest.Secret("Password:", "hunter2"))
+			m := newTestModel(t, srv)
+
+			m, cmd := login(t, m, "alice", "hunter2")
+			msg := awaitAuth(t, cmd)
+			if _, ok := msg.(authNoticeMsg); !ok {
+				t.Fatalf("expected the notice with the password, got %v", msg)
+			}
+			m, cmd = update(t, m, msg)
+			if m.mode != ModeLoading || len(m.authNotices) != 1 || m.authNotices[0].Text != tt.notice.Message {
+				t.Fatalf("expected the notice to be shown while loading, got %s %+v", m.mode, m.authNotices)
+			}
+
+			// The typed password answers the prompt that follows without asking again
+			if msg := awaitAuth(t, cmd); msg != "success" {
+				t.Fatalf("expected success, got %v", msg)
+			}
+		})
+	}
+}
+
+func TestPasswordlessLogin(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("kiosk")
+	m := newTestModel(t, srv)
+	m.config.UsernameOnly = true
+
+	m.usernameInput.SetValue("kiosk")
+	m, cmd := update(t, m, keyEnter)
+	if m.mode != ModeLoading {
+		t.Fatalf("expected username-only login to submit, got %s", m.mode)
+	}
+
+	msg := awaitAuth(t, cmd)
+	if msg != "success" {
+		t.Fatalf("expected success, got %v", msg)
+	}
+	if req := lastRequest(t, srv); req.Type != ipc.StartSessionRequest {
+		t.Fatalf("expected start_session, got %s", req.Type)
+	}
+}
+
+func TestStartSessionFailure(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	srv.FailStartSession("error", "exec failed: sway not found")
+	m := newTestModel(t, srv)
+
+	m, cmd := login(t, m, "alice", "hunter2")
+	msg := awaitAuth(t, cmd)
+	err, ok := msg.(error)
+	if !ok {
+		t.Fatalf("expected error, got %v", msg)
+	}
+	if !strings.Contains(err.Error(), "sway not found") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m, _ = update(t, m, msg)
+	if m.mode != ModePassword || m.errorMessage == "" {
+		t.Fatalf("expected error shown in password mode, got mode %s message %q", m.mode, m.errorMessage)
+	}
+	if req := lastRequest(t, srv); req.Type != ipc.CancelSessionRequest {
+		t.Fatalf("expected cancel_session after failure, got %s", req.Type)
+	}
+}
+
+func TestEscCancelsConversation(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("bob",
+		greetdtest.Secret("Password:", "hunter2"),
+		greetdtest.Visible("Verification code:", "123456"),
+	)
+	m := newTestModel(t, srv)
+
+	m, cmd := login(t, m, "bob", "hunter2")
+	msg := awaitAuth(t, cmd)
+	m, _ = update(t, m, msg)
+	if !m.authActive {
+		t.Fatal("expected conversation to be active")
+	}
+
+	m, cmd = update(t, m, tea.KeyPressMsg{Code: tea.KeyEsc})
+	if m.mode != ModeLogin || m.authActive {
+		t.Fatalf("expected login mode without conversation, got %s", m.mode)
+	}
+
+	// Run the cancel command and wait for greetd to see it
+	awaitRequest(t, srv, cmd, ipc.CancelSessionRequest)
+}
+
+// awaitRequest runs cmd in the background and waits until srv receives a request of type want
+func awaitRequest(t *testing.T, srv *greetdtest.Server, cmd tea.Cmd, want ipc.RequestType) {
+	t.Helper()
+	go func() {
+		if cmd == nil {
+			return
+		}
+		if batch, ok := cmd().(tea.BatchMsg); ok {
+			for _, c := range batch {
+				if c != nil {
+					go c()
+				}
+			}
+		}
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if reqs := srv.Requests(); len(reqs) > 0 && reqs[len(reqs)-1].Type == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s request", want)
+}
+
+func TestEscCancelsHungRequest(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Step{Type: ipc.AuthMessageSecret, Message: "Password:", Answer: "hunter2", Delay: time.Minute})
+	m := newTestModel(t, srv)
+
+	m, authCmd := login(t, m, "alice", "hunter2")
+	result := make(chan tea.Msg, 1)
+	go func() { result <- awaitAuth(t, authCmd) }()
+
+	// Wait until greetd is sitting on the request before pressing Esc
+	deadline := time.Now().Add(5 * time.Second)
+	for len(srv.Requests()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	m, _ = update(t, m, tea.KeyPressMsg{Code: tea.KeyEsc})
+	if m.mode != ModeLogin {
+		t.Fatalf("expected login mode after Esc, got %s", m.mode)
+	}
+
+	var msg tea.Msg
+	select {
+	case msg = <-result:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the cancelled request")
+	}
+	if _, ok := msg.(authCancelledMsg); !ok {
+		t.Fatalf("expected authCancelledMsg, got %v", msg)
+	}
+	if req := lastRequest(t, srv); req.Type != ipc.CancelSessionRequest {
+		t.Fatalf("expected cancel_session after Esc, got %s", req.Type)
+	}
+
+	m, _ = update(t, m, msg)
+	if m.failedAttempts != 0 {
+		t.Fatal("cancellation must not count as a failed attempt")
+	}
+}
+
+func TestTimeoutReportedToUI(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Step{Type: ipc.AuthMessageSecret, Message: "Password:", Answer: "hunter2", Delay: time.Minute})
+	m := newTestModel(t, srv)
+	m.ipcClient.Timeout = 50 * time.Millisecond
+
+	m, cmd := login(t, m, "alice", "hunter2")
+	msg := awaitAuth(t, cmd)
+	if _, ok := msg.(error); !ok {
+		t.Fatalf("expected error, got %v", msg)
+	}
+
+	m, _ = update(t, m, msg)
+	if !strings.Contains(m.errorMessage, "did not respond") {
+		t.Fatalf("unexpected error message: %q", m.errorMessage)
+	}
+}
+
+func TestSessionEnvironment(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	m := newTestModel(t, srv)
+	m.selectedSession = &sessions.Session{
+		Name:         "Hyprland",
+		Exec:         "Hyprland",
+		Type:         "Wayland",
+		Path:         "/usr/share/wayland-sessions/hyprland.desktop",
+		DesktopNames: []string{"Hyprland"},
+	}
+	m.config.System = sysconfig.File{
+		Env: map[string]string{"MOZ_ENABLE_WAYLAND": "1", "XDG_CURRENT_DESKTOP": ""},
+		SessionEnv: map[string]map[string]string{
+			"hyprland": {"WLR_NO_HARDWARE_CURSORS": "1"},
+			"sway":     {"WLR_RENDERER": "vulkan"},
+		},
+	}
+
+	m, cmd := login(t, m, "alice", "hunter2")
+	if msg := awaitAuth(t, cmd); msg != "success" {
+		t.Fatalf("expected success, got %v", msg)
+	}
+
+	env := lastRequest(t, srv).Env
+	want := []string{
+		"XDG_SESSION_TYPE=wayland",
+		"XDG_SESSION_DESKTOP=hyprland",
+		"DESKTOP_SESSION=hyprland",
+		"MOZ_ENABLE_WAYLAND=1",
+		"WLR_NO_HARDWARE_CURSORS=1",
+	}
+	if strings.Join(env, " ") != strings.Join(want, " ") {
+		t.Fatalf("unexpected session environment:\n got %v\nwant %v", env, want)
+	}
+}
+
+func TestPerUserPreferences(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	m := newTestModel(t, srv)
+	m.config.RememberUsername = true
+	m.sessions = []sessions.Session{
+		{Name: "Sway", Exec: "sway", Type: "Wayland"},
+		{Name: "Hyprland", Exec: "Hyprland", Type: "Wayland"},
+	}
+	m.selectedSession, m.sessionIndex = &m.sessions[0], 0
+
+	for _, prefs := range []cache.UserPreferences{
+		{Theme: "nord", Session: "Hyprland", Username: "alice", ASCIIIndex: 2},
+		{Theme: "gruvbox", Session: "Sway", Username: "bob"},
+	} {
+		if err := cache.UpdatePreferences(prefs.Username, func(p *cache.UserPreferences) { *p = prefs }); err != nil {
+			t.Fatalf("UpdatePreferences: %v", err)
+		}
+	}
+
+	// Typing alice restores her session and theme, not bob's (the most recent)
+	m, cmd := login(t, m, "alice", "hunter2")
+	if m.selectedSession.Name != "Hyprland" || m.sessionIndex != 1 {
+		t.Fatalf("expected alice's session to be restored, got %s", m.selectedSession.Name)
+	}
+	if m.currentTheme != "nord" || m.asciiArtIndex != 2 {
+		t.Fatalf("expected alice's theme and ASCII variant, got %s/%d", m.currentTheme, m.asciiArtIndex)
+	}
+
+	msg := awaitAuth(t, cmd)
+	if msg != "success" {
+		t.Fatalf("expected success, got %v", msg)
+	}
+	if req := lastRequest(t, srv); req.Cmd[0] != "Hyprland" {
+		t.Fatalf("expected alice's session to start, got %v", req.Cmd)
+	}
+	update(t, m, msg)
+
+	// alice is now the last user and pre-fills the username field
+	prefs, err := cache.LoadPreferences()
+	if err != nil || prefs == nil {
+		t.Fatalf("LoadPreferences: %v", err)
+	}
+	if prefs.Username != "alice" || prefs.Session != "Hyprland" {
+		t.Fatalf("unexpected last-user preferences: %+v", prefs)
+	}
+	if bob, _ := cache.LoadUserPreferences("bob"); bob == nil || bob.Theme != "gruvbox" {
+		t.Fatalf("expected bob's preferences to be kept, got %+v", bob)
+	}
+}
@@ -0,0 +1,577 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Nomadcxx/sysc-greet/internal/animations"
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+	"github.com/Nomadcxx/sysc-greet/internal/themes"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+// check.go - "sysc-greet check": validating configuration and assets offline
+// Runs the loaders the greeter uses over the system config, the ASCII and
+// screensaver configs, custom themes and session files, and reports what the
+// greeter would reject, ignore or quietly replace with a default. Exits 1 when
+// anything is broken, so packagers can run it from post-install hooks
+
+// Art has to fit the smallest screen the greeter is commonly shown on, the
+// Linux console at 1024x768 with its 8x16 font, and the 80 column guideline
+// for ASCII art
+const (
+	checkScreenRows      = 48
+	checkArtColumns      = 80
+	loginFormRows        = 26 // Login form, borders and help line around the art
+	screensaverClockRows = 10 // Clock, date and spacing below the screensaver art
+)
+
+type checkLevel int
+
+const (
+	checkError checkLevel = iota
+	checkWarning
+)
+
+// finding is one problem reported by check
+type finding struct {
+	path  string
+	line  int // 0 when the problem is not tied to a line
+	level checkLevel
+	msg   string
+}
+
+func (f finding) String() string {
+	level := "error"
+	if f.level == checkWarning {
+		level = "warning"
+	}
+	switch {
+	case f.path == "":
+		return fmt.Sprintf("%s: %s", level, f.msg)
+	case f.line == 0:
+		return fmt.Sprintf("%s: %s: %s", f.path, level, f.msg)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", f.path, f.line, level, f.msg)
+}
+
+// checker collects the findings of one run
+type checker struct {
+	findings []finding
+}
+
+func (c *checker) errorf(path string, line int, format string, args ...any) {
+	c.findings = append(c.findings, finding{path, line, checkError, fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) warnf(path string, line int, format string, args ...any) {
+	c.findings = append(c.findings, finding{path, line, checkWarning, fmt.Sprintf(format, args...)})
+}
+
+// sortFindings orders the findings from index i on by line, for checks that
+// report a problem once they reach its end
+func (c *checker) sortFindings(i int) {
+	slices.SortStableFunc(c.findings[i:], func(a, b finding) int { return a.line - b.line })
+}
+
+// counts returns the number of errors and warnings found
+func (c *checker) counts() (errs, warnings int) {
+	for _, f := range c.findings {
+		if f.level == checkError {
+			errs++
+		} else {
+			warnings++
+		}
+	}
+	return errs, warnings
+}
+
+// runCheckCommand implements "sysc-greet check [-config PATH] [-data-dir DIR] [-strict]"
+func runCheckCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", sysconfig.DefaultPath, "System configuration file (config.d/ next to it is checked too)")
+	dir := fs.String("data-dir", "", "Directory with ascii_configs/ and themes/ (default from [paths], else "+dataDir+")")
+	strict := fs.Bool("strict", false, "Exit non-zero on warnings too")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "Usage: sysc-greet check [-config PATH] [-data-dir DIR] [-strict]\n")
+		return 2
+	}
+
+	if *dir == "" {
+		*dir = dataDir
+		// Problems in the file are reported below, only [paths] is needed here
+		if system, _ := sysconfig.Load(*configPath); system.Paths.DataDir != "" {
+			*dir = strings.TrimRight(system.Paths.DataDir, "/")
+		}
+	}
+
+	c := &checker{}
+	c.checkASCIIConfigs(filepath.Join(*dir, "ascii_configs"))
+	from := len(c.findings)
+	c.checkScreensaverConfig(filepath.Join(*dir, "ascii_configs", "screensaver.conf"))
+	c.sortFindings(from)
+	themeNames := c.checkCustomThemes(customThemeDirs(*dir))
+	c.checkSystemConfig(*configPath, themeNames)
+	c.checkSessions(sessions.DataDirs())
+
+	for _, f := range c.findings {
+		fmt.Fprintln(stdout, f)
+	}
+	errs, warnings := c.counts()
+	fmt.Fprintf(stdout, "%d error%s, %d warning%s\n", errs, plural(errs), warnings, plural(warnings))
+	if errs > 0 || (*strict && warnings > 0) {
+		return 1
+	}
+	return 0
+}
+
+// asciiKeys are the keys loadASCIIConfig reads besides the ascii_N variants
+// colors is documented for older configs and only checked for valid colors
+var asciiKeys = map[string]bool{
+	"name": true, "color": true, "colors": true, "roasts": true,
+	"animation_style": true, "animation_speed": true, "animation_direction": true,
+}
+
+var (
+	asciiAnimationStyles     = []string{"gradient", "wave", "pulse", "rainbow", "matrix", "typewriter", "glow", "static"}
+	asciiAnimationDirections = []string{"left", "right", "up", "down"}
+)
+
+// checkASCIIConfigs checks every session ASCII config in dir
+func (c *checker) checkASCIIConfigs(dir string) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.conf"))
+	var checked int
+	for _, path := range files {
+		if filepath.Base(path) == "screensaver.conf" {
+			continue
+		}
+		from := len(c.findings)
+		c.checkASCIIConfig(path)
+		c.sortFindings(from)
+		checked++
+	}
+	if checked == 0 {
+		c.warnf(dir, 0, "no ASCII configs found, sessions are shown by name")
+	}
+}
+
+// checkASCIIConfig checks one session ASCII config
+// The file is read with loadASCIIConfig, then walked line by line the same way
+// to point at what it skips: unknown keys, art lines it mistakes for keys or
+// comments, and variants that end up empty
+func (c *checker) checkASCIIConfig(path string) {
+	config, err := loadASCIIConfig(path)
+	if err != nil {
+		c.errorf(path, 0, "%v", err)
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.errorf(path, 0, "%v", err)
+		return
+	}
+
+	var variant string // Key of the variant being read, "" outside of one
+	var start int
+	var rows []string
+	var commented bool // A comment inside the variant was reported
+	endVariant := func() {
+		if variant != "" {
+			c.checkArtSize(path, start, variant, rows, checkScreenRows-loginFormRows)
+		}
+		variant, rows, commented = "", nil, false
+	}
+
+	ignored := false
+	for i, line := range strings.Split(string(data), "\n") {
+		n := i + 1
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			if variant != "" && trimmed != "" && !commented {
+				c.warnf(path, n, "lines starting with # are read as comments and left out of %s", variant)
+				commented = true
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			switch {
+			case variant != "" && trimmed == `"""`:
+				endVariant()
+			case variant != "":
+				rows = append(rows, line)
+			default:
+				// One warning for a run of ignored lines, e.g. art without a key
+				if !ignored {
+					c.warnf(path, n, "line is neither key=value nor part of an ascii_N variant and is ignored")
+				}
+				ignored = true
+				continue
+			}
+			ignored = false
+			continue
+		}
+		ignored = false
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		if key == "ascii" || strings.HasPrefix(key, "ascii_") {
+			endVariant()
+			variant, start = key, n
+			if value != "" && value != `"""` {
+				rows = append(rows, value)
+			}
+			continue
+		}
+		if !asciiKeys[key] {
+			if key == "" {
+				c.warnf(path, n, "line starts with \"=\" and is ignored")
+			} else if variant != "" {
+				c.warnf(path, n, "unknown key %q ends %s (art lines must not contain \"=\")", key, variant)
+			} else {
+				c.warnf(path, n, "unknown key %q", key)
+			}
+		}
+		endVariant()
+
+		switch key {
+		case "color":
+			if !themes.ValidHexColor(value) {
+				c.errorf(path, n, "invalid hex color %q (expected #rrggbb)", value)
+			}
+		case "colors":
+			for _, color := range strings.Split(value, ",") {
+				if color = strings.TrimSpace(color); !themes.ValidHexColor(color) {
+					c.errorf(path, n, "invalid hex color %q in colors (expected #rrggbb)", color)
+				}
+			}
+		case "animation_speed":
+			if speed, err := strconv.ParseFloat(value, 64); err != nil || speed <= 0 {
+				c.errorf(path, n, "animation_speed %q is not a positive number", value)
+			}
+		case "animation_style":
+			if !slices.Contains(asciiAnimationStyles, value) {
+				c.warnf(path, n, "unknown animation_style %q, gradient is used (one of %s)", value, strings.Join(asciiAnimationStyles, ", "))
+			}
+		case "animation_direction":
+			if !slices.Contains(asciiAnimationDirections, value) {
+				c.warnf(path, n, "unknown animation_direction %q, right is used (one of %s)", value, strings.Join(asciiAnimationDirections, ", "))
+			}
+		}
+	}
+	endVariant()
+
+	if len(config.ASCIIVariants) == 0 {
+		c.errorf(path, 0, "no ASCII art (expected ascii_1= followed by the art), the session name is shown instead")
+	}
+}
+
+// checkArtSize reports an empty variant or one that does not fit the screen
+// maxRows is what is left of checkScreenRows once the rest of the view is drawn
+func (c *checker) checkArtSize(path string, line int, variant string, rows []string, maxRows int) {
+	if len(rows) == 0 {
+		c.warnf(path, line, "%s is empty", variant)
+		return
+	}
+	if len(rows) > maxRows {
+		c.warnf(path, line, "%s is %d rows tall, more than the %d that fit a %d row console", variant, len(rows), maxRows, checkScreenRows)
+	}
+	width := 0
+	for _, row := range rows {
+		width = max(width, lipgloss.Width(row))
+	}
+	if width > checkArtColumns {
+		c.warnf(path, line, "%s is %d columns wide, more than %d", variant, width, checkArtColumns)
+	}
+}
+
+// screensaverKeys are the keys loadScreensaverConfig reads besides the ascii_N variants
+var screensaverKeys = map[string]bool{
+	"idle_timeout": true, "time_format": true, "date_format": true, "clock_style": true,
+	"animate_on_start": true, "animation_type": true, "animation_speed": true,
+}
+
+// checkScreensaverConfig checks screensaver.conf, following the rules of
+// loadScreensaverConfig: a variant runs until a line with "=" that does not
+// start with whitespace. A missing file is fine, the defaults are used then
+func (c *checker) checkScreensaverConfig(path string) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		c.errorf(path, 0, "%v", err)
+		return
+	}
+
+	var variant string
+	var start int
+	var rows []string
+	endVariant := func() {
+		if variant != "" {
+			c.checkArtSize(path, start, variant, rows, checkScreenRows-screensaverClockRows)
+		}
+		variant, rows = "", nil
+	}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		n := i + 1
+		trimmed := strings.TrimSpace(line)
+		if variant == "" && (trimmed == "" || strings.HasPrefix(trimmed, "#")) {
+			continue
+		}
+		if strings.HasPrefix(line, "ascii_") && strings.Contains(line, "=") {
+			endVariant()
+			key, value, _ := strings.Cut(line, "=")
+			variant, start = key, n
+			if value != "" {
+				rows = append(rows, value)
+			}
+			continue
+		}
+		if variant != "" {
+			if !strings.Contains(line, "=") || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+				rows = append(rows, line)
+				continue
+			}
+			endVariant()
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			c.warnf(path, n, "line is neither key=value nor part of an ascii_N variant and is ignored")
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "idle_timeout", "animation_speed":
+			if v, err := strconv.Atoi(value); err != nil || v < 0 {
+				c.errorf(path, n, "%s %q is not a whole number", key, value)
+			}
+		case "clock_style":
+			c.checkClockFont(path, n, value)
+		case "animate_on_start":
+			if value != "true" && value != "false" {
+				c.warnf(path, n, "animate_on_start %q is read as false (expected true or false)", value)
+			}
+		case "animation_type":
+			if value != "print" && value != "none" {
+				c.warnf(path, n, "unknown animation_type %q (expected print or none)", value)
+			}
+		default:
+			if !screensaverKeys[key] {
+				c.warnf(path, n, "unknown key %q", key)
+			}
+		}
+	}
+	endVariant()
+}
+
+// checkClockFont reports a screensaver clock style without digits
+// The screensaver silently falls back to kompaktblk for those
+func (c *checker) checkClockFont(path string, line int, style string) {
+	if _, ok := animations.ClockStyleDigits[style]; ok {
+		return
+	}
+	fonts := slices.Sorted(maps.Keys(animations.ClockStyleDigits))
+	c.errorf(path, line, "clock font %q does not exist (one of %s)", style, strings.Join(fonts, ", "))
+}
+
+// checkCustomThemes checks the custom theme files the greeter scans and
+// returns the names of those that load
+func (c *checker) checkCustomThemes(dirs []string) []string {
+	var names []string
+	for _, dir := range dirs {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.toml"))
+		for _, path := range files {
+			theme, unknown, invalid, err := themes.CheckCustomTheme(path)
+			if err != nil {
+				var fe *themes.FieldError
+				if errors.As(err, &fe) {
+					c.errorf(path, tomlKeyLine(path, "colors."+fe.Field), "%v", err)
+				} else {
+					c.tomlError(path, err)
+				}
+				continue
+			}
+			c.unknownKeys(path, unknown)
+			for _, fe := range invalid {
+				c.errorf(path, tomlKeyLine(path, "colors."+fe.Field), "%v", &fe)
+			}
+			names = append(names, theme.Name)
+		}
+	}
+	return names
+}
+
+// checkSystemConfig checks config.toml and its drop-ins, each on its own so
+// that problems point at the file and line that cause them
+func (c *checker) checkSystemConfig(path string, customThemes []string) {
+	for _, file := range sysconfig.Files(path) {
+		from := len(c.findings)
+		c.checkSystemConfigFile(file, customThemes)
+		c.sortFindings(from)
+	}
+}
+
+// checkSystemConfigFile checks one of the files making up the system config
+func (c *checker) checkSystemConfigFile(file string, customThemes []string) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		c.errorf(file, 0, "%v", err)
+		return
+	}
+
+	var f sysconfig.File
+	md, err := toml.Decode(string(data), &f)
+	if err != nil {
+		c.tomlError(file, err)
+		return
+	}
+	var unknown []string
+	for _, key := range md.Undecoded() {
+		unknown = append(unknown, key.String())
+	}
+	c.unknownKeys(file, unknown)
+
+	if theme := f.Appearance.Theme; theme != "" && !isKnownTheme(theme, customThemes) {
+		c.errorf(file, tomlKeyLine(file, "appearance.theme"), "theme %q is neither built in nor a custom theme", theme)
+	}
+	if launch := f.X11.Launch; launch != "" {
+		opts := sessions.LaunchOptions{X11: launch, Wrapper: f.X11.Wrapper}
+		if _, err := sessions.LaunchCommand(sessions.Session{Name: "check", Type: "X11"}, []string{"true"}, opts); err != nil {
+			line := tomlKeyLine(file, "x11.launch")
+			if launch == sessions.LaunchWrapper && f.X11.Wrapper != "" {
+				line = tomlKeyLine(file, "x11.wrapper")
+			}
+			c.errorf(file, line, "%v", err)
+		}
+	}
+	if style := f.Screensaver.ClockStyle; style != "" {
+		c.checkClockFont(file, tomlKeyLine(file, "screensaver.clock_style"), style)
+	}
+	for i, art := range f.Screensaver.ASCII {
+		c.checkArtSize(file, tomlKeyLine(file, "screensaver.ascii"), fmt.Sprintf("screensaver.ascii[%d]", i), strings.Split(art, "\n"), checkScreenRows-screensaverClockRows)
+	}
+}
+
+// checkSessions checks the session files below dataDirs
+func (c *checker) checkSessions(dataDirs []string) {
+	found, errs := sessions.CheckSessionsFrom(dataDirs, sessions.CurrentLocale())
+	for _, err := range errs {
+		var fe *sessions.FileError
+		if !errors.As(err, &fe) {
+			c.errorf("", 0, "%v", err)
+			continue
+		}
+		var se *sessions.SyntaxError
+		if errors.As(fe.Err, &se) {
+			c.errorf(fe.Path, se.Line, "%s", se.Msg)
+		} else {
+			c.errorf(fe.Path, 0, "%v", fe.Err)
+		}
+	}
+	for _, s := range found {
+		if _, err := exec.LookPath(s.Command[0]); err != nil {
+			c.warnf(s.Path, 0, "Exec command %q is not installed", s.Command[0])
+		}
+	}
+	if len(found) == 0 && len(errs) == 0 {
+		c.warnf("", 0, "no session files in xsessions/ or wayland-sessions/ below %s", strings.Join(dataDirs, ", "))
+	}
+}
+
+// tomlTypeError matches the errors toml returns for values of the wrong type,
+// which are not a toml.ParseError and only carry the position in their text
+var tomlTypeError = regexp.MustCompile(`^toml: line (\d+) \(last key "([^"]*)"\): (.*)$`)
+
+// tomlError reports a TOML decoding error at the line it names
+func (c *checker) tomlError(path string, err error) {
+	var pe toml.ParseError
+	if errors.As(err, &pe) {
+		c.errorf(path, pe.Position.Line, "%s", pe.Message)
+		return
+	}
+	if m := tomlTypeError.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		c.errorf(path, line, "%s: %s", m[2], m[3])
+		return
+	}
+	c.errorf(path, 0, "%v", err)
+}
+
+// unknownKeys warns about keys no setting uses
+// Keys below an unknown table are left out, the table is reported instead
+func (c *checker) unknownKeys(path string, keys []string) {
+	var reported []string
+	for _, key := range keys {
+		below := false
+		for _, r := range reported {
+			below = below || strings.HasPrefix(key, r+".")
+		}
+		if below {
+			continue
+		}
+		reported = append(reported, key)
+		c.warnf(path, tomlKeyLine(path, key), "unknown key %s", key)
+	}
+}
+
+// tomlKeyLine returns the line that sets a dotted key such as "colors.primary"
+// in the TOML file at path, or 0 when it cannot be found
+func tomlKeyLine(path, key string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	table, name := "", key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		table, name = key[:i], key[i+1:]
+	}
+
+	current := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			header, _, _ := strings.Cut(line, "#")
+			current = strings.TrimSpace(strings.Trim(strings.TrimSpace(header), "[]"))
+			if current == key {
+				return i + 1
+			}
+			continue
+		}
+		if k, _, ok := strings.Cut(line, "="); ok && current == table && strings.Trim(strings.TrimSpace(k), `"'`) == name {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// isKnownTheme reports whether name is a built-in or custom theme
+func isKnownTheme(name string, customThemes []string) bool {
+	for _, theme := range append(themes.GetAvailableThemes(), customThemes...) {
+		if strings.EqualFold(theme, name) {
+			return true
+		}
+	}
+	return false
+}
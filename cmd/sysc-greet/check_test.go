@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files (path relative to dir -> contents) below dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// runCheck runs "sysc-greet check" against dir and returns its exit status and output
+func runCheck(t *testing.T, dir string, args ...string) (int, string) {
+	t.Helper()
+	t.Setenv("HOME", filepath.Join(dir, "home"))
+	t.Setenv("XDG_DATA_DIRS", filepath.Join(dir, "share"))
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-config", filepath.Join(dir, "etc", "config.toml")}, args...)
+	code := runCheckCommand(args, &stdout, &stderr)
+	if stderr.Len() > 0 {
+		t.Fatalf("unexpected output on stderr: %s", stderr.String())
+	}
+	return code, stdout.String()
+}
+
+const validTheme = `name = "Harbor"
+
+[colors]
+bg_base = "#1a1a2e"
+bg_active = "#2a2a3e"
+primary = "#e94560"
+secondary = "#0f3460"
+accent = "#16213e"
+warning = "#f59e0b"
+danger = "#ef4444"
+fg_primary = "#ffffff"
+fg_secondary = "#cccccc"
+fg_muted = "#888888"
+border_focus = "#e94560"
+`
+
+func TestCheckCommand(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"etc/config.toml": `[appearance]
+theme = "harbor"
+colour = "red"
+
+[screensaver]
+clock_style = "dos_rebel"
+`,
+		"etc/config.d/10-types.toml":  "[greeter]\nipc_timeout = \"soon\"\n",
+		"etc/config.d/20-broken.toml": "[greeter\n",
+		"etc/config.d/30-x11.toml":    "[x11]\nlaunch = \"wrapper\"\nwrapper = \"xinit -- %v\"\n",
+
+		"data/ascii_configs/sway.conf": `name=sway
+color=#89b4fz
+animation_speed=fast
+animation_style=sparkle
+ascii_1=
+ ___
+|___|
+ascii_2=
+## ##
+ascii_3=
+ [=] [=]
+sparkle=yes
+`,
+		"data/ascii_configs/openbox.conf": "OPENBOX\nOPENBOX\nroasts=Right-click\n",
+		"data/ascii_configs/tall.conf":    "name=tall\nascii_1=\n" + strings.Repeat("|||\n", 30),
+		"data/ascii_configs/screensaver.conf": `idle_timeout=soon
+clock_style=delta_corp
+animate_on_start=yes
+ascii_1=
+zzz
+`,
+
+		"data/themes/harbor.toml":                   validTheme,
+		"data/themes/broken.toml":                   strings.Replace(validTheme, `primary = "#e94560"`, `primary = "e94560"`, 1),
+		"home/.config/sysc-greet/themes/extra.toml": strings.Replace(validTheme, `name = "Harbor"`, "name = \"Extra\"\nauthor = \"me\"", 1),
+
+		"share/wayland-sessions/sway.desktop":    "[Desktop Entry]\nName=Sway\nExec=sh\n",
+		"share/wayland-sessions/missing.desktop": "[Desktop Entry]\nName=Missing\nExec=sysc-greet-no-such-compositor\n",
+		"share/xsessions/broken.desktop":         "Name=Broken\n[Desktop Entry]\n",
+	})
+
+	code, out := runCheck(t, dir, "-data-dir", filepath.Join(dir, "data"))
+	if code != 1 {
+		t.Fatalf("expected exit status 1, got %d:\n%s", code, out)
+	}
+
+	want := []string{
+		"ascii_configs/sway.conf:2: error: invalid hex color \"#89b4fz\"",
+		"ascii_configs/sway.conf:3: error: animation_speed \"fast\" is not a positive number",
+		"ascii_configs/sway.conf:4: warning: unknown animation_style \"sparkle\"",
+		"ascii_configs/sway.conf:9: warning: lines starting with # are read as comments and left out of ascii_2",
+		"ascii_configs/sway.conf:8: warning: ascii_2 is empty",
+		"ascii_configs/sway.conf:11: warning: unknown key \"[\" ends ascii_3",
+		"ascii_configs/sway.conf:12: warning: unknown key \"sparkle\"",
+		"ascii_configs/openbox.conf:1: warning: line is neither key=value nor part of an ascii_N variant",
+		"ascii_configs/openbox.conf: error: no ASCII art",
+		"ascii_configs/tall.conf:2: warning: ascii_1 is 30 rows tall",
+		"ascii_configs/screensaver.conf:1: error: idle_timeout \"soon\" is not a whole number",
+		"ascii_configs/screensaver.conf:2: error: clock font \"delta_corp\" does not exist",
+		"ascii_configs/screensaver.conf:3: warning: animate_on_start \"yes\" is read as false",
+		"themes/broken.toml:6: error: primary: invalid hex color \"e94560\"",
+		"themes/extra.toml:2: warning: unknown key author",
+		"etc/config.toml:3: warning: unknown key appearance.colour",
+		"etc/config.toml:6: error: clock font \"dos_rebel\" does not exist",
+		"config.d/10-types.toml:2: error: greeter.ipc_timeout: incompatible types",
+		"config.d/20-broken.toml:2: error:",
+		"config.d/30-x11.toml:3: error: X11 wrapper \"xinit -- %v\" has no %s",
+		"xsessions/broken.desktop:1: error: key \"Name\" outside of a group",
+		"wayland-sessions/missing.desktop: warning: Exec command \"sysc-greet-no-such-compositor\" is not installed",
+		"11 errors, 12 warnings",
+	}
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("expected %q in the output:\n%s", w, out)
+		}
+	}
+	if strings.Contains(out, "harbor") || strings.Contains(out, "sway.desktop") {
+		t.Errorf("expected the custom theme and the working session to pass:\n%s", out)
+	}
+	// Findings of one file are listed by line
+	if strings.Index(out, "sway.conf:8:") > strings.Index(out, "sway.conf:9:") {
+		t.Errorf("expected sway.conf findings in line order:\n%s", out)
+	}
+}
+
+func TestCheckCommandShippedAssets(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"share/wayland-sessions/sway.desktop": "[Desktop Entry]\nName=Sway\nExec=sh\n",
+	})
+
+	// The ASCII configs in the repository may have warnings, but no errors
+	code, out := runCheck(t, dir, "-data-dir", "../..")
+	if code != 0 || strings.Contains(out, "error:") {
+		t.Fatalf("expected the shipped assets to pass, got %d:\n%s", code, out)
+	}
+	if code, _ := runCheck(t, dir, "-data-dir", "../..", "-strict"); code != 1 {
+		t.Fatalf("expected -strict to fail on the warnings, got %d", code)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Nomadcxx/sysc-greet/internal/cache"
+	"github.com/Nomadcxx/sysc-greet/internal/crash"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+// crash_report.go - [crash_report] telling the user why the greeter is back
+// Sessions are started through "sysc-greet run-session", which runs the real
+// command as the user and writes a report when it exits (see internal/crash).
+// When the greeter starts and finds a report of a failed session, it shows the
+// summary above the form; Ctrl+L expands the session's last stderr lines and
+// Ctrl+O selects a different session
+
+// reportDir returns the directory session reports are written to
+func reportDir(config Config) string {
+	if dir := config.System.CrashReport.Dir; dir != "" {
+		return dir
+	}
+	return crash.DefaultDir
+}
+
+// wrapSession prefixes cmd with the run-session wrapper when reports are enabled
+func (m model) wrapSession(cmd []string) []string {
+	c := m.config.System.CrashReport
+	if !c.Enabled {
+		return cmd
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		logWarn("Not recording the session, cannot find the sysc-greet binary: %v", err)
+		return cmd
+	}
+	session := m.selectedSession
+	wrapper := []string{exe, "run-session",
+		"-dir", reportDir(m.config),
+		"-session", session.Name,
+		"-key", session.Key(),
+	}
+	if c.Lines > 0 {
+		wrapper = append(wrapper, "-lines", fmt.Sprint(c.Lines))
+	}
+	return append(append(wrapper, "--"), cmd...)
+}
+
+// runSessionCommand implements "sysc-greet run-session": it runs the session
+// command given after --, records how it ended and exits with its status
+func runSessionCommand(args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("run-session", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir := fs.String("dir", crash.DefaultDir, "Directory to write the report to")
+	session := fs.String("session", "", "Session name shown in the report")
+	key := fs.String("key", "", "Session key, used to offer a different session")
+	lines := fs.Int("lines", crash.DefaultLines, "Lines of stderr to keep")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	argv := fs.Args()
+	if len(argv) == 0 {
+		fmt.Fprintf(stderr, "Usage: sysc-greet run-session [-dir DIR] [-session NAME] -- COMMAND [ARGS...]\n")
+		return 2
+	}
+
+	// greetd signals the session at logout and shutdown; pass that on and stay
+	// alive long enough to write the report
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGINT)
+	defer signal.Stop(signals)
+	r := crash.Run(argv, stderr, *lines, func(p *os.Process) {
+		go func() {
+			for sig := range signals {
+				p.Signal(sig)
+			}
+		}()
+	})
+
+	r.Session, r.Key = *session, *key
+	if r.Session == "" {
+		r.Session = argv[0]
+	}
+	if u, err := user.Current(); err == nil {
+		r.User = u.Username
+	}
+	if err := crash.Write(*dir, r); err != nil {
+		fmt.Fprintf(stderr, "sysc-greet: %v\n", err)
+	}
+
+	if r.Signal != "" {
+		if status, ok := signalNumber(r.Signal); ok {
+			return 128 + status
+		}
+		return 1
+	}
+	return r.ExitCode
+}
+
+// signalNumber maps a signal description from a report back to its number
+func signalNumber(name string) (int, bool) {
+	for sig := syscall.Signal(1); sig < 32; sig++ {
+		if sig.String() == name {
+			return int(sig), true
+		}
+	}
+	return 0, false
+}
+
+// loadLastSession picks up the report of a session that failed since the
+// greeter last ran
+func (m *model) loadLastSession() {
+	c := m.config.System.CrashReport
+	if !c.Enabled || m.config.TestMode {
+		return
+	}
+	// Reports are only taken from the account the last session ran as; anyone
+	// else's files in the shared directory are dropped unread
+	uid := -1
+	username, err := cache.LoadSessionUser()
+	if err != nil {
+		logWarn("Failed to load the last session's user: %v", err)
+	}
+	if u, err := user.Lookup(username); username != "" && err == nil {
+		if id, err := strconv.Atoi(u.Uid); err == nil {
+			uid = id
+		}
+	}
+	r, err := crash.Latest(reportDir(m.config), time.Now(), uid)
+	if err != nil {
+		logWarn("Failed to read session reports: %v", err)
+	}
+	if r == nil || !r.Failed(c.MinimumRuntime()) {
+		return
+	}
+	greeterLog.Info("Last session failed", "session", r.Session, "exit_code", r.ExitCode, "signal", r.Signal, "runtime", r.Runtime().Round(time.Second))
+	m.lastSession = r
+}
+
+// recordSessionUser remembers who the started session runs as, for loadLastSession
+func (m model) recordSessionUser() {
+	if !m.config.System.CrashReport.Enabled || m.config.TestMode {
+		return
+	}
+	if err := cache.SaveSessionUser(m.usernameInput.Value()); err != nil {
+		logWarn("Failed to save the session's user: %v", err)
+	}
+}
+
+// fallbackSessionIndex returns the session to offer instead of the failed one:
+// [crash_report] fallback, else the next regular session in the list. It
+// returns -1 when there is none or it is already selected
+func (m model) fallbackSessionIndex() int {
+	if m.lastSession == nil || m.selectedSession == nil || m.selectedSession.Key() != m.lastSession.Key {
+		return -1
+	}
+	if name := m.config.System.CrashReport.Fallback; name != "" {
+		for i, s := range m.sessions {
+			if s.Matches(name) && s.Key() != m.lastSession.Key {
+				return i
+			}
+		}
+	}
+	for step := 1; step < len(m.sessions); step++ {
+		i := (m.sessionIndex + step) % len(m.sessions)
+		s := &m.sessions[i]
+		if s.Key() != m.lastSession.Key && !isGuestSession(s) && !isCustomSession(s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectFallbackSession switches to the session offered by fallbackSessionIndex
+func (m *model) selectFallbackSession() {
+	i := m.fallbackSessionIndex()
+	if i < 0 {
+		return
+	}
+	m.sessionIndex = i
+	m.selectedSession = &m.sessions[i]
+	greeterLog.Info("Switched to fallback session", "session", m.selectedSession.Name)
+}
+
+// renderLastSession renders the failed session report shown above the form
+func (m model) renderLastSession(width int) string {
+	r := m.lastSession
+	warning := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FFAA00")).
+		Bold(true).
+		Width(width).
+		Render("⚠ " + r.Summary())
+	parts := []string{warning}
+
+	hints := []string{"Ctrl+L Show log"}
+	if m.lastSessionLog {
+		hints[0] = "Ctrl+L Hide log"
+	}
+	if i := m.fallbackSessionIndex(); i >= 0 {
+		hints = append(hints, "Ctrl+O Use "+m.sessions[i].Name)
+	}
+	muted := lipgloss.NewStyle().Foreground(FgMuted)
+	parts = append(parts, muted.Render(strings.Join(hints, " • ")))
+
+	if m.lastSessionLog {
+		lines := r.Stderr
+		if len(lines) == 0 {
+			lines = []string{"(nothing was written to stderr)"}
+		}
+		logStyle := lipgloss.NewStyle().
+			Foreground(FgSecondary).
+			Background(BgBase).
+			MaxWidth(width)
+		parts = append(parts, "", logStyle.Render(strings.Join(lines, "\n")))
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/user"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nomadcxx/sysc-greet/internal/cache"
+	"github.com/Nomadcxx/sysc-greet/internal/crash"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+func TestRunSessionCommand(t *testing.T) {
+	dir := t.TempDir()
+	var stderr bytes.Buffer
+	code := runSessionCommand([]string{"-dir", dir, "-session", "Hyprland", "-key", "Wayland:hyprland", "--",
+		"sh", "-c", "echo 'failed to open DRM device' >&2; exit 1"}, &stderr)
+	if code != 1 {
+		t.Fatalf("expected the session's exit status, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "failed to open DRM device") {
+		t.Fatalf("expected stderr to be passed through, got %q", stderr.String())
+	}
+
+	r, err := crash.Latest(dir, time.Now(), os.Getuid())
+	if err != nil || r == nil {
+		t.Fatalf("expected a report, got %v, %v", r, err)
+	}
+	if r.Session != "Hyprland" || r.Key != "Wayland:hyprland" || r.ExitCode != 1 || !reflect.DeepEqual(r.Stderr, []string{"failed to open DRM device"}) {
+		t.Fatalf("unexpected report %+v", r)
+	}
+
+	if code := runSessionCommand([]string{"-dir", dir, "--", "sh", "-c", "kill -ABRT $$"}, &stderr); code != 134 {
+		t.Fatalf("expected 128+SIGABRT for a killed session, got %d", code)
+	}
+}
+
+func TestSessionWrapper(t *testing.T) {
+	m := newTestModel(t, greetdtest.NewServer(t))
+	m.selectedSession = &sessions.Session{Name: "Sway", Type: "Wayland", FileID: "sway", Command: []string{"sway"}}
+	m.config.System.SessionArgs = map[string][]string{"sway": {}}
+	m.config.System.CrashReport.Enabled = true
+	m.config.System.CrashReport.Dir = "/run/reports"
+
+	cmd, err := m.launchCommand()
+	if err != nil {
+		t.Fatalf("launchCommand: %v", err)
+	}
+	want := []string{"run-session", "-dir", "/run/reports", "-session", "Sway", "-key", "Wayland:sway", "--", "sway"}
+	if len(cmd) != len(want)+1 || !reflect.DeepEqual(cmd[1:], want) {
+		t.Fatalf("launchCommand() = %q, want the wrapper followed by %q", cmd, want)
+	}
+}
+
+// withLastSession makes m find a report of a failed Hyprland session on start
+func withLastSession(t *testing.T, m model) model {
+	t.Helper()
+	m.sessions = []sessions.Session{
+		{Name: "Hyprland", Type: "Wayland", FileID: "hyprland"},
+		{Name: "Plasma", Type: "Wayland", FileID: "plasma"},
+		{Name: "Sway", Type: "Wayland", FileID: "sway"},
+	}
+	m.sessionIndex, m.selectedSession = 0, &m.sessions[0]
+
+	dir := t.TempDir()
+	m.config.System.CrashReport.Enabled = true
+	m.config.System.CrashReport.Dir = dir
+	now := time.Now()
+	err := crash.Write(dir, crash.Report{
+		Session: "Hyprland", Key: "Wayland:hyprland", ExitCode: 1,
+		Started: now.Add(-2 * time.Second), Ended: now,
+		Stderr: []string{"[ERR] failed to open DRM device"},
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// The session ran as the user running the tests
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("no current user: %v", err)
+	}
+	m.usernameInput.SetValue(current.Username)
+	m.recordSessionUser()
+	m.loadLastSession()
+	return m
+}
+
+func TestLastSessionReport(t *testing.T) {
+	m := withLastSession(t, newTestModel(t, greetdtest.NewServer(t)))
+	if m.lastSession == nil {
+		t.Fatal("expected the failed session to be reported")
+	}
+
+	view := m.renderLastSession(80)
+	if !strings.Contains(view, "Last session (Hyprland) exited with code 1 after 2s") || !strings.Contains(view, "Ctrl+O Use Plasma") {
+		t.Fatalf("unexpected report view:\n%s", view)
+	}
+	if strings.Contains(view, "failed to open DRM device") {
+		t.Fatal("expected the log to be collapsed at first")
+	}
+
+	m, _ = update(t, m, tea.KeyPressMsg{Code: 'l', Mod: tea.ModCtrl})
+	if view := m.renderLastSession(80); !strings.Contains(view, "failed to open DRM device") {
+		t.Fatalf("expected Ctrl+L to expand the log:\n%s", view)
+	}
+
+	m, _ = update(t, m, tea.KeyPressMsg{Code: 'o', Mod: tea.ModCtrl})
+	if m.selectedSession.Name != "Plasma" || m.sessionIndex != 1 {
+		t.Fatalf("expected Ctrl+O to select the next session, got %s", m.selectedSession.Name)
+	}
+	if view := m.renderLastSession(80); strings.Contains(view, "Ctrl+O") {
+		t.Fatalf("expected no fallback offer once switched:\n%s", view)
+	}
+}
+
+func TestLastSessionConfiguredFallback(t *testing.T) {
+	m := newTestModel(t, greetdtest.NewServer(t))
+	m.config.System.CrashReport.Fallback = "sway"
+	m = withLastSession(t, m)
+	m.selectFallbackSession()
+	if m.selectedSession.Name != "Sway" {
+		t.Fatalf("expected the configured fallback, got %s", m.selectedSession.Name)
+	}
+}
+
+func TestLastSessionCleanExit(t *testing.T) {
+	m := newTestModel(t, greetdtest.NewServer(t))
+	dir := t.TempDir()
+	m.config.System.CrashReport.Enabled = true
+	m.config.System.CrashReport.Dir = dir
+	now := time.Now()
+	crash.Write(dir, crash.Report{Session: "Sway", Started: now.Add(-time.Hour), Ended: now})
+
+	m.loadLastSession()
+	if m.lastSession != nil {
+		t.Fatalf("expected a logout after an hour not to be reported, got %+v", m.lastSession)
+	}
+}
+
+func TestLastSessionOfAnotherUser(t *testing.T) {
+	m := newTestModel(t, greetdtest.NewServer(t))
+	dir := t.TempDir()
+	m.config.System.CrashReport.Enabled = true
+	m.config.System.CrashReport.Dir = dir
+	now := time.Now()
+
+	// Nobody logged in through the greeter yet, so any report is planted
+	crash.Write(dir, crash.Report{Session: "Sway", ExitCode: 1, Started: now, Ended: now})
+	m.loadLastSession()
+	if m.lastSession != nil {
+		t.Fatalf("expected no report without a recorded session user, got %+v", m.lastSession)
+	}
+
+	// A session user whose reports are not the ones in the directory
+	if err := cache.SaveSessionUser("nobody-" + strconv.Itoa(os.Getpid())); err != nil {
+		t.Fatal(err)
+	}
+	crash.Write(dir, crash.Report{Session: "Sway", ExitCode: 1, Started: now, Ended: now})
+	m.loadLastSession()
+	if m.lastSession != nil {
+		t.Fatalf("expected a report owned by someone else to be ignored, got %+v", m.lastSession)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"os/user"
+	"strings"
+
+	"github.com/Nomadcxx/sysc-greet/internal/cache"
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+// custom_session.go - sessions that have no desktop file
+// [[sessions.extra]] entries are fixed commands from the config (e.g. a console
+// shell when no desktop session is installed). With custom_command, a "Custom
+// command" entry shows a field above the username to type any command, split
+// like a shell would; ↑↓ in that field recall the user's recent commands from
+// the cache
+
+// customSessionID is the desktop file ID of the custom command entry
+const customSessionID = "custom-command"
+
+// customSession is the entry whose command is typed in the greeter
+func customSession() sessions.Session {
+	return sessions.Session{
+		Name:    "Custom command",
+		Type:    "TTY",
+		Comment: "Run a command typed below",
+		FileID:  customSessionID,
+	}
+}
+
+// isCustomSession reports whether s is the custom command entry
+func isCustomSession(s *sessions.Session) bool {
+	return s != nil && s.FileID == customSessionID && s.Path == ""
+}
+
+// sessionTypeName maps a type from the config ("wayland", "x11", "tty") to a session type
+func sessionTypeName(name, fallback string) string {
+	switch strings.ToLower(name) {
+	case "wayland":
+		return "Wayland"
+	case "x11":
+		return "X11"
+	case "tty":
+		return "TTY"
+	}
+	return fallback
+}
+
+// configSessions returns the sessions defined in the config
+// Fallback entries are only included when no desktop sessions are installed
+func configSessions(config Config, installed int) []sessions.Session {
+	var list []sessions.Session
+	for _, e := range config.System.Sessions.Extra {
+		if e.Fallback && installed > 0 {
+			continue
+		}
+		command, err := sessions.ParseExec(e.Command, sessions.ExecContext{Name: e.Name})
+		if err == nil && len(command) == 0 {
+			err = fmt.Errorf("empty command")
+		}
+		if e.Name == "" || err != nil {
+			logWarn("Skipping extra session %q with command %q: %v", e.Name, e.Command, err)
+			continue
+		}
+		list = append(list, sessions.Session{
+			Name:    e.Name,
+			Exec:    e.Command,
+			Type:    sessionTypeName(e.Type, "TTY"),
+			Comment: e.Comment,
+			Command: command,
+		})
+	}
+	if config.System.Sessions.CustomCommand {
+		list = append(list, customSession())
+	}
+	return list
+}
+
+// initCustomCommand sets up the command field
+// The history is loaded once the username is known (loadCommandHistory)
+func (m *model) initCustomCommand() {
+	ti := textinput.New()
+	ti.Prompt = ""
+	ti.Placeholder = "e.g. dbus-run-session sway"
+	ti.Styles.Focused.Text = lipgloss.NewStyle().Foreground(FgPrimary)
+	ti.Styles.Focused.Placeholder = lipgloss.NewStyle().Foreground(FgMuted).Italic(true)
+	ti.Styles.Blurred.Placeholder = lipgloss.NewStyle().Foreground(FgMuted).Italic(true)
+	m.commandInput = ti
+	m.historyIndex = -1
+}
+
+// loadCommandHistory makes commandHistory username's own, loading it from the
+// cache when another user's (or none) is loaded
+// CHANGED 2026-10-16 - Keyed by username like the preferences, so nobody is
+// shown the commands of whoever logged in before them
+func (m *model) loadCommandHistory(username string) {
+	if username == m.historyUser {
+		return
+	}
+	m.historyUser = username
+	m.commandHistory = nil
+	m.historyIndex = -1
+	if username == "" || !m.config.System.Sessions.CustomCommand || m.config.TestMode {
+		return
+	}
+	history, err := cache.LoadCommandHistory(username)
+	if err != nil {
+		logWarn("Failed to load command history: %v", err)
+	}
+	m.commandHistory = history
+}
+
+// customSelected reports whether the custom command entry is the selected session
+func (m model) customSelected() bool {
+	return m.config.System.Sessions.CustomCommand && isCustomSession(m.selectedSession)
+}
+
+// focusCommand moves the focus to the command field
+func (m model) focusCommand() model {
+	m.focusState = FocusCommand
+	m.usernameInput.Blur()
+	m.passwordInput.Blur()
+	m.commandInput.Focus()
+	return m
+}
+
+// customCommand splits the typed command, expanding ~ to home
+func (m model) customCommand(home string) ([]string, error) {
+	line := strings.TrimSpace(m.commandInput.Value())
+	if line == "" {
+		return nil, fmt.Errorf("enter a command to run")
+	}
+	argv, err := sessions.SplitCommand(line, home)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command: %v", err)
+	}
+	return argv, nil
+}
+
+// customCommandError returns the message to show when the custom command entry
+// is selected and the typed command cannot be run, or ""
+func (m model) customCommandError() string {
+	if !m.customSelected() {
+		return ""
+	}
+	if _, err := m.customCommand(""); err != nil {
+		msg := err.Error()
+		return strings.ToUpper(msg[:1]) + msg[1:]
+	}
+	return ""
+}
+
+// sessionArgv returns the command line of the selected session
+// A custom command is split with ~ expanded to the home of the user logging in
+func (m model) sessionArgv() ([]string, error) {
+	if m.customSelected() {
+		home := ""
+		if u, err := user.Lookup(m.usernameInput.Value()); err == nil {
+			home = u.HomeDir
+		}
+		return m.customCommand(home)
+	}
+	return m.selectedSession.Argv(), nil
+}
+
+// updateCommandInput passes msg to the focused command field
+func (m *model) updateCommandInput(msg tea.Msg) tea.Cmd {
+	typed := m.commandInput.Value()
+	var cmd tea.Cmd
+	m.commandInput, cmd = m.commandInput.Update(msg)
+	if m.commandInput.Value() != typed {
+		m.errorMessage = ""
+	}
+	return cmd
+}
+
+// browseCommandHistory steps through recent commands: 1 is older, -1 newer
+// Stepping past the newest command empties the field again. The history is
+// that of the remembered username in the form, empty while there is none
+func (m *model) browseCommandHistory(step int) {
+	m.loadCommandHistory(m.rememberedUsername())
+	index := m.historyIndex + step
+	if index >= len(m.commandHistory) || index < -1 {
+		return
+	}
+	m.historyIndex = index
+	if index == -1 {
+		m.commandInput.SetValue("")
+		return
+	}
+	m.commandInput.SetValue(m.commandHistory[index])
+	m.commandInput.CursorEnd()
+}
+
+// recordCustomCommand adds the command of a started custom session to the
+// history of the user who started it. Like the per-user preferences, nothing is
+// kept when usernames are not remembered
+func (m *model) recordCustomCommand() {
+	username := m.rememberedUsername()
+	if !m.customSelected() || m.config.TestMode || username == "" {
+		return
+	}
+	m.loadCommandHistory(username)
+	m.commandHistory = cache.AddCommand(m.commandHistory, strings.TrimSpace(m.commandInput.Value()))
+	m.historyIndex = -1
+	if err := cache.SaveCommandHistory(username, m.commandHistory); err != nil {
+		logWarn("Failed to save command history: %v", err)
+	}
+}
+
+// renderCommandRow renders the command field shown for the custom command entry
+func (m model) renderCommandRow() string {
+	label := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.getFocusColor(FocusCommand)).
+		Width(10).
+		Render("Command:")
+	input := lipgloss.NewStyle().
+		Background(BgBase).
+		Padding(0, 1).
+		Render(m.commandInput.View())
+	return lipgloss.JoinHorizontal(lipgloss.Left, label, " ", input)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Nomadcxx/sysc-greet/internal/cache"
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+func TestConfigSessions(t *testing.T) {
+	extra := []sysconfig.ExtraSession{
+		{Name: "Shell", Command: "bash -l", Fallback: true},
+		{Name: "Debug Sway", Command: `sh -c "sway -d 2>/tmp/sway.log"`, Type: "wayland", Comment: "Sway with debug log"},
+		{Name: "Broken", Command: `wm "unterminated`},
+		{Command: "nameless"},
+	}
+
+	tests := []struct {
+		name      string
+		custom    bool
+		installed int
+		want      []string // Key of each session
+	}{
+		{"desktop sessions installed", false, 3, []string{"Wayland:debug-sway"}},
+		{"no desktop sessions", false, 0, []string{"TTY:shell", "Wayland:debug-sway"}},
+		{"custom command", true, 3, []string{"Wayland:debug-sway", "TTY:custom-command"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{}
+			config.System.Sessions = sysconfig.Sessions{Extra: extra, CustomCommand: tt.custom}
+			var keys []string
+			for _, s := range configSessions(config, tt.installed) {
+				keys = append(keys, s.Key())
+			}
+			if !reflect.DeepEqual(keys, tt.want) {
+				t.Fatalf("configSessions() = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+
+	config := Config{}
+	config.System.Sessions.Extra = extra[1:2]
+	if argv := configSessions(config, 1)[0].Argv(); !reflect.DeepEqual(argv, []string{"sh", "-c", "sway -d 2>/tmp/sway.log"}) {
+		t.Fatalf("unexpected command %q", argv)
+	}
+}
+
+// withCustomCommand offers the custom command entry in m and selects it
+func withCustomCommand(m model) model {
+	m.config.System.Sessions.CustomCommand = true
+	m.sessions = arrangeSessions(append(m.sessions, customSession()), m.config, m.sessionStats)
+	m.sessionIndex = len(m.sessions) - 1
+	m.selectedSession = &m.sessions[m.sessionIndex]
+	return m
+}
+
+func TestCustomCommandLogin(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	m := withCustomCommand(newTestModel(t, srv))
+	m.config.RememberUsername = true
+
+	m.focusState = FocusSession
+	m, _ = update(t, m, tea.KeyPressMsg{Code: tea.KeyTab})
+	if m.focusState != FocusCommand {
+		t.Fatalf("expected Tab to reach the command field, got focus %v", m.focusState)
+	}
+	m.commandInput.SetValue(`dbus-run-session sway -d --config "/etc/sway/debug config"`)
+	m, _ = update(t, m, keyEnter)
+	if m.focusState != FocusUsername {
+		t.Fatalf("expected Enter to move on to the username, got focus %v", m.focusState)
+	}
+
+	m, cmd := login(t, m, "alice", "hunter2")
+	msg := awaitAuth(t, cmd)
+	if msg != "success" {
+		t.Fatalf("expected success, got %v", msg)
+	}
+	req := lastRequest(t, srv)
+	want := []string{"dbus-run-session", "sway", "-d", "--config", "/etc/sway/debug config"}
+	if !reflect.DeepEqual(req.Cmd, want) {
+		t.Fatalf("StartSession cmd = %q, want %q", req.Cmd, want)
+	}
+	if !reflect.DeepEqual(req.Env, []string{"XDG_SESSION_TYPE=tty"}) {
+		t.Fatalf("unexpected environment %q", req.Env)
+	}
+
+	update(t, m, msg)
+	history, err := cache.LoadCommandHistory("alice")
+	if err != nil || len(history) != 1 || history[0] != m.commandInput.Value() {
+		t.Fatalf("expected the command in alice's history, got %q (%v)", history, err)
+	}
+}
+
+func TestCommandHistoryIsPerUser(t *testing.T) {
+	m := withCustomCommand(newTestModel(t, greetdtest.NewServer(t)))
+	m.config.RememberUsername = true
+	cache.SaveCommandHistory("alice", []string{"zsh"})
+	cache.SaveCommandHistory("bob", []string{"startx ~/.xinitrc-debug"})
+	m = m.focusCommand()
+
+	up := tea.KeyPressMsg{Code: tea.KeyUp}
+	steps := []struct {
+		username string
+		want     string
+	}{
+		{"", ""},
+		{"bob", "startx ~/.xinitrc-debug"},
+		{"alice", "zsh"},
+		{"carol", ""},
+	}
+	for _, step := range steps {
+		m.usernameInput.SetValue(step.username)
+		m.commandInput.SetValue("")
+		m, _ = update(t, m, up)
+		if got := m.commandInput.Value(); got != step.want {
+			t.Fatalf("%q: expected %q, got %q", step.username, step.want, got)
+		}
+	}
+}
+
+func TestCustomCommandRequired(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"", "Enter a command to run"},
+		{"sway 'unterminated", "Invalid command: unterminated single quote"},
+	}
+
+	for _, tt := range tests {
+		srv := greetdtest.NewServer(t)
+		m := withCustomCommand(newTestModel(t, srv))
+		m.commandInput.SetValue(tt.command)
+
+		m.usernameInput.SetValue("alice")
+		m, _ = update(t, m, keyEnter)
+		if m.errorMessage != tt.want || m.focusState != FocusCommand {
+			t.Fatalf("%q: expected %q at the command field, got %q focus %v", tt.command, tt.want, m.errorMessage, m.focusState)
+		}
+		if len(srv.Requests()) != 0 {
+			t.Fatalf("%q: expected greetd not to be contacted", tt.command)
+		}
+	}
+}
+
+func TestCommandHistory(t *testing.T) {
+	m := withCustomCommand(newTestModel(t, greetdtest.NewServer(t)))
+	m.commandHistory = []string{"zsh", "startx ~/.xinitrc-debug"}
+	m = m.focusCommand()
+
+	up := tea.KeyPressMsg{Code: tea.KeyUp}
+	down := tea.KeyPressMsg{Code: tea.KeyDown}
+	steps := []struct {
+		key  tea.Msg
+		want string
+	}{
+		{up, "zsh"},
+		{up, "startx ~/.xinitrc-debug"},
+		{up, "startx ~/.xinitrc-debug"},
+		{down, "zsh"},
+		{down, ""},
+		{down, ""},
+	}
+	for i, step := range steps {
+		m, _ = update(t, m, step.key)
+		if got := m.commandInput.Value(); got != step.want {
+			t.Fatalf("step %d: expected %q, got %q", i, step.want, got)
+		}
+	}
+
+	// j and k are typed, not used for browsing
+	m, _ = update(t, m, tea.KeyPressMsg{Code: 'k', Text: "k"})
+	if got := m.commandInput.Value(); got != "k" {
+		t.Fatalf("expected k to be typed, got %q", got)
+	}
+}
+
+func TestAddCommand(t *testing.T) {
+	var history []string
+	for i := 0; i < cache.MaxCommandHistory+2; i++ {
+		history = cache.AddCommand(history, strings.Repeat("x", i+1))
+	}
+	history = cache.AddCommand(history, "xxx")
+
+	if len(history) != cache.MaxCommandHistory {
+		t.Fatalf("expected %d commands, got %d", cache.MaxCommandHistory, len(history))
+	}
+	if history[0] != "xxx" || history[1] != strings.Repeat("x", cache.MaxCommandHistory+2) {
+		t.Fatalf("expected the re-used command first without duplicates, got %q", history[:2])
+	}
+	for _, c := range history[1:] {
+		if c == "xxx" {
+			t.Fatal("expected the older copy to be dropped")
+		}
+	}
+}
+
+func TestArrangeSessionsKeepsCustomLast(t *testing.T) {
+	config := Config{}
+	list := []sessions.Session{
+		customSession(),
+		{Name: "Sway", Exec: "sway", Type: "Wayland"},
+		{Name: "Awesome", Exec: "awesome", Type: "X11"},
+	}
+	list = arrangeSessions(list, config, cache.SessionStats{})
+	if !isCustomSession(&list[len(list)-1]) {
+		t.Fatalf("expected the custom command entry last, got %s", list[len(list)-1].Name)
+	}
+}
@@ -0,0 +1,499 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/BurntSushi/toml"
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+	"github.com/charmbracelet/colorprofile"
+)
+
+// doctor.go - "sysc-greet doctor": diagnosing the installation around the greeter
+// Where check validates sysc-greet's own files, doctor looks at the setup that
+// has to be right for the greeter to come up at all: greetd's config, PAM, the
+// greeter user's home and cache, the compositor, kitty and gSlapper, the greetd
+// socket and the terminal. Every result comes with a suggested fix
+
+const (
+	greetdConfigPath = "/etc/greetd/config.toml"
+	pamDir           = "/etc/pam.d"
+	// greetd runs the default session as this user when config.toml names none
+	defaultGreeterUser = "greeter"
+)
+
+type doctorStatus int
+
+const (
+	doctorPass doctorStatus = iota
+	doctorWarn
+	doctorFail
+)
+
+// doctorResult is one line of the doctor report
+type doctorResult struct {
+	status doctorStatus
+	name   string
+	detail string
+	fix    string // empty for passed checks
+}
+
+func (r doctorResult) String() string {
+	label := [...]string{"PASS", "WARN", "FAIL"}[r.status]
+	s := fmt.Sprintf("%s  %s: %s", label, r.name, r.detail)
+	if r.fix != "" {
+		s += "\n      fix: " + r.fix
+	}
+	return s
+}
+
+// doctor inspects the system; the lookups are fields so tests can fake them
+type doctor struct {
+	greetdConfig  string
+	pamDir        string
+	dataDir       string
+	stateDir      string // [paths] state_dir, empty for ~/.cache/sysc-greet
+	wallpaperDirs []string
+	profile       colorprofile.Profile
+	getenv        func(string) string
+	lookPath      func(string) (string, error)
+	lookupUser    func(string) (*user.User, error)
+
+	results []doctorResult
+}
+
+func (d *doctor) pass(name, format string, args ...any) {
+	d.results = append(d.results, doctorResult{doctorPass, name, fmt.Sprintf(format, args...), ""})
+}
+
+func (d *doctor) warn(name, fix, format string, args ...any) {
+	d.results = append(d.results, doctorResult{doctorWarn, name, fmt.Sprintf(format, args...), fix})
+}
+
+func (d *doctor) fail(name, fix, format string, args ...any) {
+	d.results = append(d.results, doctorResult{doctorFail, name, fmt.Sprintf(format, args...), fix})
+}
+
+// greeterSetup is what doctor learns about how greetd starts the greeter
+type greeterSetup struct {
+	user             string
+	home             string // HOME set by the compositor config, if any
+	compositorConfig string
+	words            []string // Words of the compositor config, or of command= when it starts sysc-greet directly
+}
+
+// runDoctorCommand implements "sysc-greet doctor [-greetd-config PATH] [-config PATH]"
+func runDoctorCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	greetdConfig := fs.String("greetd-config", greetdConfigPath, "greetd configuration file")
+	configPath := fs.String("config", sysconfig.DefaultPath, "System configuration file, for [paths]")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "Usage: sysc-greet doctor [-greetd-config PATH] [-config PATH]\n")
+		return 2
+	}
+
+	d := &doctor{
+		greetdConfig:  *greetdConfig,
+		pamDir:        pamDir,
+		dataDir:       dataDir,
+		wallpaperDirs: wallpaperDirectories(),
+		profile:       colorprofile.Detect(os.Stdout, os.Environ()),
+		getenv:        os.Getenv,
+		lookPath:      exec.LookPath,
+		lookupUser:    user.Lookup,
+	}
+	// Problems in the file are check's business, only [paths] is needed here
+	system, _ := sysconfig.Load(*configPath)
+	if dir := system.Paths.DataDir; dir != "" {
+		d.dataDir = strings.TrimRight(dir, "/")
+	}
+	if len(system.Paths.WallpaperDirs) > 0 {
+		d.wallpaperDirs = system.Paths.WallpaperDirs
+	}
+	d.stateDir = system.Paths.StateDir
+
+	d.run()
+	return d.report(stdout)
+}
+
+// run performs every check in the order the greeter needs them
+func (d *doctor) run() {
+	setup := d.checkGreetdConfig()
+	d.checkPAM()
+	d.checkGreeterUser(setup)
+	d.checkGreeterCommands(setup)
+	d.checkDataDir()
+	d.checkWallpapers()
+	d.checkGreetdSocket()
+	d.checkTerminal()
+}
+
+// report prints the results and a summary, and returns the exit status
+func (d *doctor) report(w io.Writer) int {
+	var counts [3]int
+	for _, r := range d.results {
+		fmt.Fprintln(w, r)
+		counts[r.status]++
+	}
+	fmt.Fprintf(w, "%d passed, %d warning%s, %d failed\n",
+		counts[doctorPass], counts[doctorWarn], plural(counts[doctorWarn]), counts[doctorFail])
+	if counts[doctorFail] > 0 {
+		return 1
+	}
+	return 0
+}
+
+// checkGreetdConfig checks greetd's [terminal] and [default_session], and
+// follows command= to the compositor config that starts the greeter
+func (d *doctor) checkGreetdConfig() greeterSetup {
+	const name = "greetd config"
+	setup := greeterSetup{user: defaultGreeterUser}
+	fixConfig := "see Installation in the docs for a working " + d.greetdConfig
+
+	var cfg struct {
+		Terminal struct {
+			VT any `toml:"vt"`
+		} `toml:"terminal"`
+		DefaultSession struct {
+			Command string `toml:"command"`
+			User    string `toml:"user"`
+		} `toml:"default_session"`
+	}
+	if _, err := toml.DecodeFile(d.greetdConfig, &cfg); err != nil {
+		d.fail(name, fixConfig, "cannot read %s: %v", d.greetdConfig, err)
+		return setup
+	}
+	if cfg.DefaultSession.User != "" {
+		setup.user = cfg.DefaultSession.User
+	}
+	if cfg.Terminal.VT == nil {
+		d.fail(name, "add vt = 1 to [terminal] in "+d.greetdConfig, "[terminal] vt is not set, greetd will not start")
+	}
+
+	command := cfg.DefaultSession.Command
+	if command == "" {
+		d.fail(name, fixConfig, "[default_session] has no command")
+		return setup
+	}
+	argv, err := sessions.SplitCommand(command, "")
+	if err != nil || len(argv) == 0 {
+		d.fail(name, fixConfig, "cannot parse command = %q: %v", command, err)
+		return setup
+	}
+	if _, err := d.lookPath(argv[0]); err != nil {
+		d.fail(name, "install "+argv[0]+" or correct command= in "+d.greetdConfig,
+			"command = %q starts %s, which is not installed", command, argv[0])
+		return setup
+	}
+	d.pass(name, "%s runs %q as %s", d.greetdConfig, command, setup.user)
+
+	if filepath.Base(argv[0]) == "sysc-greet" {
+		setup.words = argv
+		return setup
+	}
+	setup.compositorConfig = compositorConfigArg(argv)
+	if setup.compositorConfig == "" {
+		d.warn("compositor config", "start the compositor with -c and one of the greeter configs in /etc/greetd",
+			"%s is started without -c, cannot tell how it starts sysc-greet", argv[0])
+		return setup
+	}
+	data, err := os.ReadFile(setup.compositorConfig)
+	if err != nil {
+		d.fail("compositor config", "reinstall the greeter config for "+filepath.Base(argv[0])+", see the Compositors docs",
+			"cannot read %s: %v", setup.compositorConfig, err)
+		return setup
+	}
+	setup.words = configWords(string(data))
+	for _, word := range setup.words {
+		if v, ok := strings.CutPrefix(word, "HOME="); ok {
+			setup.home = v
+		}
+	}
+	d.pass("compositor config", "%s", setup.compositorConfig)
+	return setup
+}
+
+// compositorConfigArg returns the config file given with -c or --config in argv
+func compositorConfigArg(argv []string) string {
+	for i, arg := range argv {
+		if v, ok := strings.CutPrefix(arg, "--config="); ok {
+			return v
+		}
+		if (arg == "-c" || arg == "--config") && i+1 < len(argv) {
+			return argv[i+1]
+		}
+	}
+	return ""
+}
+
+// configWords splits a compositor config into shell-ish words, which is
+// enough to find the commands and variables on its exec lines. Comment lines
+// (# and //) are skipped
+func configWords(data string) []string {
+	var words []string
+	for line := range strings.Lines(data) {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		words = append(words, strings.FieldsFunc(line, func(r rune) bool {
+			return strings.ContainsRune(" \t\n\"';&|", r)
+		})...)
+	}
+	return words
+}
+
+// checkPAM checks that greetd has a PAM service that authenticates
+func (d *doctor) checkPAM() {
+	const name = "PAM service"
+	path := filepath.Join(d.pamDir, "greetd")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		d.fail(name, "reinstall greetd, which ships "+path+", or create it from your distribution's login service",
+			"cannot read %s: %v", path, err)
+		return
+	}
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "auth") || strings.HasPrefix(line, "-auth") || strings.HasPrefix(line, "@include") {
+			d.pass(name, "%s", path)
+			return
+		}
+	}
+	d.fail(name, "add auth lines to "+path+", e.g. auth include login", "%s has no auth lines, every login will fail", path)
+}
+
+// checkGreeterUser checks the greeter user and that it can write its home and cache
+func (d *doctor) checkGreeterUser(setup greeterSetup) {
+	u, err := d.lookupUser(setup.user)
+	if err != nil {
+		fix := "useradd -M -G video,render,input -s /usr/bin/nologin " + setup.user
+		if _, ok := err.(user.UnknownUserError); ok {
+			d.fail("greeter user", fix, "user %q does not exist", setup.user)
+		} else {
+			d.fail("greeter user", fix, "cannot look up %q: %v", setup.user, err)
+		}
+		return
+	}
+	d.pass("greeter user", "%s (uid %s)", u.Username, u.Uid)
+
+	home := u.HomeDir
+	if setup.home != "" {
+		home = setup.home
+	}
+	fixOwner := fmt.Sprintf("mkdir -p %s && chown -R %s: %s", home, u.Username, home)
+	info, err := os.Stat(home)
+	if err != nil || !info.IsDir() {
+		d.fail("greeter home", fixOwner, "%s does not exist", home)
+		return
+	}
+	if !writableBy(info, u.Uid) {
+		d.fail("greeter home", fixOwner, "%s is not writable by %s (owned by uid %d)", home, u.Username, fileOwner(info))
+		return
+	}
+	d.pass("greeter home", "%s", home)
+
+	// internal/cache keeps the preferences in $HOME/.cache/sysc-greet, or [paths] state_dir
+	cacheDir := filepath.Join(home, ".cache", "sysc-greet")
+	if d.stateDir != "" {
+		cacheDir = d.stateDir
+	}
+	fixCache := fmt.Sprintf("chown -R %s: %s", u.Username, cacheDir)
+	info, err = os.Stat(cacheDir)
+	if os.IsNotExist(err) && d.stateDir != "" {
+		d.fail("cache directory", fmt.Sprintf("install -d -o %s -g %s -m 0700 %s", u.Username, u.Username, cacheDir),
+			"[paths] state_dir %s does not exist", cacheDir)
+		return
+	}
+	if os.IsNotExist(err) {
+		parent := filepath.Dir(cacheDir)
+		if info, err := os.Stat(parent); err == nil && !writableBy(info, u.Uid) {
+			d.fail("cache directory", fmt.Sprintf("chown -R %s: %s", u.Username, parent),
+				"%s is not writable by %s, so %s cannot be created", parent, u.Username, cacheDir)
+			return
+		}
+		d.pass("cache directory", "%s will be created on first login", cacheDir)
+		return
+	}
+	if err != nil || !writableBy(info, u.Uid) {
+		d.fail("cache directory", fixCache, "%s is not writable by %s, preferences will not be saved", cacheDir, u.Username)
+		return
+	}
+	entries, _ := os.ReadDir(cacheDir)
+	for _, e := range entries {
+		info, err := e.Info()
+		if err == nil && info.Mode().IsRegular() && !writableBy(info, u.Uid) {
+			d.fail("cache directory", fixCache, "%s is not writable by %s, preferences will not be saved",
+				filepath.Join(cacheDir, e.Name()), u.Username)
+			return
+		}
+	}
+	d.pass("cache directory", "%s", cacheDir)
+}
+
+// writableBy reports whether the owner bits let uid write to the file
+func writableBy(info os.FileInfo, uid string) bool {
+	return strconv.Itoa(fileOwner(info)) == uid && info.Mode().Perm()&0200 != 0
+}
+
+// fileOwner returns the uid owning the file
+func fileOwner(info os.FileInfo) int {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid)
+	}
+	return -1
+}
+
+// checkGreeterCommands checks the programs the compositor config starts
+func (d *doctor) checkGreeterCommands(setup greeterSetup) {
+	if setup.words == nil {
+		return
+	}
+	var greeter, kitty, kittyConfig, gslapper string
+	for _, word := range setup.words {
+		switch filepath.Base(word) {
+		case "sysc-greet":
+			greeter = word
+		case "kitty":
+			kitty = word
+		case "gslapper":
+			gslapper = word
+		}
+		if v, ok := strings.CutPrefix(word, "--config="); ok && kitty != "" && kittyConfig == "" {
+			kittyConfig = v
+		}
+	}
+
+	where := setup.compositorConfig
+	if where == "" {
+		where = d.greetdConfig
+	}
+	switch {
+	case greeter == "":
+		d.fail("sysc-greet", "start kitty with sysc-greet from "+where+", see the Compositors docs",
+			"%s does not start sysc-greet", where)
+	case d.executable(greeter):
+		d.pass("sysc-greet", "%s", greeter)
+	default:
+		d.fail("sysc-greet", "correct the path in "+where+" (the binary may be in /usr/bin)",
+			"%s starts %s, which is not installed", where, greeter)
+	}
+
+	if kitty != "" {
+		if d.executable(kitty) {
+			d.pass("kitty", "%s", kitty)
+		} else {
+			d.fail("kitty", "install kitty, the greeter runs inside it", "%s starts kitty, which is not installed", where)
+		}
+		if kittyConfig != "" {
+			if _, err := os.Stat(kittyConfig); err != nil {
+				d.warn("kitty", "install config/kitty-greeter.conf as "+kittyConfig,
+					"%s does not exist, kitty falls back to its defaults", kittyConfig)
+			}
+		}
+	}
+
+	if gslapper != "" {
+		if d.executable(gslapper) {
+			d.pass("gslapper", "%s", gslapper)
+		} else {
+			d.warn("gslapper", "install gSlapper (https://github.com/Nomadcxx/gSlapper) or drop it from "+where,
+				"%s starts gslapper, which is not installed, so there are no wallpapers", where)
+		}
+	}
+}
+
+// executable reports whether a command from a config can be run: absolute
+// paths must exist, other names are looked up in $PATH
+func (d *doctor) executable(command string) bool {
+	if filepath.IsAbs(command) {
+		info, err := os.Stat(command)
+		return err == nil && !info.IsDir() && info.Mode().Perm()&0111 != 0
+	}
+	_, err := d.lookPath(command)
+	return err == nil
+}
+
+// checkDataDir checks that the shipped assets are where the greeter looks
+func (d *doctor) checkDataDir() {
+	dir := filepath.Join(d.dataDir, "ascii_configs")
+	if _, err := os.Stat(dir); err != nil {
+		d.fail("data directory", "reinstall sysc-greet, or point [paths] data_dir at its data",
+			"%s is missing, the greeter has no ASCII art", dir)
+		return
+	}
+	d.pass("data directory", "%s", d.dataDir)
+}
+
+// checkWallpapers checks that the wallpaper menu has something to offer
+func (d *doctor) checkWallpapers() {
+	const name = "wallpapers"
+	for _, dir := range d.wallpaperDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		n := 0
+		for _, e := range entries {
+			if !e.IsDir() && isWallpaperFile(e.Name()) {
+				n++
+			}
+		}
+		if n > 0 {
+			d.pass(name, "%d in %s", n, dir)
+			return
+		}
+	}
+	d.warn(name, "copy images or videos to "+d.wallpaperDirs[0],
+		"no wallpapers in %s", strings.Join(d.wallpaperDirs, ", "))
+}
+
+// checkGreetdSocket checks the socket the greeter talks to greetd through
+func (d *doctor) checkGreetdSocket() {
+	const name = "greetd socket"
+	sock := d.getenv("GREETD_SOCK")
+	if sock == "" {
+		d.warn(name, "expected outside the greeter session; use sysc-greet --test to try the greeter from a shell",
+			"GREETD_SOCK is not set, the greeter exits at once without it")
+		return
+	}
+	info, err := os.Stat(sock)
+	if err != nil || info.Mode().Type() != os.ModeSocket {
+		d.fail(name, "restart greetd and do not set GREETD_SOCK yourself", "GREETD_SOCK=%s is not a socket", sock)
+		return
+	}
+	d.pass(name, "%s", sock)
+}
+
+// checkTerminal reports the color support detected for this terminal
+func (d *doctor) checkTerminal() {
+	const name = "terminal"
+	term := d.getenv("TERM")
+	switch d.profile {
+	case colorprofile.NoTTY:
+		d.warn(name, "run doctor from the terminal the greeter runs in, e.g. kitty",
+			"output is not a terminal, cannot detect colors")
+	case colorprofile.Ascii:
+		d.warn(name, "run the greeter in kitty, or set TERM to match your terminal",
+			"no colors (TERM=%s), themes and effects are drawn in plain text", term)
+	case colorprofile.ANSI:
+		d.warn(name, "run the greeter in kitty, or set COLORTERM=truecolor if the terminal supports it",
+			"16 colors (TERM=%s), themes are approximated", term)
+	case colorprofile.ANSI256:
+		d.pass(name, "256 colors (TERM=%s), themes are approximated", term)
+	default:
+		d.pass(name, "true color (TERM=%s)", term)
+	}
+}
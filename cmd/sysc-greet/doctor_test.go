@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/colorprofile"
+)
+
+// newTestDoctor returns a doctor looking at dir, where only the commands in
+// installed exist and the greeter user is the current user
+func newTestDoctor(t *testing.T, dir string, installed ...string) *doctor {
+	t.Helper()
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("no current user: %v", err)
+	}
+	env := map[string]string{"TERM": "xterm-kitty"}
+	return &doctor{
+		greetdConfig:  filepath.Join(dir, "etc/greetd/config.toml"),
+		pamDir:        filepath.Join(dir, "etc/pam.d"),
+		dataDir:       filepath.Join(dir, "share"),
+		wallpaperDirs: []string{filepath.Join(dir, "home/Pictures/wallpapers")},
+		profile:       colorprofile.TrueColor,
+		getenv:        func(key string) string { return env[key] },
+		lookPath: func(name string) (string, error) {
+			for _, c := range installed {
+				if c == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		},
+		lookupUser: func(name string) (*user.User, error) {
+			if name != "greeter" {
+				return nil, user.UnknownUserError(name)
+			}
+			u := *current
+			u.Username = name
+			return &u, nil
+		},
+	}
+}
+
+func runDoctor(d *doctor) (int, string) {
+	var out bytes.Buffer
+	d.run()
+	code := d.report(&out)
+	return code, out.String()
+}
+
+func TestDoctorHealthy(t *testing.T) {
+	dir := t.TempDir()
+	greeter := filepath.Join(dir, "bin/sysc-greet")
+	writeFiles(t, dir, map[string]string{
+		"etc/greetd/config.toml": "[terminal]\nvt = 1\n\n[default_session]\ncommand = \"niri -c " + dir + "/etc/greetd/niri.kdl\"\nuser = \"greeter\"\n",
+		"etc/greetd/niri.kdl": `// spawn-at-startup "not-installed"
+spawn-at-startup "gslapper" "-f" "*" "/usr/share/sysc-greet/wallpapers/sysc-greet-default.png"
+spawn-sh-at-startup "HOME=` + dir + `/home kitty --config=` + dir + `/etc/greetd/kitty.conf ` + greeter + `; niri msg action quit"
+`,
+		"etc/greetd/kitty.conf":                  "",
+		"etc/pam.d/greetd":                       "#%PAM-1.0\nauth       include      login\n",
+		"bin/sysc-greet":                         "",
+		"share/ascii_configs/niri.conf":          "",
+		"home/Pictures/wallpapers/beach.png":     "",
+		"home/Pictures/wallpapers/notes.txt":     "",
+		"home/.cache/sysc-greet/preferences":     "{}",
+		"home/Pictures/wallpapers/sub/video.mp4": "",
+	})
+	os.Chmod(greeter, 0755)
+
+	sock := filepath.Join(dir, "greetd.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	d := newTestDoctor(t, dir, "niri", "kitty", "gslapper")
+	d.getenv = func(key string) string { return map[string]string{"GREETD_SOCK": sock, "TERM": "xterm-kitty"}[key] }
+	code, out := runDoctor(d)
+	if code != 0 || strings.Contains(out, "WARN") || strings.Contains(out, "FAIL") {
+		t.Fatalf("expected every check to pass, got %d:\n%s", code, out)
+	}
+	for _, want := range []string{
+		"PASS  greetd config:",
+		"PASS  cache directory: " + dir + "/home/.cache/sysc-greet",
+		"PASS  sysc-greet: " + greeter,
+		"PASS  wallpapers: 1 in",
+		"PASS  terminal: true color (TERM=xterm-kitty)",
+		"PASS  greetd socket: " + sock,
+		"13 passed, 0 warnings, 0 failed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in the report:\n%s", want, out)
+		}
+	}
+}
+
+func TestDoctorProblems(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"etc/greetd/config.toml": "[default_session]\ncommand = \"sway -c " + dir + "/etc/greetd/sway\"\n",
+		"etc/greetd/sway": `exec gslapper -f '*' /usr/share/sysc-greet/wallpapers/sysc-greet-default.png
+exec "HOME=` + dir + `/home kitty --config=/etc/greetd/kitty.conf /usr/local/bin/sysc-greet-missing/sysc-greet; swaymsg exit"
+`,
+		"etc/pam.d/greetd":                   "account include login\n",
+		"home/.cache/sysc-greet/preferences": "{}",
+	})
+	os.Chmod(filepath.Join(dir, "home/.cache/sysc-greet/preferences"), 0444)
+
+	d := newTestDoctor(t, dir, "sway")
+	d.profile = colorprofile.ANSI
+	code, out := runDoctor(d)
+	if code != 1 {
+		t.Fatalf("expected exit status 1, got %d:\n%s", code, out)
+	}
+	for _, want := range []string{
+		"FAIL  greetd config: [terminal] vt is not set",
+		"fix: add vt = 1 to [terminal]",
+		"FAIL  PAM service: " + dir + "/etc/pam.d/greetd has no auth lines",
+		"FAIL  cache directory: " + dir + "/home/.cache/sysc-greet/preferences is not writable by greeter",
+		"fix: chown -R greeter: " + dir + "/home/.cache/sysc-greet",
+		"FAIL  sysc-greet: " + dir + "/etc/greetd/sway starts /usr/local/bin/sysc-greet-missing/sysc-greet, which is not installed",
+		"FAIL  kitty: " + dir + "/etc/greetd/sway starts kitty, which is not installed",
+		"WARN  kitty: /etc/greetd/kitty.conf does not exist",
+		"WARN  gslapper:",
+		"FAIL  data directory:",
+		"WARN  wallpapers: no wallpapers in",
+		"WARN  greetd socket: GREETD_SOCK is not set",
+		"WARN  terminal: 16 colors",
+		"4 passed, 5 warnings, 6 failed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in the report:\n%s", want, out)
+		}
+	}
+}
+
+func TestDoctorUnknownUser(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"etc/greetd/config.toml": "[terminal]\nvt = \"next\"\n\n[default_session]\ncommand = \"sysc-greet --theme nord\"\nuser = \"kiosk\"\n",
+	})
+	code, out := runDoctor(newTestDoctor(t, dir, "sysc-greet"))
+	if code != 1 || !strings.Contains(out, `FAIL  greeter user: user "kiosk" does not exist`) {
+		t.Fatalf("expected the missing user to fail, got %d:\n%s", code, out)
+	}
+	// sysc-greet started directly by greetd, without a compositor
+	if !strings.Contains(out, "PASS  sysc-greet: sysc-greet") || strings.Contains(out, "compositor config") {
+		t.Fatalf("expected command= itself to be searched for sysc-greet:\n%s", out)
+	}
+}
+
+func TestDoctorStateDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"etc/greetd/config.toml": "[terminal]\nvt = 1\n\n[default_session]\ncommand = \"sysc-greet\"\n",
+	})
+	d := newTestDoctor(t, dir, "sysc-greet")
+	d.lookupUser = func(name string) (*user.User, error) {
+		return &user.User{Username: name, Uid: strconv.Itoa(os.Getuid()), HomeDir: dir}, nil
+	}
+	d.stateDir = filepath.Join(dir, "var/lib/sysc-greet")
+	_, out := runDoctor(d)
+	want := "FAIL  cache directory: [paths] state_dir " + d.stateDir + " does not exist\n      fix: install -d -o greeter -g greeter -m 0700 " + d.stateDir
+	if !strings.Contains(out, want) {
+		t.Fatalf("expected %q in the report:\n%s", want, out)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// guest.go - [guest] passwordless guest entry and kiosk lockdown
+// The guest entry is listed first in the session list. While it is selected the
+// username field is hidden and Enter logs in as the guest account with the fixed
+// command; greetd starts it without prompts when PAM lets the account in
+// (e.g. pam_permit), and any prompt PAM does send is shown as usual
+
+// guestSessionID is the desktop file ID of the guest entry
+const guestSessionID = "guest"
+
+// guestSession builds the guest entry from the [guest] section
+// Returns false when the entry is disabled or its command cannot be parsed
+func guestSession(config Config) (sessions.Session, bool) {
+	g := config.System.Guest
+	if !g.Enabled {
+		return sessions.Session{}, false
+	}
+	command, err := sessions.ParseExec(g.Command, sessions.ExecContext{Name: g.Label()})
+	if err == nil && len(command) == 0 {
+		err = fmt.Errorf("empty command")
+	}
+	if err != nil {
+		logWarn("Guest entry disabled, invalid command %q: %v", g.Command, err)
+		return sessions.Session{}, false
+	}
+
+	return sessions.Session{
+		Name:    g.Label(),
+		Exec:    g.Command,
+		Type:    sessionTypeName(g.Type, "Wayland"),
+		Comment: "Guest session as " + g.Account(),
+		Command: command,
+		FileID:  guestSessionID,
+	}, true
+}
+
+// isGuestSession reports whether s is the guest entry rather than an installed session
+func isGuestSession(s *sessions.Session) bool {
+	return s != nil && s.FileID == guestSessionID && s.Path == ""
+}
+
+// guestSelected reports whether the guest entry is the selected session
+func (m model) guestSelected() bool {
+	return m.config.System.Guest.Enabled && isGuestSession(m.selectedSession)
+}
+
+// startGuest logs in as the guest account without asking for a username
+func (m model) startGuest() (model, tea.Cmd) {
+	user := m.config.System.Guest.Account()
+	greeterLog.Info("Starting guest session", "user", user)
+	m.usernameInput.SetValue(user)
+	return m.submitLogin(user, nil)
+}
+
+// startGuestCountdown selects the guest entry and arms its autostart countdown
+// An autologin already armed takes precedence
+func (m *model) startGuestCountdown() {
+	g := m.config.System.Guest
+	if !g.Enabled || g.Autostart <= 0 || !m.autologinAt.IsZero() {
+		return
+	}
+	for i := range m.sessions {
+		if isGuestSession(&m.sessions[i]) {
+			m.sessionIndex = i
+			m.selectedSession = &m.sessions[i]
+			m.autologinAt = time.Now().Add(time.Duration(g.Autostart) * time.Second)
+			m.mode = ModeLogin
+			m.focusState = FocusUsername
+			m.passwordInput.Blur()
+			greeterLog.Debug("Guest autostart armed", "delay", g.Autostart)
+			return
+		}
+	}
+}
+
+// menuLocked reports whether [guest] lock_menu disables the F1 menu
+func (m model) menuLocked() bool {
+	return m.config.System.Guest.LockMenu
+}
+
+// powerLocked reports whether [guest] lock_power disables the F4 power menu
+func (m model) powerLocked() bool {
+	return m.config.System.Guest.LockPower
+}
+
+// functionKeyHelp lists the function keys that are not locked
+func (m model) functionKeyHelp() string {
+	var keys []string
+	if !m.menuLocked() {
+		keys = append(keys, "F1 Menu")
+	}
+	keys = append(keys, "F2 Sessions", "F3 Notes")
+	if !m.powerLocked() {
+		keys = append(keys, "F4 Power")
+	}
+	return strings.Join(keys, " • ")
+}
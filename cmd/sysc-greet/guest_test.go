@@ -0,0 +1,194 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nomadcxx/sysc-greet/internal/cache"
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+func TestGuestSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		guest    sysconfig.Guest
+		ok       bool
+		wantName string
+		wantType string
+		wantArgv []string
+	}{
+		{"disabled", sysconfig.Guest{Command: "cage -- firefox"}, false, "", "", nil},
+		{"defaults", sysconfig.Guest{Enabled: true, Command: "cage -- firefox --kiosk"}, true,
+			"Guest", "Wayland", []string{"cage", "--", "firefox", "--kiosk"}},
+		{"quoted x11", sysconfig.Guest{Enabled: true, Name: "Library", Type: "x11", Command: `sh -c "exec chromium --kiosk"`}, true,
+			"Library", "X11", []string{"sh", "-c", "exec chromium --kiosk"}},
+		{"empty command", sysconfig.Guest{Enabled: true}, false, "", "", nil},
+		{"unterminated quote", sysconfig.Guest{Enabled: true, Command: `sh -c "exec`}, false, "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{}
+			config.System.Guest = tt.guest
+			s, ok := guestSession(config)
+			if ok != tt.ok {
+				t.Fatalf("guestSession() ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if s.Name != tt.wantName || s.Type != tt.wantType || !reflect.DeepEqual(s.Argv(), tt.wantArgv) {
+				t.Fatalf("unexpected guest session %+v", s)
+			}
+			if !isGuestSession(&s) {
+				t.Fatal("expected the entry to be recognised as the guest session")
+			}
+		})
+	}
+}
+
+// withGuest offers the guest entry in m and selects it
+func withGuest(t *testing.T, m model, g sysconfig.Guest) model {
+	t.Helper()
+	g.Enabled = true
+	m.config.System.Guest = g
+	guest, ok := guestSession(m.config)
+	if !ok {
+		t.Fatal("expected a guest session")
+	}
+	m.sessions = arrangeSessions(append(m.sessions, guest), m.config, m.sessionStats)
+	if !isGuestSession(&m.sessions[0]) {
+		t.Fatalf("expected the guest entry first, got %s", m.sessions[0].Name)
+	}
+	m.sessionIndex = 0
+	m.selectedSession = &m.sessions[0]
+	return m
+}
+
+func TestGuestLogin(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("visitor")
+	m := withGuest(t, newTestModel(t, srv), sysconfig.Guest{User: "visitor", Command: "cage -- firefox --kiosk"})
+
+	m, _ = update(t, m, tea.KeyPressMsg{Code: 'x', Text: "x"})
+	if m.usernameInput.Value() != "" {
+		t.Fatalf("expected the hidden username field to ignore typing, got %q", m.usernameInput.Value())
+	}
+
+	m, cmd := update(t, m, keyEnter)
+	if m.mode != ModeLoading {
+		t.Fatalf("expected Enter to start the guest session, got mode %s", m.mode)
+	}
+	if msg := awaitAuth(t, cmd); msg != "success" {
+		t.Fatalf("expected success, got %v", msg)
+	}
+
+	types := srv.RequestTypes()
+	if !reflect.DeepEqual(types, []ipc.RequestType{ipc.CreateSessionRequest, ipc.StartSessionRequest}) {
+		t.Fatalf("expected a prompt-free login, got %v", types)
+	}
+	reqs := srv.Requests()
+	if reqs[0].Username != "visitor" {
+		t.Fatalf("expected the guest account, got %q", reqs[0].Username)
+	}
+	if got := reqs[1].Cmd; strings.Join(got, " ") != "cage -- firefox --kiosk" {
+		t.Fatalf("expected the guest command, got %v", got)
+	}
+}
+
+func TestGuestAutostart(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("guest")
+	m := newTestModel(t, srv)
+	m.config.System.Guest = sysconfig.Guest{Enabled: true, Command: "cage -- firefox", Autostart: 30}
+	guest, _ := guestSession(m.config)
+	m.sessions = append(m.sessions, guest)
+
+	m.startGuestCountdown()
+	if !m.guestSelected() || m.autologinAt.IsZero() {
+		t.Fatal("expected the guest entry to be selected with a countdown")
+	}
+	if remaining := m.autologinRemaining(); remaining < 29 || remaining > 30 {
+		t.Fatalf("expected about 30 seconds left, got %d", remaining)
+	}
+
+	m.autologinAt = time.Now().Add(-time.Second)
+	m, cmd := update(t, m, tickMsg(time.Now()))
+	if m.mode != ModeLoading {
+		t.Fatalf("expected the expired countdown to start the guest session, got mode %s", m.mode)
+	}
+	awaitRequest(t, srv, cmd, ipc.StartSessionRequest)
+	if got := lastRequest(t, srv).Cmd; strings.Join(got, " ") != "cage -- firefox" {
+		t.Fatalf("expected the guest command, got %v", got)
+	}
+}
+
+func TestGuestAutostartCancelled(t *testing.T) {
+	m := newTestModel(t, greetdtest.NewServer(t))
+	m.config.System.Guest = sysconfig.Guest{Enabled: true, Command: "cage -- firefox", Autostart: 30}
+	guest, _ := guestSession(m.config)
+	m.sessions = append(m.sessions, guest)
+
+	m.startGuestCountdown()
+	m, _ = update(t, m, tea.KeyPressMsg{Code: 'a', Text: "a"})
+	if !m.autologinAt.IsZero() || !m.guestSelected() {
+		t.Fatal("expected a key to cancel the countdown and keep the guest entry selected")
+	}
+}
+
+func TestKioskLockdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		guest    sysconfig.Guest
+		key      rune
+		wantMode ViewMode
+	}{
+		{"menu open", sysconfig.Guest{}, tea.KeyF1, ModeMenu},
+		{"menu locked", sysconfig.Guest{LockMenu: true}, tea.KeyF1, ModeLogin},
+		{"power open", sysconfig.Guest{}, tea.KeyF4, ModePower},
+		{"power locked", sysconfig.Guest{LockPower: true}, tea.KeyF4, ModeLogin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, greetdtest.NewServer(t))
+			m.config.System.Guest = tt.guest
+			m, _ = update(t, m, tea.KeyPressMsg{Code: tt.key})
+			if m.mode != tt.wantMode {
+				t.Fatalf("expected mode %s, got %s", tt.wantMode, m.mode)
+			}
+		})
+	}
+
+	m := newTestModel(t, greetdtest.NewServer(t))
+	m.config.System.Guest = sysconfig.Guest{LockMenu: true, LockPower: true}
+	if help := m.renderMainHelp(); strings.Contains(help, "F1") || strings.Contains(help, "F4") {
+		t.Fatalf("expected locked keys to be left out of the help, got %q", help)
+	}
+}
+
+func TestArrangeSessionsKeepsGuestFirst(t *testing.T) {
+	config := Config{}
+	config.System.Guest = sysconfig.Guest{Enabled: true, Command: "cage -- firefox"}
+	guest, _ := guestSession(config)
+	list := []sessions.Session{
+		{Name: "Sway", Exec: "sway", Type: "Wayland"},
+		guest,
+		{Name: "Awesome", Exec: "awesome", Type: "X11"},
+	}
+
+	list = arrangeSessions(list, config, cache.SessionStats{})
+	var names []string
+	for _, s := range list {
+		names = append(names, s.Name)
+	}
+	if want := []string{"Guest", "Awesome", "Sway"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("arrangeSessions() = %v, want %v", names, want)
+	}
+}
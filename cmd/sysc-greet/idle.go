@@ -0,0 +1,66 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// idle.go - [idle] policies for credentials left in an unattended form
+// A typed password is cleared after clear_password_after seconds without keystrokes.
+// When the screensaver starts, the open greetd session is cancelled and the form
+// goes back to the username field (reset_on_screensaver, default on), optionally
+// forgetting the username too (forget_username)
+
+// clearIdlePassword wipes typed passwords once no key was pressed for clear_password_after seconds
+func (m *model) clearIdlePassword(now time.Time) {
+	after := m.config.System.Idle.ClearPasswordAfter
+	if after <= 0 || now.Sub(m.lastKeyPress) < time.Duration(after)*time.Second {
+		return
+	}
+	if m.passwordInput.Len() == 0 && m.newPasswordInput.Len() == 0 && m.confirmPasswordInput.Len() == 0 {
+		return
+	}
+	logDebug("Clearing password after %d seconds without keystrokes", after)
+	// pendingConfirm is kept: it belongs to a request already sent to greetd
+	m.passwordInput.Reset()
+	m.newPasswordInput.Reset()
+	m.confirmPasswordInput.Reset()
+}
+
+// resetForScreensaver applies the idle policy when the screensaver starts
+// Returns the command cancelling the open greetd session, if any
+func (m *model) resetForScreensaver() tea.Cmd {
+	m.wipeSecrets()
+
+	idle := m.config.System.Idle
+	if !idle.ResetsOnScreensaver() {
+		// Leave the conversation open and come back to where the user was
+		m.resumeMode = m.mode
+		return nil
+	}
+
+	var cancelCmd tea.Cmd
+	*m, cancelCmd = m.abortAuth()
+	m.resumeMode = ModeLogin
+	m.errorMessage = ""
+	m.focusState = FocusUsername
+	m.passwordInput.Blur()
+	m.usernameInput.Focus()
+	if idle.ForgetUsername {
+		m.usernameInput.SetValue("")
+		m.userIndex = 0
+		m.userPicked = false
+	}
+	return cancelCmd
+}
+
+// exitScreensaver returns from the screensaver to the form
+func (m *model) exitScreensaver() {
+	m.mode = m.resumeMode
+	if m.mode == "" {
+		m.mode = ModeLogin
+	}
+	m.resumeMode = ""
+	m.idleTimer = time.Now()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nomadcxx/sysc-greet/internal/ipc"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+func TestClearIdlePassword(t *testing.T) {
+	tests := []struct {
+		name  string
+		after int
+		idle  time.Duration
+		want  int
+	}{
+		{"disabled", 0, time.Hour, 7},
+		{"recent keystroke", 30, 10 * time.Second, 7},
+		{"idle too long", 30, 31 * time.Second, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, greetdtest.NewServer(t))
+			m.config.System.Idle.ClearPasswordAfter = tt.after
+			m.mode = ModePassword
+			setSecret(m.passwordInput, "hunter2")
+
+			now := time.Now()
+			m.lastKeyPress = now.Add(-tt.idle)
+			m, _ = update(t, m, tickMsg(now))
+			if got := m.passwordInput.Len(); got != tt.want {
+				t.Fatalf("expected %d characters left, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// waitForPrompt logs bob in up to his verification code prompt
+func waitForPrompt(t *testing.T, srv *greetdtest.Server) model {
+	t.Helper()
+	srv.AddUser("bob",
+		greetdtest.Secret("Password:", "hunter2"),
+		greetdtest.Visible("Verification code:", "123456"),
+	)
+	m := newTestModel(t, srv)
+	m, cmd := login(t, m, "bob", "hunter2")
+	m, _ = update(t, m, awaitAuth(t, cmd))
+	if m.mode != ModePassword || !m.authActive {
+		t.Fatalf("expected an open conversation at the code prompt, got mode %s", m.mode)
+	}
+	setSecret(m.passwordInput, "123")
+	return m
+}
+
+// startScreensaver ticks m past the screensaver idle timeout
+func startScreensaver(t *testing.T, m model) (model, tea.Cmd) {
+	t.Helper()
+	m.idleTimer = time.Now().Add(-24 * time.Hour)
+	m, cmd := update(t, m, tickMsg(time.Now()))
+	if m.mode != ModeScreensaver {
+		t.Fatalf("expected screensaver, got %s", m.mode)
+	}
+	if m.passwordInput.Len() != 0 {
+		t.Fatal("expected the screensaver to wipe the password")
+	}
+	return m, cmd
+}
+
+func TestScreensaverResetsForm(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	m := waitForPrompt(t, srv)
+
+	m, cmd := startScreensaver(t, m)
+	if m.authActive {
+		t.Fatal("expected the conversation to be abandoned")
+	}
+	awaitRequest(t, srv, cmd, ipc.CancelSessionRequest)
+
+	m, _ = update(t, m, tea.KeyPressMsg{Code: 'a', Text: "a"})
+	if m.mode != ModeLogin || m.focusState != FocusUsername {
+		t.Fatalf("expected the username field after the screensaver, got mode %s focus %v", m.mode, m.focusState)
+	}
+	if m.usernameInput.Value() != "bob" {
+		t.Fatalf("expected username to be kept, got %q", m.usernameInput.Value())
+	}
+}
+
+func TestScreensaverForgetsUsername(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	m := waitForPrompt(t, srv)
+	m.config.System.Idle.ForgetUsername = true
+
+	m, _ = startScreensaver(t, m)
+	m, _ = update(t, m, tea.KeyPressMsg{Code: 'a', Text: "a"})
+	if m.mode != ModeLogin || m.usernameInput.Value() != "" {
+		t.Fatalf("expected an empty username field, got mode %s username %q", m.mode, m.usernameInput.Value())
+	}
+}
+
+func TestScreensaverWithoutReset(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	m := waitForPrompt(t, srv)
+	reset := false
+	m.config.System.Idle.ResetOnScreensaver = &reset
+
+	m, _ = startScreensaver(t, m)
+	if !m.authActive {
+		t.Fatal("expected the conversation to stay open")
+	}
+
+	m, _ = update(t, m, tea.MouseClickMsg{})
+	if m.mode != ModePassword || m.authPromptLabel() != "Verification code:" {
+		t.Fatalf("expected to resume at the prompt, got mode %s label %q", m.mode, m.authPromptLabel())
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+)
+
+// launch.go - the command greetd runs for the selected session
+// [session_args] adds arguments after the session's program and X11 sessions
+// are wrapped in an X server according to [x11] (see internal/sessions/launch.go)
+
+// nvidiaArgs are added when the NVIDIA driver is loaded and [session_args] has
+// no entry for the session, keyed by program name
+var nvidiaArgs = map[string][]string{
+	"sway": {"--unsupported-gpu"},
+}
+
+// nvidiaModule exists while the proprietary NVIDIA kernel module is loaded
+var nvidiaModule = "/sys/module/nvidia"
+
+// sessionArgs returns the extra arguments for the selected session with program argv0
+func (m model) sessionArgs(argv0 string) []string {
+	session := m.selectedSession
+	if args, ok := m.config.System.SessionArguments(session.ID(), session.Name); ok {
+		return args
+	}
+	if _, err := os.Stat(nvidiaModule); err == nil {
+		return nvidiaArgs[filepath.Base(argv0)]
+	}
+	return nil
+}
+
+// greeterVT returns the VT for X servers: [x11] vt, else the one greetd runs on
+func (m model) greeterVT() int {
+	if vt := m.config.System.X11.VT; vt > 0 {
+		return vt
+	}
+	vt, err := strconv.Atoi(os.Getenv("XDG_VTNR"))
+	if err != nil || vt < 1 {
+		return 0
+	}
+	return vt
+}
+
+// launchCommand returns the command greetd should run for the selected session
+func (m model) launchCommand() ([]string, error) {
+	argv, err := m.sessionArgv()
+	if err != nil {
+		return nil, err
+	}
+	x11 := m.config.System.X11
+	opts := sessions.LaunchOptions{X11: x11.Launch, Wrapper: x11.Wrapper, VT: m.greeterVT()}
+	if len(argv) > 0 {
+		opts.Args = m.sessionArgs(argv[0])
+	}
+	cmd, err := sessions.LaunchCommand(*m.selectedSession, argv, opts)
+	if err != nil {
+		return nil, err
+	}
+	cmd = m.wrapSession(cmd)
+	if m.config.Debug {
+		// FIXED 2026-10-16 - Not the arguments: custom commands can carry secrets
+		greeterLog.Debug("Session command", slog.String("program", cmd[0]), slog.Int("args", len(cmd)-1))
+	}
+	return cmd, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+)
+
+func TestX11Launch(t *testing.T) {
+	t.Setenv("XDG_VTNR", "1")
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	m := newTestModel(t, srv)
+	m.selectedSession = &sessions.Session{
+		Name:    "i3",
+		Exec:    "i3",
+		Type:    "X11",
+		Path:    "/usr/share/xsessions/i3.desktop",
+		Command: []string{"i3"},
+	}
+	m.config.System.X11.Launch = sessions.LaunchWrapper
+	m.config.System.X11.Wrapper = "/usr/lib/x11-session %v %s"
+
+	m, cmd := login(t, m, "alice", "hunter2")
+	if msg := awaitAuth(t, cmd); msg != "success" {
+		t.Fatalf("expected success, got %v", msg)
+	}
+	req := lastRequest(t, srv)
+	if want := []string{"/usr/lib/x11-session", "vt1", "i3"}; !reflect.DeepEqual(req.Cmd, want) {
+		t.Fatalf("StartSession cmd = %q, want %q", req.Cmd, want)
+	}
+	if len(req.Env) == 0 || req.Env[0] != "XDG_SESSION_TYPE=x11" {
+		t.Fatalf("expected an x11 session type, got %q", req.Env)
+	}
+}
+
+func TestSessionArgs(t *testing.T) {
+	loaded := t.TempDir()
+	tests := []struct {
+		name   string
+		args   map[string][]string
+		nvidia string
+		want   []string
+	}{
+		{"nothing", nil, "/nonexistent", []string{"sway", "-d"}},
+		{"nvidia default", nil, loaded, []string{"sway", "--unsupported-gpu", "-d"}},
+		{"configured by id", map[string][]string{"sway": {"--verbose"}}, "/nonexistent", []string{"sway", "--verbose", "-d"}},
+		{"configured by name", map[string][]string{"Sway": {"--verbose"}}, loaded, []string{"sway", "--verbose", "-d"}},
+		{"empty list disables default", map[string][]string{"sway": {}}, loaded, []string{"sway", "-d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := nvidiaModule
+			nvidiaModule = tt.nvidia
+			defer func() { nvidiaModule = orig }()
+
+			m := newTestModel(t, greetdtest.NewServer(t))
+			m.selectedSession = &sessions.Session{Name: "Sway", Type: "Wayland", FileID: "sway", Command: []string{"sway", "-d"}}
+			m.config.System.SessionArgs = tt.args
+			got, err := m.launchCommand()
+			if err != nil {
+				t.Fatalf("launchCommand: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("launchCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
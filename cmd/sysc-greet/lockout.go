@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+// lockout.go - login rate limiting and PAM lockout messages
+// After BackoffAfter consecutive failures the form is disabled for a growing delay;
+// every MaxAttempts failures the configured action (screensaver, clear username) runs
+
+// Defaults for the [login] section of the system config
+const (
+	defaultBackoffAfter      = 3
+	defaultBackoffSeconds    = 5
+	defaultBackoffMaxSeconds = 60
+)
+
+// Actions for [login] max_attempts_action
+const (
+	maxAttemptsScreensaver   = "screensaver"
+	maxAttemptsClearUsername = "clear-username"
+)
+
+// authError is a greetd Error response that ended the conversation
+type authError struct {
+	ErrorType   string
+	Description string
+}
+
+func (e authError) Error() string {
+	return fmt.Sprintf("authentication failed: %s - %s", e.ErrorType, e.Description)
+}
+
+// authErrorType returns the greetd error type of err ("" if it is not an authError)
+func authErrorType(err error) string {
+	var authErr authError
+	if errors.As(err, &authErr) {
+		return authErr.ErrorType
+	}
+	return ""
+}
+
+// lockoutInfo is what pam_faillock/pam_tally2 told us about a locked account
+type lockoutInfo struct {
+	Failures  int           // Failed logins that caused the lock (0 if unknown)
+	Remaining time.Duration // Time until unlock (0 if unknown)
+}
+
+var (
+	// pam_faillock: "The account is locked due to 3 failed logins."
+	lockedRe = regexp.MustCompile(`(?i)account (?:is )?(?:temporarily )?locked(?: due to (\d+) failed logins?)?`)
+	// pam_faillock: "(10 minutes left to unlock)"; pam_tally2: "(600 seconds left)"
+	remainingRe = regexp.MustCompile(`(?i)\((\d+) (minute|second)s? left`)
+)
+
+// parseLockout recognises account lockout messages
+func parseLockout(text string) (lockoutInfo, bool) {
+	match := lockedRe.FindStringSubmatch(text)
+	if match == nil {
+		return lockoutInfo{}, false
+	}
+
+	var info lockoutInfo
+	if match[1] != "" {
+		info.Failures, _ = strconv.Atoi(match[1])
+	}
+	if left := remainingRe.FindStringSubmatch(text); left != nil {
+		n, _ := strconv.Atoi(left[1])
+		unit := time.Second
+		if strings.EqualFold(left[2], "minute") {
+			unit = time.Minute
+		}
+		info.Remaining = time.Duration(n) * unit
+	}
+	return info, true
+}
+
+// String formats the lockout for the form
+func (l lockoutInfo) String() string {
+	msg := "Account locked"
+	if l.Failures > 0 {
+		msg += fmt.Sprintf(" after %d failed logins", l.Failures)
+	}
+	switch {
+	case l.Remaining >= time.Minute:
+		minutes := int((l.Remaining + time.Minute - 1) / time.Minute)
+		msg += fmt.Sprintf(" - try again in %d minute%s", minutes, plural(minutes))
+	case l.Remaining > 0:
+		seconds := int(l.Remaining / time.Second)
+		msg += fmt.Sprintf(" - try again in %d second%s", seconds, plural(seconds))
+	default:
+		msg += " - contact your administrator"
+	}
+	return msg
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// noteLockout remembers a lockout reported in a PAM notice, since the notice
+// arrives before greetd's final Error response
+func (m *model) noteLockout(text string) {
+	if info, ok := parseLockout(text); ok {
+		m.lockout = &info
+	}
+}
+
+// authErrorMessage turns a failed conversation into the text shown in the form
+func (m model) authErrorMessage(err error) string {
+	var ae authError
+	if !errors.As(err, &ae) {
+		return err.Error()
+	}
+	if info, ok := parseLockout(ae.Description); ok {
+		return info.String()
+	}
+	if m.lockout != nil {
+		return m.lockout.String()
+	}
+	if ae.ErrorType == "auth_error" {
+		return "Login incorrect"
+	}
+	if ae.Description != "" {
+		return "Login failed: " + ae.Description
+	}
+	return "Login failed"
+}
+
+// loginPolicy returns the [login] settings with defaults filled in
+func (m model) loginPolicy() (after int, base, limit time.Duration) {
+	login := m.config.System.Login
+	after = login.BackoffAfter
+	if after <= 0 {
+		after = defaultBackoffAfter
+	}
+	seconds := login.BackoffSeconds
+	if seconds <= 0 {
+		seconds = defaultBackoffSeconds
+	}
+	maxSeconds := login.BackoffMaxSeconds
+	if maxSeconds <= 0 {
+		maxSeconds = defaultBackoffMaxSeconds
+	}
+	return after, time.Duration(seconds) * time.Second, time.Duration(maxSeconds) * time.Second
+}
+
+// registerFailure counts a failed login and starts the backoff once the threshold is reached
+// The delay doubles with every further failure, up to the configured maximum
+func (m *model) registerFailure(now time.Time) {
+	m.failedAttempts++
+
+	after, base, limit := m.loginPolicy()
+	if m.failedAttempts < after {
+		return
+	}
+	delay := base
+	for i := after; i < m.failedAttempts && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	m.backoffUntil = now.Add(delay)
+	logDebug("Login backoff for %v after %d failed attempts", delay, m.failedAttempts)
+}
+
+// maxAttemptsReached reports whether this failure hits the configured attempts threshold
+func (m model) maxAttemptsReached() bool {
+	limit := m.config.System.Login.MaxAttempts
+	return limit > 0 && m.failedAttempts > 0 && m.failedAttempts%limit == 0
+}
+
+// applyMaxAttemptsAction runs the [login] max_attempts_action
+// FIXED 2026-10-16 - Only clear-username forgets the username; the screensaver
+// keeps it, subject to the [idle] policy like any other screensaver start
+func (m *model) applyMaxAttemptsAction() tea.Cmd {
+	if m.config.System.Login.MaxAttemptsAction == maxAttemptsScreensaver {
+		logDebug("Max attempts reached - starting screensaver")
+		return m.activateScreensaver(m.screensaverConfig)
+	}
+
+	logDebug("Max attempts reached - clearing username")
+	m.usernameInput.SetValue("")
+	m.passwordInput.Reset()
+	m.mode = ModeLogin
+	m.focusState = FocusUsername
+	m.passwordInput.Blur()
+	m.usernameInput.Focus()
+	return nil
+}
+
+// inBackoff reports whether input is disabled after too many failures
+func (m model) inBackoff(now time.Time) bool {
+	return !m.backoffUntil.IsZero() && now.Before(m.backoffUntil)
+}
+
+// backoffRemaining returns the whole seconds left on the backoff countdown
+func (m model) backoffRemaining() int {
+	remaining := time.Until(m.backoffUntil)
+	if remaining < 0 {
+		return 0
+	}
+	return int((remaining + time.Second - 1) / time.Second)
+}
+
+// renderBackoff renders the backoff countdown ("" when input is enabled)
+func (m model) renderBackoff() string {
+	if !m.inBackoff(time.Now()) {
+		return ""
+	}
+	seconds := m.backoffRemaining()
+	return lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FF5555")).
+		Bold(true).
+		Render(fmt.Sprintf("⏳ Too many failed attempts - try again in %d second%s", seconds, plural(seconds)))
+}
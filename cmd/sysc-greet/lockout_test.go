@@ -0,0 +1,169 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+func TestParseLockout(t *testing.T) {
+	tests := []struct {
+		text   string
+		locked bool
+		want   string
+	}{
+		{
+			text:   "The account is locked due to 3 failed logins.",
+			locked: true,
+			want:   "Account locked after 3 failed logins - contact your administrator",
+		},
+		{
+			text:   "The account is locked due to 5 failed logins.\n(10 minutes left to unlock)",
+			locked: true,
+			want:   "Account locked after 5 failed logins - try again in 10 minutes",
+		},
+		{
+			text:   "Account temporarily locked (90 seconds left)",
+			locked: true,
+			want:   "Account locked - try again in 2 minutes",
+		},
+		{
+			text:   "Account temporarily locked (1 second left)",
+			locked: true,
+			want:   "Account locked - try again in 1 second",
+		},
+		{text: "Authentication failure"},
+		{text: "Password:"},
+	}
+
+	for _, tt := range tests {
+		info, ok := parseLockout(tt.text)
+		if ok != tt.locked {
+			t.Errorf("parseLockout(%q) locked = %v, want %v", tt.text, ok, tt.locked)
+			continue
+		}
+		if ok && info.String() != tt.want {
+			t.Errorf("parseLockout(%q) = %q, want %q", tt.text, info.String(), tt.want)
+		}
+	}
+}
+
+func TestAccountLockedMessage(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddFailingUser("alice",
+		ipc.Error{ErrorType: "auth_error", Description: "Authentication failure"},
+		greetdtest.ErrorMessage("The account is locked due to 3 failed logins."),
+		greetdtest.ErrorMessage("(10 minutes left to unlock)"),
+		greetdtest.Secret("Password:", "hunter2"),
+	)
+	m := newTestModel(t, srv)
+
+	m, cmd := login(t, m, "alice", "hunter2")
+	for {
+		msg := awaitAuth(t, cmd)
+		m, cmd = update(t, m, msg)
+		if _, ok := msg.(error); ok {
+			break
+		}
+		if m.mode == ModePassword {
+			setSecret(m.passwordInput, "hunter2")
+			m, cmd = update(t, m, keyEnter)
+		}
+	}
+
+	if want := "Account locked after 3 failed logins - contact your administrator"; m.errorMessage != want {
+		t.Fatalf("errorMessage = %q, want %q", m.errorMessage, want)
+	}
+	if m.lockout != nil {
+		t.Fatal("expected lockout state to be cleared with the conversation")
+	}
+}
+
+func TestLoginBackoff(t *testing.T) {
+	m := initialModel(Config{TestMode: true}, false)
+	m.config.System.Login = sysconfig.Login{BackoffAfter: 2, BackoffSeconds: 5, BackoffMaxSeconds: 15}
+	now := time.Now()
+
+	tests := []struct {
+		attempt int
+		delay   time.Duration
+	}{
+		{1, 0},
+		{2, 5 * time.Second},
+		{3, 10 * time.Second},
+		{4, 15 * time.Second},
+		{5, 15 * time.Second},
+	}
+	for _, tt := range tests {
+		m.registerFailure(now)
+		if m.failedAttempts != tt.attempt {
+			t.Fatalf("failedAttempts = %d, want %d", m.failedAttempts, tt.attempt)
+		}
+		var got time.Duration
+		if !m.backoffUntil.IsZero() {
+			got = m.backoffUntil.Sub(now)
+		}
+		if got != tt.delay {
+			t.Errorf("attempt %d: backoff %v, want %v", tt.attempt, got, tt.delay)
+		}
+	}
+
+	// Keys are swallowed while the countdown runs
+	m.backoffUntil = time.Now().Add(time.Minute)
+	m.mode = ModeLogin
+	m.focusState = FocusUsername
+	m.usernameInput.Focus()
+	m.usernameInput.SetValue("")
+	m, _ = update(t, m, tea.KeyPressMsg{Code: 'a', Text: "a"})
+	if m.usernameInput.Value() != "" {
+		t.Fatalf("expected input to be ignored during backoff, got %q", m.usernameInput.Value())
+	}
+	if m.renderBackoff() == "" {
+		t.Fatal("expected a countdown during backoff")
+	}
+
+	m.backoffUntil = time.Now().Add(-time.Second)
+	m, _ = update(t, m, tea.KeyPressMsg{Code: 'a', Text: "a"})
+	if m.usernameInput.Value() != "a" {
+		t.Fatalf("expected input after backoff, got %q", m.usernameInput.Value())
+	}
+}
+
+func TestMaxAttemptsAction(t *testing.T) {
+	tests := []struct {
+		action   string
+		mode     ViewMode
+		username string
+	}{
+		{"", ModeLogin, ""},
+		{maxAttemptsClearUsername, ModeLogin, ""},
+		{maxAttemptsScreensaver, ModeScreensaver, "alice"},
+	}
+
+	for _, tt := range tests {
+		m := initialModel(Config{TestMode: true}, false)
+		m.config.System.Login = sysconfig.Login{MaxAttempts: 2, MaxAttemptsAction: tt.action}
+		m.usernameInput.SetValue("alice")
+		m.mode = ModePassword
+
+		m.registerFailure(time.Now())
+		if m.maxAttemptsReached() {
+			t.Fatalf("%q: threshold reached after one failure", tt.action)
+		}
+		m.registerFailure(time.Now())
+		if !m.maxAttemptsReached() {
+			t.Fatalf("%q: threshold not reached after two failures", tt.action)
+		}
+		m.applyMaxAttemptsAction()
+		if m.mode != tt.mode {
+			t.Errorf("%q: mode = %s, want %s", tt.action, m.mode, tt.mode)
+		}
+		if got := m.usernameInput.Value(); got != tt.username {
+			t.Errorf("%q: username = %q, want %q", tt.action, got, tt.username)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Nomadcxx/sysc-greet/internal/logging"
+)
+
+// logging.go - greeter logging setup (see internal/logging)
+// Nothing is written to the terminal: the TUI owns it, so log output goes to
+// the configured sink (journald or a rotated file by default)
+
+// greeterLog is the logger of the UI and authentication flow
+var greeterLog = logging.For("greeter")
+
+// animationLog is the logger of background and ASCII effects
+var animationLog = logging.For("animations")
+
+// wallpaperLog is the logger of gSlapper/swww wallpaper handling
+var wallpaperLog = logging.For("wallpaper")
+
+// themeLog is the logger of theme loading and switching
+var themeLog = logging.For("themes")
+
+// configLog is the logger of config files reloaded while the greeter runs
+var configLog = logging.For("config")
+
+// logDebug logs a debug message
+// Never pass usernames, secrets, PAM text, session commands or environments in
+// the format arguments: the message is not redacted. Use greeterLog with
+// "user"/"password" attributes, or leave the value out
+func logDebug(format string, args ...interface{}) {
+	greeterLog.Debug(fmt.Sprintf(format, args...))
+}
+
+// logWarn logs a problem the greeter recovers from
+func logWarn(format string, args ...interface{}) {
+	greeterLog.Warn(fmt.Sprintf(format, args...))
+}
+
+// loggingOptions builds the logging options from the [log] config section
+// -debug always lowers the level to debug
+func loggingOptions(config Config) logging.Options {
+	log := config.System.Log
+	opts := logging.Options{
+		Level:        log.Level,
+		Sink:         log.Sink,
+		Path:         log.Path,
+		MaxSize:      int64(log.MaxSizeKB) * 1024,
+		MaxFiles:     log.MaxFiles,
+		LogUsernames: log.LogUsernames,
+	}
+	if config.Debug {
+		opts.Level = "debug"
+	}
+	return opts
+}
+
+// setupLogging installs the configured sink
+// On error logs are discarded - a logging problem must never keep the greeter from starting
+func setupLogging(config Config) (io.Closer, error) {
+	closer, err := logging.Setup(loggingOptions(config))
+	if err != nil {
+		closer, _ = logging.Setup(logging.Options{Sink: logging.SinkNone})
+		return closer, err
+	}
+	return closer, nil
+}
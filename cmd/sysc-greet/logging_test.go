@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+	"github.com/Nomadcxx/sysc-greet/internal/logging"
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+)
+
+// captureLog sends debug logs to a file for the rest of the test and returns its path
+func captureLog(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "greeter.log")
+	closer, err := logging.Setup(logging.Options{Level: "debug", Sink: logging.SinkFile, Path: path})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	t.Cleanup(func() {
+		logging.Setup(logging.Options{Sink: logging.SinkNone})
+		closer.Close()
+	})
+	return path
+}
+
+func TestLogsLeaveOutSessionDetails(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    func(t *testing.T, m model)
+		logged string // Proves the path was logged at all
+		secret string
+	}{
+		{
+			name: "session environment",
+			run: func(t *testing.T, m model) {
+				m.config.System.SessionEnv = map[string]map[string]string{"sway": {"API_TOKEN": "tok-3f9a"}}
+				m.sessionEnvironment()
+			},
+			logged: "API_TOKEN",
+			secret: "tok-3f9a",
+		},
+		{
+			name: "session command",
+			run: func(t *testing.T, m model) {
+				m.config.System.SessionArgs = map[string][]string{"sway": {"--password=hunter2"}}
+				if _, err := m.launchCommand(); err != nil {
+					t.Fatalf("launchCommand: %v", err)
+				}
+			},
+			logged: "Session command",
+			secret: "hunter2",
+		},
+		{
+			name: "cancelled authentication",
+			run: func(t *testing.T, m model) {
+				update(t, m, authError{ErrorType: "auth_error", Description: "Authentication failure for alice"})
+			},
+			logged: "auth_error",
+			secret: "alice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := captureLog(t)
+			m := newTestModel(t, greetdtest.NewServer(t))
+			m.config.Debug = true
+			m.selectedSession = &sessions.Session{Name: "Sway", Type: "Wayland", FileID: "sway", Command: []string{"sway"}}
+
+			tt.run(t, m)
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			out := string(data)
+			if !strings.Contains(out, tt.logged) {
+				t.Fatalf("expected %q in the log, got:\n%s", tt.logged, out)
+			}
+			if strings.Contains(out, tt.secret) {
+				t.Fatalf("log contains %q:\n%s", tt.secret, out)
+			}
+		})
+	}
+}
@@ -1,21 +1,27 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"image/color"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/Nomadcxx/sysc-greet/internal/animations"
+	"github.com/Nomadcxx/sysc-greet/internal/audit"
 	"github.com/Nomadcxx/sysc-greet/internal/cache"
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+	"github.com/Nomadcxx/sysc-greet/internal/crash"
 	"github.com/Nomadcxx/sysc-greet/internal/ipc"
+	"github.com/Nomadcxx/sysc-greet/internal/secret"
 	"github.com/Nomadcxx/sysc-greet/internal/sessions"
 	themesOld "github.com/Nomadcxx/sysc-greet/internal/themes"
+	"github.com/Nomadcxx/sysc-greet/internal/users"
+	"github.com/Nomadcxx/sysc-greet/internal/watch"
 	"github.com/charmbracelet/bubbles/v2/spinner"
 	"github.com/charmbracelet/bubbles/v2/textinput"
 	tea "github.com/charmbracelet/bubbletea/v2"
@@ -37,24 +43,7 @@ var (
 // NixOS flake injects the actual Nix store path at build time
 var dataDir = "/usr/share/sysc-greet"
 
-// CHANGED 2025-10-06 - Add debug logging to file
-var debugLog *log.Logger
-
-func initDebugLog() {
-	logFile, err := os.OpenFile("/tmp/sysc-greet-debug.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		// Fallback to stderr if can't open log file
-		debugLog = log.New(os.Stderr, "[DEBUG] ", log.Ldate|log.Ltime|log.Lshortfile)
-		return
-	}
-	debugLog = log.New(logFile, "[DEBUG] ", log.Ldate|log.Ltime|log.Lshortfile)
-}
-
-func logDebug(format string, args ...interface{}) {
-	if debugLog != nil {
-		debugLog.Printf(format, args...)
-	}
-}
+// Logging moved to logging.go (internal/logging replaces /tmp/sysc-greet-debug.log)
 
 // TTY-safe colors with profile detection
 var (
@@ -242,10 +231,10 @@ type ASCIIConfig struct {
 	ASCIIVariants      []string // Support multiple ASCII art variants (ascii_1, ascii_2, etc.)
 	MaxASCIIHeight     int      // Track max height across all variants for normalization
 	Color              string   // Optional hex color override for ASCII art (e.g., "#89b4fa")
-	AnimationStyle     string  // "gradient", "wave", "pulse", "rainbow", "matrix", "typewriter", "glow", "static"
-	AnimationSpeed     float64 // 0.1 (slow) to 2.0 (fast), default 1.0
-	AnimationDirection string  // "left", "right", "up", "down", "center-out", "random"
-	Roasts             string  // Custom roast messages separated by │
+	AnimationStyle     string   // "gradient", "wave", "pulse", "rainbow", "matrix", "typewriter", "glow", "static"
+	AnimationSpeed     float64  // 0.1 (slow) to 2.0 (fast), default 1.0
+	AnimationDirection string   // "left", "right", "up", "down", "center-out", "random"
+	Roasts             string   // Custom roast messages separated by │
 }
 
 // Parse multiple ASCII variants (ascii_1, ascii_2, etc.)
@@ -266,6 +255,15 @@ type Config struct {
 	ShowTime         bool
 	ThemeName        string
 	RememberUsername bool
+	UsernameOnly     bool   // Submit on username Enter; PAM decides which prompts follow
+	AutologinUser    string // Account to log in automatically after AutologinDelay
+	AutologinDelay   int    // Autologin countdown in seconds (any key cancels)
+	IPCTimeout       int    // Seconds to wait for each greetd response (0 = wait forever)
+	UserList         bool   // Offer a list of accounts below the username field
+	ConfigPath       string // System configuration file
+	System           sysconfig.File
+	Audit            *audit.Logger  // Login audit trail (nil when disabled)
+	Watcher          *watch.Watcher // Config, theme and ASCII art changes (nil when not watching)
 }
 
 type ViewMode string
@@ -273,9 +271,11 @@ type ViewMode string
 const (
 	ModeLogin    ViewMode = "login"
 	ModePassword ViewMode = "password"
-	ModeLoading  ViewMode = "loading"
-	ModePower    ViewMode = "power"
-	ModeMenu     ViewMode = "menu"
+	// Change-password view for expired credentials (see password_change.go)
+	ModeChangePassword ViewMode = "change_password"
+	ModeLoading        ViewMode = "loading"
+	ModePower          ViewMode = "power"
+	ModeMenu           ViewMode = "menu"
 	// Added new menu modes for structured menu system
 	ModeThemesSubmenu       ViewMode = "themes_submenu"
 	ModeBordersSubmenu      ViewMode = "borders_submenu"
@@ -294,11 +294,14 @@ const (
 	FocusSession FocusState = iota
 	FocusUsername
 	FocusPassword
+	FocusNewPassword     // Change-password view: new password
+	FocusConfirmPassword // Change-password view: confirmation
+	FocusCommand         // Custom command entry: the command to run
 )
 
 type model struct {
 	usernameInput   textinput.Model
-	passwordInput   textinput.Model
+	passwordInput   secretInput // Wipeable buffer instead of a textinput (see secret_input.go)
 	spinner         spinner.Model
 	sessions        []sessions.Session
 	selectedSession *sessions.Session
@@ -319,6 +322,23 @@ type model struct {
 
 	// Session dropdown
 	sessionDropdownOpen bool
+	sessionStats        cache.SessionStats // Usage counts and favorites for ordering
+	prefsUser           string             // User whose cached preferences are applied
+
+	// Custom command entry (see custom_session.go)
+	commandInput   textinput.Model
+	commandHistory []string // Recent custom commands, most recent first
+	historyIndex   int      // Position while browsing commandHistory (-1 when not browsing)
+	historyUser    string   // User whose commandHistory is loaded
+
+	// Report of a failed last session (see crash_report.go)
+	lastSession    *crash.Report
+	lastSessionLog bool // Show the session's last stderr lines
+
+	// User picker (UserList mode)
+	users      []users.User
+	userIndex  int
+	userPicked bool // The highlight was moved with ↑↓, Enter takes it over the typed name
 
 	// Menu system
 	menuOptions []string
@@ -341,8 +361,26 @@ type model struct {
 	// Focus management
 	focusState FocusState
 
-	// Authentication tracking
+	// Authentication tracking (see lockout.go)
 	failedAttempts int
+	backoffUntil   time.Time    // Form input disabled until then after repeated failures
+	lockout        *lockoutInfo // Account lockout reported by PAM during the conversation
+
+	// PAM conversation state (see auth.go)
+	authActive  bool               // greetd session created and waiting on the user
+	authUser    string             // Username the conversation was started for
+	authPrompt  string             // Current PAM prompt shown in place of "Password:"
+	authNotices []authNotice       // Info/error messages received during the conversation
+	autologinAt time.Time          // Autologin deadline (zero when disarmed or cancelled)
+	authCtx     context.Context    // Cancelled by Esc while waiting on greetd
+	authCancel  context.CancelFunc // Cancels authCtx
+
+	// Password change for expired credentials (see password_change.go)
+	passwordChange       bool       // PAM is changing an expired password
+	changeStep           changeStep // Prompt the change-password view answers
+	newPasswordInput     secretInput
+	confirmPasswordInput secretInput
+	pendingConfirm       *secret.Buffer // Confirmed new password, sent when PAM asks to retype it
 
 	// Animation state
 	animationFrame int
@@ -380,6 +418,9 @@ type model struct {
 	screensaverTime   time.Time               // Current time for screensaver display
 	screensaverPrint  *animations.PrintEffect // CHANGED 2025-10-11 - Print effect animation for screensaver
 	screensaverActive bool                    // CHANGED 2025-10-11 - Track if screensaver just activated
+	screensaverConfig ScreensaverConfig       // CHANGED 2026-10-16 - Parsed at startup and when the files change, not on every tick
+	resumeMode        ViewMode                // Mode the screensaver returns to (see idle.go)
+	lastKeyPress      time.Time               // Last key press, for [idle] clear_password_after
 
 	// ASCII navigation fields for multi-variant support
 	asciiArtIndex      int         // Current variant index (0-indexed)
@@ -424,14 +465,7 @@ func initialModel(config Config, screensaverMode bool) model {
 	ti.Styles.Focused.Placeholder = lipgloss.NewStyle().Foreground(FgMuted).Italic(true)
 
 	// Setup password input
-	pi := textinput.New()
-	pi.Prompt = ""      // Remove prompt, will be added by layout
-	pi.Placeholder = "" // Remove placeholder
-	pi.EchoMode = textinput.EchoPassword
-	// Updated for textinput v2 API
-	pi.Styles.Focused.Prompt = lipgloss.NewStyle().Foreground(Primary).Bold(true)
-	pi.Styles.Focused.Text = lipgloss.NewStyle().Foreground(FgPrimary)
-	pi.Styles.Focused.Placeholder = lipgloss.NewStyle().Foreground(FgMuted).Italic(true)
+	pi := newSecretInput()
 
 	// Load sessions
 	sess, _ := sessions.LoadSessions()
@@ -445,10 +479,30 @@ func initialModel(config Config, screensaverMode bool) model {
 			{Name: "Xfce Session", Exec: "startxfce4", Type: "X11"},
 		}
 	}
+
+	// Sessions from the config: [[sessions.extra]] and the custom command entry
+	sess = append(sess, configSessions(config, len(sess))...)
+
+	// The [guest] entry is offered like an installed session
+	if guest, ok := guestSession(config); ok {
+		sess = append(sess, guest)
+	}
+
+	// Favorites first, then the configured order (alphabetical, frequency or pinned)
+	var sessionStats cache.SessionStats
+	if !config.TestMode {
+		stats, err := cache.LoadSessionStats()
+		if err != nil {
+			logWarn("Failed to load session stats: %v", err)
+		}
+		sessionStats = stats
+	}
+	sess = arrangeSessions(sess, config, sessionStats)
+
 	if config.Debug {
 		logDebug(" Loaded %d sessions", len(sess))
 		for _, s := range sess {
-			fmt.Printf("  - %s (%s)\n", s.Name, s.Type)
+			greeterLog.Debug("Session", "name", s.Name, "type", s.Type, "id", s.ID())
 		}
 	}
 
@@ -468,19 +522,20 @@ func initialModel(config Config, screensaverMode bool) model {
 		if err != nil {
 			// CRITICAL: If IPC fails, we cannot authenticate with greetd
 			// Log the error and exit rather than continue with nil client
-			logDebug("FATAL: IPC client creation failed: %v", err)
+			greeterLog.Error("Failed to create IPC client", "err", err)
 			fmt.Fprintf(os.Stderr, "FATAL: Failed to create IPC client: %v\n", err)
 			fmt.Fprintf(os.Stderr, "GREETD_SOCK environment variable: %s\n", os.Getenv("GREETD_SOCK"))
 			fmt.Fprintf(os.Stderr, "This greeter must be run by greetd with GREETD_SOCK set.\n")
 			os.Exit(1)
 		}
+		client.Timeout = time.Duration(config.IPCTimeout) * time.Second
 		ipcClient = client
 		logDebug("IPC client created successfully")
 
 		// Load cached session and find its index
 		cached, err := cache.LoadSelectedSession()
-		if err != nil && config.Debug {
-			logDebug(" Failed to load cached session: %v", err)
+		if err != nil {
+			logWarn("Failed to load cached session: %v", err)
 		} else if cached != nil {
 			selectedSession = cached
 			// Find the index of the cached session
@@ -493,6 +548,12 @@ func initialModel(config Config, screensaverMode bool) model {
 		}
 	}
 
+	// A cached guest or custom command entry is only valid while the config offers it
+	if isGuestSession(selectedSession) && !config.System.Guest.Enabled ||
+		isCustomSession(selectedSession) && !config.System.Sessions.CustomCommand {
+		selectedSession = nil
+	}
+
 	// Default to first session if none selected
 	if selectedSession == nil && len(sess) > 0 {
 		selectedSession = &sess[0]
@@ -503,7 +564,7 @@ func initialModel(config Config, screensaverMode bool) model {
 	themesDir := "themes"
 	loadedThemes, err := themesOld.LoadThemesFromDir(themesDir)
 	if err != nil && config.Debug {
-		logDebug(" Failed to load themes: %v", err)
+		logWarn("Failed to load themes: %v", err)
 	}
 
 	// Use specified theme if available, otherwise default
@@ -517,11 +578,7 @@ func initialModel(config Config, screensaverMode bool) model {
 	}
 
 	// Scan for custom themes
-	themeDirs := []string{
-		dataDir + "/themes",
-		filepath.Join(os.Getenv("HOME"), ".config/sysc-greet/themes"),
-	}
-	customThemeNames := themesOld.ScanCustomThemes(themeDirs)
+	customThemeNames := themesOld.ScanCustomThemes(customThemeDirs(dataDir))
 
 	// Combine built-in and custom themes
 	availableThemes := themesOld.GetAvailableThemes()
@@ -541,26 +598,30 @@ func initialModel(config Config, screensaverMode bool) model {
 	}
 
 	m := model{
-		usernameInput:       ti,
-		passwordInput:       pi,
-		spinner:             sp,
-		sessions:            sess,
-		selectedSession:     selectedSession,
-		sessionIndex:        sessionIndex,
-		ipcClient:           ipcClient,
-		theme:               currentTheme,
-		mode:                initialMode,
-		config:              config,
-		startTime:           time.Now(),
-		width:               80,
-		height:              24,
-		powerOptions:        []string{"Reboot", "Shutdown", "Cancel"},
-		powerIndex:          0,
-		sessionDropdownOpen: false,
-		focusState:          FocusUsername,
-		animationFrame:      0,
-		pulseColor:          0,
-		borderFrame:         0,
+		usernameInput:        ti,
+		passwordInput:        pi,
+		newPasswordInput:     newSecretInput(),
+		confirmPasswordInput: newSecretInput(),
+		spinner:              sp,
+		sessions:             sess,
+		selectedSession:      selectedSession,
+		sessionIndex:         sessionIndex,
+		ipcClient:            ipcClient,
+		theme:                currentTheme,
+		mode:                 initialMode,
+		config:               config,
+		startTime:            time.Now(),
+		width:                80,
+		height:               24,
+		powerOptions:         []string{"Reboot", "Shutdown", "Cancel"},
+		powerIndex:           0,
+		sessionDropdownOpen:  false,
+		sessionStats:         sessionStats,
+		users:                loadUserList(config),
+		focusState:           FocusUsername,
+		animationFrame:       0,
+		pulseColor:           0,
+		borderFrame:          0,
 		// Initialize default border and background settings
 		// Set Dracula as default theme and disable border animation
 		selectedBorderStyle:    "classic",
@@ -579,6 +640,7 @@ func initialModel(config Config, screensaverMode bool) model {
 		// CHANGED 2025-10-10 - Initialize screensaver timers
 		idleTimer:       time.Now(),
 		screensaverTime: time.Now(),
+		lastKeyPress:    time.Now(),
 		// Initialize fire effect with default size
 		fireEffect: animations.NewFireEffect(80, 30, animations.GetDefaultFirePalette()),
 		// CHANGED 2025-10-08 - Initialize rain effect with default size
@@ -593,12 +655,27 @@ func initialModel(config Config, screensaverMode bool) model {
 		typewriterTicker: nil,
 	}
 
+	m.initCustomCommand()
+	m.loadLastSession()
+
+	// Look from [appearance] until a user picks another from the menu (cached below)
+	if bg := config.System.Appearance.Background; bg != "" {
+		m.selectedBackground = bg
+	}
+	if border := config.System.Appearance.Border; border != "" {
+		m.selectedBorderStyle = border
+	}
+
 	// CHANGED 2025-10-03 - Load cached preferences including session
 	// CHANGED 2025-10-03 - Skip cache in test mode
 	// FIXED 2025-10-17 - Apply Dracula as fallback if no cached theme exists
 	themeApplied := false
 	if !m.config.TestMode {
-		if prefs, err := cache.LoadPreferences(); err == nil && prefs != nil {
+		prefs, err := cache.LoadPreferences()
+		if err != nil {
+			logWarn("Failed to load preferences: %v", err)
+		}
+		if prefs != nil {
 			if prefs.Theme != "" {
 				m.currentTheme = prefs.Theme
 				applyTheme(prefs.Theme, m.config.TestMode)
@@ -629,127 +706,42 @@ func initialModel(config Config, screensaverMode bool) model {
 			m.asciiArtIndex = prefs.ASCIIIndex
 
 			// Initialize ASCII effect objects based on cached selection
-			if m.selectedSession != nil {
-				sessionName := strings.ToLower(strings.Fields(m.selectedSession.Name)[0])
-				var configFileName string
-				switch sessionName {
-				case "gnome":
-					configFileName = "gnome_desktop"
-				case "i3":
-					configFileName = "i3wm"
-				case "bspwm":
-					configFileName = "bspwm_manager"
-				case "plasma":
-					configFileName = "kde"
-				case "xmonad":
-					configFileName = "xmonad"
-				default:
-					configFileName = sessionName
-				}
-				configPath := fmt.Sprintf("%s/ascii_configs/%s.conf", dataDir, configFileName)
-
-				switch m.selectedBackground {
-				case "ticker":
-					customRoasts := ""
-					if asciiConfig, err := loadASCIIConfig(configPath); err == nil {
-						customRoasts = asciiConfig.Roasts
-					}
-					m.typewriterTicker = animations.NewTypewriterTicker(m.selectedSession.Name, customRoasts)
-				case "print":
-					if asciiConfig, err := loadASCIIConfig(configPath); err == nil && len(asciiConfig.ASCIIVariants) > 0 {
-						variantIndex := m.asciiArtIndex
-						if variantIndex >= len(asciiConfig.ASCIIVariants) {
-							variantIndex = 0
-						}
-						ascii := asciiConfig.ASCIIVariants[variantIndex]
-						m.printEffect = animations.NewPrintEffect(ascii, time.Millisecond*3)
-					}
-				case "beams":
-					if asciiConfig, err := loadASCIIConfig(configPath); err == nil && len(asciiConfig.ASCIIVariants) > 0 {
-						variantIndex := m.asciiArtIndex
-						if variantIndex >= len(asciiConfig.ASCIIVariants) {
-							variantIndex = 0
-						}
-						ascii := asciiConfig.ASCIIVariants[variantIndex]
-						beamColors, finalColors := getThemeColorsForBeams(m.currentTheme)
-						lines := strings.Split(ascii, "\n")
-						asciiHeight := len(lines)
-						asciiWidth := 0
-						for _, line := range lines {
-							if len([]rune(line)) > asciiWidth {
-								asciiWidth = len([]rune(line))
-							}
-						}
-						m.beamsEffect = animations.NewBeamsTextEffect(animations.BeamsTextConfig{
-							Width:              asciiWidth,
-							Height:             asciiHeight,
-							Text:               ascii,
-							BeamGradientStops:  beamColors,
-							FinalGradientStops: finalColors,
-						})
-					}
-				case "pour":
-					if asciiConfig, err := loadASCIIConfig(configPath); err == nil && len(asciiConfig.ASCIIVariants) > 0 {
-						variantIndex := m.asciiArtIndex
-						if variantIndex >= len(asciiConfig.ASCIIVariants) {
-							variantIndex = 0
-						}
-						ascii := asciiConfig.ASCIIVariants[variantIndex]
-						pourColors := getThemeColorsForPour(m.currentTheme)
-						lines := strings.Split(ascii, "\n")
-						asciiHeight := len(lines)
-						asciiWidth := 0
-						for _, line := range lines {
-							if len([]rune(line)) > asciiWidth {
-								asciiWidth = len([]rune(line))
-							}
-						}
-						m.pourEffect = animations.NewPourEffect(animations.PourConfig{
-							Width:                  asciiWidth,
-							Height:                 asciiHeight,
-							Text:                   ascii,
-							PourDirection:          "down",
-							PourSpeed:              1,
-							MovementSpeed:          0.05,
-							Gap:                    2,
-							StartingColor:          "#ffffff",
-							FinalGradientStops:     pourColors,
-							FinalGradientSteps:     12,
-							FinalGradientFrames:    5,
-							FinalGradientDirection: "horizontal",
-						})
-					}
-				case "aquarium":
-					// selectedBackground already set on line 589
-					// Leave m.aquariumEffect = nil, will initialize in WindowSizeMsg
-				default:
-					// For gslapper wallpapers, selectedBackground already set on line 589
-					// Don't launch yet - wait for compositor in WindowSizeMsg
-				}
-			}
+			m.initASCIIEffects()
 
 			// FIXED 2025-10-17 - Load username and auto-advance to password if matches current session
-			if m.config.RememberUsername && prefs.Username != "" && m.selectedSession != nil && prefs.Session == m.selectedSession.Name {
+			if m.config.RememberUsername && prefs.Username != "" && m.selectedSession != nil && prefs.Session == m.selectedSession.Name && !isGuestSession(m.selectedSession) {
 				m.usernameInput.SetValue(prefs.Username)
+				m.prefsUser = prefs.Username
 				// FIXED 2025-10-17 - Automatically switch to password mode when username is cached
 				m.mode = ModePassword
 				m.focusState = FocusPassword
 				m.usernameInput.Blur()
 				m.passwordInput.Focus()
-				logDebug("Loaded cached username '%s' for session: %s - auto-advancing to password", prefs.Username, m.selectedSession.Name)
+				greeterLog.Debug("Loaded cached username - auto-advancing to password", "user", prefs.Username, "session", m.selectedSession.Name)
 			}
 		}
 	}
 
+	// Autologin takes precedence over the cached username
+	if !screensaverMode {
+		m.startAutologin()
+		m.startGuestCountdown()
+	}
+
 	// FIXED 2025-10-17 - Apply Dracula as fallback if no cached theme was loaded
+	// CHANGED 2026-10-16 - Unless [appearance] theme or --theme names another
 	if !themeApplied {
-		applyTheme("dracula", m.config.TestMode)
-		logDebug("No cached theme found - applied Dracula as default")
+		if config.ThemeName != "" {
+			m.currentTheme = strings.ToLower(config.ThemeName)
+		}
+		applyTheme(m.currentTheme, m.config.TestMode)
+		logDebug("No cached theme found - applied default theme %s", m.currentTheme)
 	}
 
 	// CHANGED 2025-10-11 - Initialize print effect if starting in screensaver mode
+	m.screensaverConfig = loadScreensaverConfig(m.config.System.Screensaver)
 	if screensaverMode {
-		ssConfig := loadScreensaverConfig()
+		ssConfig := m.screensaverConfig
 		if ssConfig.AnimateOnStart && ssConfig.AnimationType == "print" && len(ssConfig.ASCIIVariants) > 0 {
 			selectedASCII := ssConfig.ASCIIVariants[0]
 			charDelay := time.Duration(ssConfig.AnimationSpeed) * time.Millisecond
@@ -769,6 +761,7 @@ func (m model) Init() tea.Cmd {
 		m.spinner.Tick,
 		doTick(),
 		tea.RequestUniformKeyLayout,
+		waitForConfigChange(m.config.Watcher),
 	)
 }
 
@@ -804,14 +797,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			})
 			m.lastAquariumWidth = m.width
 			m.lastAquariumHeight = m.height
-			logDebug("Lazy init aquarium in tick: %dx%d", m.width, m.height)
+			animationLog.Debug("Lazy init aquarium in tick", "width", m.width, "height", m.height)
 		}
 
 		// Lazy init: launch gslapper on first tick when compositor is ready
 		if !m.gslapperLaunched && m.width > 0 && m.selectedWallpaper != "" {
 			launchGslapperWallpaper(m.selectedWallpaper)
 			m.gslapperLaunched = true
-			logDebug("Lazy init gslapper in tick: %s", m.selectedWallpaper)
+			wallpaperLog.Debug("Lazy init gslapper in tick", "wallpaper", m.selectedWallpaper)
 		}
 
 		// CHANGED 2025-10-10 - Update screensaver time and check for activation
@@ -822,24 +815,22 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.screensaverPrint.Tick(m.screensaverTime)
 		}
 
+		// Autologin once the countdown expires
+		if m.autologinDue(m.screensaverTime) {
+			newModel, cmd := m.autologin()
+			m = newModel
+			cmds = append(cmds, cmd)
+		}
+
+		// Clear a password left half-typed ([idle] clear_password_after)
+		m.clearIdlePassword(m.screensaverTime)
+
 		// Check for screensaver activation using configurable timeout
 		if m.mode == ModeLogin || m.mode == ModePassword {
-			ssConfig := loadScreensaverConfig()
+			ssConfig := m.screensaverConfig
 			idleDuration := time.Since(m.idleTimer)
 			if idleDuration >= time.Duration(ssConfig.IdleTimeout)*time.Minute && m.mode != ModeScreensaver {
-				m.mode = ModeScreensaver
-				m.screensaverActive = true // CHANGED 2025-10-11 - Mark screensaver as just activated
-
-				// CHANGED 2025-10-11 - Initialize print effect animation if enabled
-				if ssConfig.AnimateOnStart && ssConfig.AnimationType == "print" {
-					// Get the ASCII variant to animate
-					variantIndex := 0 // Start with first variant
-					if len(ssConfig.ASCIIVariants) > 0 {
-						selectedASCII := ssConfig.ASCIIVariants[variantIndex]
-						charDelay := time.Duration(ssConfig.AnimationSpeed) * time.Millisecond
-						m.screensaverPrint = animations.NewPrintEffect(selectedASCII, charDelay)
-					}
-				}
+				cmds = append(cmds, m.activateScreensaver(ssConfig))
 			}
 		}
 
@@ -925,7 +916,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Load cached username for NEW session if available
 			if !m.config.TestMode {
-				if prefs, err := cache.LoadPreferences(); err == nil && prefs != nil {
+				if prefs, err := cache.LoadPreferences(); err != nil {
+					logWarn("Failed to load preferences: %v", err)
+				} else if prefs != nil {
 					if m.config.RememberUsername && prefs.Username != "" && prefs.Session == session.Name {
 						m.usernameInput.SetValue(prefs.Username)
 						logDebug("Loaded cached username for new session: %s", session.Name)
@@ -938,31 +931,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			logDebug(" Selected session: %s", session.Name)
 		}
 		if m.config.TestMode {
-			fmt.Println("Test mode: Selected session:", session.Name)
+			greeterLog.Info("Test mode: selected session", "session", session.Name)
 			return m, tea.Quit
 		} else {
 			// Save to cache
-			if err := cache.SaveSelectedSession(session); err != nil && m.config.Debug {
-				logDebug(" Failed to save session: %v", err)
+			if err := cache.SaveSelectedSession(session); err != nil {
+				logWarn("Failed to save session: %v", err)
 			}
 			// CHANGED 2025-10-03 - Save session preference
 			// CHANGED 2025-10-03 - Skip saving in test mode
 			// FIXED 2025-10-17 - Save current username value (already loaded for this session above)
-			if !m.config.TestMode {
-				username := ""
-				if m.config.RememberUsername {
-					username = m.usernameInput.Value()
-				}
-				cache.SavePreferences(cache.UserPreferences{
-					Theme:       m.currentTheme,
-					Background:  m.selectedBackground,
-					Wallpaper:   m.selectedWallpaper,
-					BorderStyle: m.selectedBorderStyle,
-					Session:     session.Name,
-					Username:    username,
-					ASCIIIndex:  m.asciiArtIndex,
-				})
-			}
+			m.savePreferences(m.rememberedUsername(), m.currentPreferences)
 			return m, tea.Batch(cmds...)
 		}
 
@@ -971,22 +950,22 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch action {
 		case "Reboot":
 			if m.config.TestMode {
-				fmt.Println("Test mode: Would reboot system")
+				greeterLog.Info("Test mode: would reboot system")
 				return m, tea.Quit
 			}
 			// FIXED 2025-11-01 - Use setsid to detach reboot from greeter process tree
 			// This prevents greetd from restarting the greeter when it exits
-			fmt.Println("Rebooting...")
+			greeterLog.Info("Rebooting")
 			exec.Command("setsid", "-f", "systemctl", "reboot").Start()
 			return m, tea.Quit
 		case "Shutdown":
 			if m.config.TestMode {
-				fmt.Println("Test mode: Would shutdown system")
+				greeterLog.Info("Test mode: would shut down system")
 				return m, tea.Quit
 			}
 			// FIXED 2025-11-01 - Use setsid to detach shutdown from greeter process tree
 			// This prevents greetd from restarting the greeter when it exits
-			fmt.Println("Shutting down...")
+			greeterLog.Info("Shutting down")
 			exec.Command("setsid", "-f", "systemctl", "poweroff").Start()
 			return m, tea.Quit
 		case "Cancel":
@@ -997,56 +976,90 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, textinput.Blink)
 		}
 
+	case authMessageMsg:
+		if !m.authActive {
+			// Conversation was cancelled while this prompt was on its way
+			return m, m.cancelAuth()
+		}
+		return m.handleAuthMessage(ipc.AuthMessage(msg), nil)
+
+	case authNoticeMsg:
+		if !m.authActive {
+			msg.password.Destroy()
+			return m, m.cancelAuth()
+		}
+		return m.handleAuthMessage(msg.notice, msg.password)
+
+	case authCancelledMsg:
+		// Esc during loading already reset the form
+		return m, nil
+
+	case configChangedMsg:
+		m.applyConfigChanges(msg.paths)
+		return m, waitForConfigChange(m.config.Watcher)
+
 	case string:
+		m.resetAuthState()
 		if msg == "success" {
 			// Removed delay workaround
 			// Now we properly wait for greetd's success response in StartSession() before returning
 			// This ensures greetd has finished session initialization regardless of hardware speed
 
+			m.auditSuccess()
 			m.failedAttempts = 0 // Reset failed attempts on successful login
+			m.backoffUntil = time.Time{}
+			m.recordSessionUse()
+			m.recordCustomCommand()
+			m.recordSessionUser()
 
 			// FIXED 2025-10-17 - Save username to cache on successful login
-			if !m.config.TestMode && m.selectedSession != nil {
+			// The guest account leaves nothing behind for the next user
+			if !m.config.TestMode && m.selectedSession != nil && !m.guestSelected() {
 				sessionName := m.selectedSession.Name
-				username := ""
-			if m.config.RememberUsername {
-				username = m.usernameInput.Value()
-			}
-				cache.SavePreferences(cache.UserPreferences{
-					Theme:       m.currentTheme,
-					Background:  m.selectedBackground,
-					Wallpaper:   m.selectedWallpaper,
-					BorderStyle: m.selectedBorderStyle,
-					Session:     sessionName,
-					Username:    username,
-					ASCIIIndex:  m.asciiArtIndex,
-				})
-				logDebug("Saved username '%s' for session: %s", username, sessionName)
+				// CHANGED 2026-10-16 - Read-modify-write through cache.UpdatePreferences
+				username := m.rememberedUsername()
+				m.savePreferences(username, m.currentPreferences)
+				// The pointer pre-fills the username field next time (cleared when remembering is off)
+				if err := cache.SaveLastUser(username); err != nil {
+					logWarn("Failed to save last user: %v", err)
+				}
+				greeterLog.Debug("Saved preferences", "user", username, "session", sessionName)
 			}
 
-			fmt.Println("Session started successfully")
+			greeterLog.Info("Session started", "session", m.selectedSession.Name)
 			return m, tea.Quit
 		} else {
 			// FIXED 2025-10-17 - Return to login mode (not password mode) so user can fix username
 			m.errorMessage = msg
 			m.mode = ModeLogin
 			m.usernameInput.SetValue("") // Clear username field
-			m.passwordInput.SetValue("") // Clear password field
+			m.passwordInput.Reset()      // Clear password field
 			m.usernameInput.Focus()
 			m.passwordInput.Blur()
 			m.focusState = FocusUsername
 			return m, textinput.Blink
 		}
 	case error:
+		if !m.authActive {
+			// Result of a conversation the user already cancelled
+			// FIXED 2026-10-16 - Not the description: PAM text can name the user
+			greeterLog.Debug("Ignoring error from cancelled authentication", slog.String("error_type", authErrorType(msg)))
+			return m, nil
+		}
 		// FIXED 2025-10-17 - Return to password mode so user can retry
-		m.errorMessage = msg.Error()
-		m.failedAttempts++ // Track failed authentication attempts
+		m.auditFailure(msg)
+		m.errorMessage = m.authErrorMessage(msg)
+		m.resetAuthState()
+		m.registerFailure(time.Now()) // Track failed attempts and start the backoff
 		m.mode = ModePassword
 		// Keep username, only clear password
-		m.passwordInput.SetValue("")
+		m.passwordInput.Reset()
 		m.passwordInput.Focus()
 		m.usernameInput.Blur()
 		m.focusState = FocusPassword
+		if m.maxAttemptsReached() {
+			return m, tea.Batch(textinput.Blink, m.applyMaxAttemptsAction())
+		}
 		return m, textinput.Blink
 
 	case tea.KeyMsg:
@@ -1054,17 +1067,30 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Kitty keyboard protocol sends CAPS LOCK and NUM LOCK as ModCapsLock and ModNumLock
 		key := msg.Key()
 		m.capsLockOn = (key.Mod & tea.ModCapsLock) != 0
+		m.lastKeyPress = time.Now()
 
 		if m.config.Debug {
-			// Log ALL key presses to debug what modifiers are being sent
-			fmt.Fprintf(os.Stderr, "KEY: %q | Mod=%08b (%d) | CapsLock=%v\n",
-				key.Text, key.Mod, key.Mod, m.capsLockOn)
+			// Log modifiers to debug what the terminal sends - never the typed text,
+			// which may be a password
+			greeterLog.Debug("Key press", "mod", fmt.Sprintf("%08b", key.Mod), "caps_lock", m.capsLockOn)
 		}
 
 		// CHANGED 2025-10-12 - Handle screensaver exit on any key press
 		if m.mode == ModeScreensaver {
 			return handleScreensaverInput(m, msg)
 		}
+		// Any key cancels a pending autologin (and is not typed into the form)
+		if !m.autologinAt.IsZero() {
+			m.autologinAt = time.Time{}
+			m.idleTimer = time.Now()
+			logDebug("Autologin cancelled by key press")
+			return m, nil
+		}
+		// The form ignores input while the login backoff runs
+		if (m.mode == ModeLogin || m.mode == ModePassword) && m.inBackoff(time.Now()) {
+			m.idleTimer = time.Now()
+			return m, nil
+		}
 		newModel, cmd := m.handleKeyInput(msg)
 		m = newModel
 		cmds = append(cmds, cmd)
@@ -1072,8 +1098,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.MouseMsg:
 		// CHANGED 2025-10-12 - Exit screensaver and reset idle timer on mouse movement
 		if m.mode == ModeScreensaver {
-			m.mode = ModeLogin
-			m.idleTimer = time.Now()
+			m.exitScreensaver()
 			return m, nil
 		}
 		// Reset idle timer on any mouse input in normal modes
@@ -1083,12 +1108,23 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Update components based on current mode and focus
 	switch m.mode {
 	case ModeLogin:
-		if m.focusState == FocusUsername {
+		// The username field is hidden while the guest entry is selected
+		if m.focusState == FocusCommand {
+			cmds = append(cmds, m.updateCommandInput(msg))
+		}
+		if m.focusState == FocusUsername && !m.guestSelected() {
 			var cmd tea.Cmd
+			typed := m.usernameInput.Value()
 			m.usernameInput, cmd = m.usernameInput.Update(msg)
 			cmds = append(cmds, cmd)
+			// Typing re-filters the user list, start again from the best match
+			if m.usernameInput.Value() != typed {
+				m.userIndex = 0
+				m.userPicked = false
+			}
 			// FIXED 2025-10-17 - Clear error message when user starts typing in login mode
-			if m.errorMessage != "" && len(m.usernameInput.Value()) > 0 {
+			// (an edit, not Enter, so a refusal from the [access] policy stays visible)
+			if m.errorMessage != "" && len(m.usernameInput.Value()) > 0 && m.usernameInput.Value() != typed {
 				m.errorMessage = ""
 			}
 		}
@@ -1098,10 +1134,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.passwordInput, cmd = m.passwordInput.Update(msg)
 			cmds = append(cmds, cmd)
 			// CHANGED 2025-10-05 - Clear error message when user starts typing
-			if m.errorMessage != "" && len(m.passwordInput.Value()) > 0 {
+			if m.errorMessage != "" && m.passwordInput.Len() > 0 {
 				m.errorMessage = ""
 			}
 		}
+	case ModeChangePassword:
+		var cmd tea.Cmd
+		switch m.focusState {
+		case FocusPassword:
+			m.passwordInput, cmd = m.passwordInput.Update(msg)
+		case FocusNewPassword:
+			m.newPasswordInput, cmd = m.newPasswordInput.Update(msg)
+		case FocusConfirmPassword:
+			m.confirmPasswordInput, cmd = m.confirmPasswordInput.Update(msg)
+		}
+		cmds = append(cmds, cmd)
 	case ModeLoading:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
@@ -1116,9 +1163,9 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 	m.idleTimer = time.Now()
 
 	// Updated for tea.KeyMsg v2 API
-	if m.config.Debug {
-		keyStr := msg.String()
-		fmt.Fprintf(os.Stderr, "KEY DEBUG: String='%s'\n", keyStr)
+	if m.config.Debug && msg.Key().Text == "" {
+		// Only named keys (enter, f1, ctrl+c...) - printable keys may be part of a password
+		greeterLog.Debug("Key", "key", msg.String())
 	}
 
 	switch msg.String() {
@@ -1135,6 +1182,9 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 	case "f1":
 		// Remapped F1 to Menu
 		// Main menu - works from any mode
+		if m.menuLocked() {
+			return m, nil
+		}
 		m.sessionDropdownOpen = false
 		m.mode = ModeMenu
 		m.menuIndex = 0
@@ -1165,7 +1215,7 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 		// Release notes popup - works from any mode
 		m.sessionDropdownOpen = false
 		if m.config.Debug {
-			fmt.Println("Debug: Opening release notes")
+			logDebug("Opening release notes")
 		}
 		m.mode = ModeReleaseNotes
 		m.usernameInput.Blur()
@@ -1175,10 +1225,13 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 	case "f4":
 		// F4 remains Power
 		// Power menu - works from any mode, resets to first option
+		if m.powerLocked() {
+			return m, nil
+		}
 		m.sessionDropdownOpen = false
 		m.powerIndex = 0
 		if m.config.Debug {
-			fmt.Println("Debug: Opening power menu")
+			logDebug("Opening power menu")
 		}
 		m.mode = ModePower
 		m.usernameInput.Blur()
@@ -1190,6 +1243,15 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 		if m.mode == ModeLogin {
 			switch m.focusState {
 			case FocusSession:
+				if m.customSelected() {
+					// The command field sits between the session and the username
+					m = m.focusCommand()
+					break
+				}
+				m.focusState = FocusUsername
+				m.usernameInput.Focus()
+			case FocusCommand:
+				m.commandInput.Blur()
 				m.focusState = FocusUsername
 				m.usernameInput.Focus()
 			case FocusUsername:
@@ -1207,18 +1269,30 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 				m.passwordInput.Blur()
 			}
 			return m, textinput.Blink
+		} else if m.mode == ModeChangePassword && m.changeStep == changeNew {
+			// Switch between the new and confirm fields
+			if m.focusState == FocusNewPassword {
+				return m.focusChangeField(FocusConfirmPassword), textinput.Blink
+			}
+			return m.focusChangeField(FocusNewPassword), textinput.Blink
 		}
 
 	case "esc":
 		switch m.mode {
-		case ModePassword:
+		case ModeLoading:
+			// Give up on a greetd request that hangs (e.g. LDAP/SSSD timeouts in PAM)
+			return m.cancelLoading()
+		case ModePassword, ModeChangePassword:
 			// CHANGED 2025-10-18 22:05 - Allow ESC to return from password mode to login mode
+			// Abandon any PAM conversation waiting on a prompt
+			var cancelCmd tea.Cmd
+			m, cancelCmd = m.abortAuth()
+			m.wipeSecrets()
 			m.mode = ModeLogin
 			m.focusState = FocusUsername
-			m.passwordInput.SetValue("") // Clear password field
 			m.usernameInput.Focus()
 			m.passwordInput.Blur()
-			return m, textinput.Blink
+			return m, tea.Batch(textinput.Blink, cancelCmd)
 		case ModePower:
 			m.mode = ModeLogin
 			m.focusState = FocusUsername
@@ -1259,6 +1333,14 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 		}
 
 	case "up", "k":
+		if msg.String() == "up" && m.userPickerActive() {
+			m.moveUserSelection(-1)
+			return m, nil
+		}
+		if msg.String() == "up" && m.mode == ModeLogin && m.focusState == FocusCommand {
+			m.browseCommandHistory(1)
+			return m, nil
+		}
 		if m.sessionDropdownOpen {
 			if m.sessionIndex > 0 {
 				m.sessionIndex--
@@ -1306,6 +1388,14 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 		}
 
 	case "down", "j":
+		if msg.String() == "down" && m.userPickerActive() {
+			m.moveUserSelection(1)
+			return m, nil
+		}
+		if msg.String() == "down" && m.mode == ModeLogin && m.focusState == FocusCommand {
+			m.browseCommandHistory(-1)
+			return m, nil
+		}
 		if m.sessionDropdownOpen {
 			if m.sessionIndex < len(m.sessions)-1 {
 				m.sessionIndex++
@@ -1385,20 +1475,8 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 					}
 
 					// Save ASCII index preference
-					if !m.config.TestMode && m.selectedSession != nil {
-					username := ""
-					if m.config.RememberUsername {
-						username = m.usernameInput.Value()
-					}
-						cache.SavePreferences(cache.UserPreferences{
-							Theme:       m.currentTheme,
-							Background:  m.selectedBackground,
-							Wallpaper:   m.selectedWallpaper,
-							BorderStyle: m.selectedBorderStyle,
-							Session:     m.selectedSession.Name,
-							Username:    username,
-							ASCIIIndex:  m.asciiArtIndex,
-						})
+					if m.selectedSession != nil {
+						m.savePreferences(m.rememberedUsername(), func(p *cache.UserPreferences) { p.ASCIIIndex = m.asciiArtIndex })
 					}
 
 					// Reset print effect with new ASCII if enabled
@@ -1447,7 +1525,7 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 
 	case "pgdn", "pgdown", "page down":
 		if m.config.Debug {
-			logDebug("Page Down pressed - mode: %v, session: %v", m.mode, m.selectedSession)
+			logDebug("Page Down pressed - mode: %v, session: %s", m.mode, m.selectedSession.Name)
 		}
 		if m.mode == ModeLogin || m.mode == ModePassword {
 			if m.selectedSession != nil {
@@ -1481,20 +1559,8 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 					}
 
 					// Save ASCII index preference
-					if !m.config.TestMode && m.selectedSession != nil {
-					username := ""
-					if m.config.RememberUsername {
-						username = m.usernameInput.Value()
-					}
-						cache.SavePreferences(cache.UserPreferences{
-							Theme:       m.currentTheme,
-							Background:  m.selectedBackground,
-							Wallpaper:   m.selectedWallpaper,
-							BorderStyle: m.selectedBorderStyle,
-							Session:     m.selectedSession.Name,
-							Username:    username,
-							ASCIIIndex:  m.asciiArtIndex,
-						})
+					if m.selectedSession != nil {
+						m.savePreferences(m.rememberedUsername(), func(p *cache.UserPreferences) { p.ASCIIIndex = m.asciiArtIndex })
 					}
 
 					// Reset print effect with new ASCII if enabled
@@ -1541,6 +1607,27 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 			return m, nil
 		}
 
+	case "ctrl+l":
+		// Expand the stderr of a failed last session
+		if m.lastSession != nil && (m.mode == ModeLogin || m.mode == ModePassword) {
+			m.lastSessionLog = !m.lastSessionLog
+			return m, nil
+		}
+
+	case "ctrl+o":
+		// Switch away from a session that failed
+		if m.lastSession != nil && (m.mode == ModeLogin || m.mode == ModePassword) {
+			m.selectFallbackSession()
+			return m, nil
+		}
+
+	case "ctrl+f":
+		// Star/unstar the highlighted session so it stays on top of the list
+		if m.sessionDropdownOpen {
+			m.toggleFavorite()
+			return m, nil
+		}
+
 	case "enter":
 		if m.sessionDropdownOpen {
 			// Select current session from dropdown
@@ -1606,26 +1693,15 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 					// CHANGED 2025-10-03 - Save theme preference
 					// CHANGED 2025-10-03 - Skip saving in test mode
 					if !m.config.TestMode {
-						sessionName := ""
-						if m.selectedSession != nil {
-							sessionName = m.selectedSession.Name
-						}
-						cache.SavePreferences(cache.UserPreferences{
-							Theme:       m.currentTheme,
-							Background:  m.selectedBackground,
-							Wallpaper:   m.selectedWallpaper,
-							BorderStyle: m.selectedBorderStyle,
-							Session:     sessionName,
-							ASCIIIndex:  m.asciiArtIndex,
-						})
+						m.savePreferences("", func(p *cache.UserPreferences) { p.Theme = m.currentTheme })
 
 						// Reinitialize ASCII effects with new theme colors if active
 						if m.selectedBackground == "beams" && m.beamsEffect != nil && m.selectedSession != nil {
-							logDebug("Theme changed to %s - reinitializing beams", themeName)
+							animationLog.Debug("Theme changed - reinitializing beams", "theme", themeName)
 							m.resetBeamsEffectForSession(m.selectedSession.Name)
 						}
 						if m.selectedBackground == "pour" && m.pourEffect != nil && m.selectedSession != nil {
-							logDebug("Theme changed to %s - reinitializing pour", themeName)
+							animationLog.Debug("Theme changed - reinitializing pour", "theme", themeName)
 							m.resetPourEffectForSession(m.selectedSession.Name)
 						}
 						// Aquarium updates palette automatically via UpdatePalette() in backgrounds.go
@@ -1662,20 +1738,7 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 				}
 				// CHANGED 2025-10-03 - Save border preference
 				// CHANGED 2025-10-03 - Skip saving in test mode
-				if !m.config.TestMode {
-					sessionName := ""
-					if m.selectedSession != nil {
-						sessionName = m.selectedSession.Name
-					}
-					cache.SavePreferences(cache.UserPreferences{
-						Theme:       m.currentTheme,
-						Background:  m.selectedBackground,
-						Wallpaper:   m.selectedWallpaper,
-						BorderStyle: m.selectedBorderStyle,
-						Session:     sessionName,
-						ASCIIIndex:  m.asciiArtIndex,
-					})
-				}
+				m.savePreferences("", func(p *cache.UserPreferences) { p.BorderStyle = m.selectedBorderStyle })
 				m.mode = ModeLogin
 				return m, nil
 
@@ -1756,20 +1819,7 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 					m.selectedBackground = "none"
 				}
 				// Save background preference
-				if !m.config.TestMode {
-					sessionName := ""
-					if m.selectedSession != nil {
-						sessionName = m.selectedSession.Name
-					}
-					cache.SavePreferences(cache.UserPreferences{
-						Theme:       m.currentTheme,
-						Background:  m.selectedBackground,
-						Wallpaper:   m.selectedWallpaper,
-						BorderStyle: m.selectedBorderStyle,
-						Session:     sessionName,
-						ASCIIIndex:  m.asciiArtIndex,
-					})
-				}
+				m.savePreferences("", func(p *cache.UserPreferences) { p.Background = m.selectedBackground })
 				// Refresh menu to update checkboxes
 				newModel, cmd := m.navigateToBackgroundsSubmenu()
 				return newModel.(model), cmd
@@ -1851,7 +1901,7 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 								// Fast print speed for main UI (3ms per char = very fast)
 								m.printEffect = animations.NewPrintEffect(ascii, time.Millisecond*3)
 								if m.config.Debug {
-									logDebug("Print effect initialized with ASCII (%d lines)", len(strings.Split(ascii, "\n")))
+									animationLog.Debug("Print effect initialized", "lines", len(strings.Split(ascii, "\n")))
 								}
 							} else {
 								if m.config.Debug {
@@ -1896,7 +1946,7 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 
 								beamColors, finalColors := getThemeColorsForBeams(m.currentTheme)
 								if m.config.Debug {
-									logDebug("Initializing beams with theme: %s, beamColors: %v, finalColors: %v", m.currentTheme, beamColors, finalColors)
+									animationLog.Debug("Initializing beams", "theme", m.currentTheme, "beam_colors", beamColors, "final_colors", finalColors)
 								}
 
 								lines := strings.Split(ascii, "\n")
@@ -1916,7 +1966,7 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 									FinalGradientStops: finalColors,
 								})
 								if m.config.Debug {
-									logDebug("Beams effect initialized")
+									animationLog.Debug("Beams effect initialized")
 								}
 							}
 						}
@@ -1957,7 +2007,7 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 
 								pourColors := getThemeColorsForPour(m.currentTheme)
 								if m.config.Debug {
-									logDebug("Initializing pour with theme: %s, colors: %v", m.currentTheme, pourColors)
+									animationLog.Debug("Initializing pour", "theme", m.currentTheme, "colors", pourColors)
 								}
 
 								lines := strings.Split(ascii, "\n")
@@ -1984,7 +2034,7 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 									FinalGradientDirection: "horizontal",
 								})
 								if m.config.Debug {
-									logDebug("Pour effect initialized")
+									animationLog.Debug("Pour effect initialized")
 								}
 							}
 						}
@@ -1996,21 +2046,7 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 				}
 
 				// Save preference
-				if !m.config.TestMode {
-					sessionName := ""
-					if m.selectedSession != nil {
-						sessionName = m.selectedSession.Name
-					}
-					cache.SavePreferences(cache.UserPreferences{
-						Theme:       m.currentTheme,
-						Background:  m.selectedBackground,
-						Wallpaper:   m.selectedWallpaper,
-						BorderStyle: m.selectedBorderStyle,
-						Session:     sessionName,
-						Username:    "",
-						ASCIIIndex:  m.asciiArtIndex,
-					})
-				}
+				m.savePreferences("", func(p *cache.UserPreferences) { p.Background = m.selectedBackground })
 				// Refresh menu to update checkboxes
 				newModel, cmd := m.navigateToASCIIEffectsSubmenu()
 				return newModel.(model), cmd
@@ -2029,16 +2065,54 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 				m.focusState = FocusUsername
 				m.usernameInput.Focus()
 				return m, textinput.Blink
+			} else if m.focusState == FocusCommand {
+				// Enter from the command goes to username
+				m.commandInput.Blur()
+				m.focusState = FocusUsername
+				m.usernameInput.Focus()
+				return m, textinput.Blink
 			} else {
 				// Enter from username goes to password
+				// A conversation left behind (e.g. via F2) is cancelled before starting over
+				if user, ok := m.pickedUser(); ok {
+					// Picked from the user list
+					m.usernameInput.SetValue(user.Name)
+					m.usernameInput.CursorEnd()
+				}
+				var cancelCmd tea.Cmd
+				m, cancelCmd = m.abortAuth()
+				// The guest entry needs no username
+				// FIXED 2026-10-16 - The old session is cancelled before a new one is created
+				if m.guestSelected() {
+					newModel, cmd := m.startGuest()
+					return newModel, tea.Sequence(cancelCmd, cmd)
+				}
+				// A custom command that cannot be run is fixed before logging in
+				if msg := m.customCommandError(); msg != "" {
+					m.errorMessage = msg
+					m = m.focusCommand()
+					return m, tea.Batch(cancelCmd, textinput.Blink)
+				}
+				// Users the [access] policy rejects never reach the password field
+				if msg := m.checkAccess(m.usernameInput.Value()); msg != "" {
+					newModel, cmd := m.denyLogin(msg, nil)
+					return newModel, tea.Batch(cancelCmd, cmd)
+				}
+				// A known user gets their own session, theme and background back
+				m.restoreUserPreferences(m.usernameInput.Value())
+				if m.config.UsernameOnly {
+					// Username-only mode: let greetd/PAM decide which prompts (if any) follow
+					newModel, cmd := m.submitLogin(m.usernameInput.Value(), nil)
+					return newModel, tea.Sequence(cancelCmd, cmd)
+				}
 				if m.config.Debug {
-					fmt.Println("Debug: Switching to password mode")
+					logDebug("Switching to password mode")
 				}
 				m.mode = ModePassword
 				m.focusState = FocusPassword
 				m.usernameInput.Blur()
 				m.passwordInput.Focus()
-				return m, textinput.Blink
+				return m, tea.Batch(textinput.Blink, cancelCmd)
 			}
 
 		case ModePassword:
@@ -2047,27 +2121,20 @@ func (m model) handleKeyInput(msg tea.KeyMsg) (model, tea.Cmd) {
 				m.focusState = FocusPassword
 				m.passwordInput.Focus()
 				return m, textinput.Blink
+			} else if m.authActive {
+				// Answer the current PAM prompt and continue the conversation
+				response := m.passwordInput.Take()
+				m.authPrompt = ""
+				m.mode = ModeLoading
+				return m, m.respondToAuthMessage(response)
 			} else {
-				// Enter from password submits
-				username := m.usernameInput.Value()
-				password := m.passwordInput.Value()
-				if m.config.Debug {
-					// SECURITY: Never log passwords - only log username for debugging
-					logDebug(" Authentication attempt for user: %s", username)
-				}
-				if m.config.TestMode {
-					fmt.Println("Test mode: Auth successful")
-					return m, tea.Quit
-				} else {
-					if m.ipcClient == nil {
-						fmt.Println("Error: No IPC client available")
-						return m, tea.Quit
-					}
-					m.mode = ModeLoading
-					return m, m.authenticate(username, password)
-				}
+				// Enter from password submits; the conversation wipes the password once sent
+				return m.submitLogin(m.usernameInput.Value(), m.passwordInput.Take())
 			}
 
+		case ModeChangePassword:
+			return m.submitPasswordChange()
+
 		case ModePower:
 			if m.powerIndex < len(m.powerOptions) {
 				option := m.powerOptions[m.powerIndex]
@@ -2283,109 +2350,35 @@ func (m model) renderMainView(termWidth, termHeight int) string {
 
 // Animation helper functions moved to theme.go
 
-func (m model) authenticate(username, password string) tea.Cmd {
-	return func() tea.Msg {
-		// CHANGED 2025-10-05 - Add nil check for IPC client
-		if m.ipcClient == nil {
-			return fmt.Errorf("IPC client not initialized - greeter must be run by greetd")
-		}
-
-		// Create session
-		if err := m.ipcClient.CreateSession(username); err != nil {
-			return err
-		}
-		// Receive auth message
-		resp, err := m.ipcClient.ReceiveResponse()
-		if err != nil {
-			// Cancel session on error
-			m.ipcClient.CancelSession()
-			return err
-		}
-
-		// CHANGED 2025-10-05 - Handle Error response from CreateSession
-		if errResp, ok := resp.(ipc.Error); ok {
-			// Cancel session on error
-			m.ipcClient.CancelSession()
-			return fmt.Errorf("authentication failed: %s - %s", errResp.ErrorType, errResp.Description)
-		}
-
-		if _, ok := resp.(ipc.AuthMessage); ok {
-			if m.config.Debug {
-				logDebug(" Received auth message")
-			}
-			// Send password as response
-			// FIXED: Pass password as value instead of pointer to avoid capture issues
-			passwordCopy := password
-			if err := m.ipcClient.PostAuthMessageResponse(&passwordCopy); err != nil {
-				// Cancel session on error
-				m.ipcClient.CancelSession()
-				return err
-			}
-			// Receive success or error
-			resp, err := m.ipcClient.ReceiveResponse()
-			if err != nil {
-				// Cancel session on error
-				m.ipcClient.CancelSession()
-				return err
-			}
+// Authentication conversation moved to auth.go
+// Includes: authenticate, respondToAuthMessage, converse, startSession, handleAuthMessage
 
-			// CHANGED 2025-10-05 - Handle Error response (wrong password)
-			if errResp, ok := resp.(ipc.Error); ok {
-				// Cancel session on authentication failure
-				m.ipcClient.CancelSession()
-				return fmt.Errorf("authentication failed: %s - %s", errResp.ErrorType, errResp.Description)
-			}
+// Utility helper functions (min, stripAnsi, extractCharsWithAnsi, etc.) moved to utils.go
 
-			if _, ok := resp.(ipc.Success); ok {
-				// Start session
-				if m.selectedSession == nil {
-					// Cancel session if no session selected
-					m.ipcClient.CancelSession()
-					return fmt.Errorf("no session selected")
-				}
-				// FIXED 2026-01-17 - Add --unsupported-gpu flag for Sway sessions (NVIDIA compatibility)
-				// This ensures NVIDIA users can log in without being kicked back to greeter
-				// Use filepath.Base() to handle full paths (e.g., /usr/bin/sway) and preserve original Exec
-				execParts := strings.Fields(m.selectedSession.Exec)
-				if len(execParts) > 0 && filepath.Base(execParts[0]) == "sway" {
-					// Check if --unsupported-gpu is already present (avoid duplicates)
-					hasFlag := false
-					for _, part := range execParts {
-						if part == "--unsupported-gpu" {
-							hasFlag = true
-							break
-						}
-					}
-					if !hasFlag {
-						// Insert --unsupported-gpu after the binary name but before other args
-						execParts = append([]string{execParts[0], "--unsupported-gpu"}, execParts[1:]...)
-					}
-				}
-				// Use parsed Exec (with --unsupported-gpu added if needed)
-				cmd := execParts
-				env := []string{} // Can be populated if needed
-				if err := m.ipcClient.StartSession(cmd, env); err != nil {
-					// Cancel session on StartSession failure
-					m.ipcClient.CancelSession()
-					return err
-				}
-				return "success"
-			} else {
-				// Cancel session on unexpected response
-				m.ipcClient.CancelSession()
-				return fmt.Errorf("expected success or error, got %T", resp)
-			}
-		} else {
-			// Cancel session on unexpected response
-			m.ipcClient.CancelSession()
-			return fmt.Errorf("expected auth message or error, got %T", resp)
-		}
+// runSubcommand runs the subcommand named by args[0], if any
+func runSubcommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	switch args[0] {
+	case "audit":
+		return runAuditCommand(args[1:], os.Stdout, os.Stderr), true
+	case "check":
+		return runCheckCommand(args[1:], os.Stdout, os.Stderr), true
+	case "doctor":
+		return runDoctorCommand(args[1:], os.Stdout, os.Stderr), true
+	case "run-session":
+		return runSessionCommand(args[1:], os.Stderr), true
 	}
+	return 0, false
 }
 
-// Utility helper functions (min, stripAnsi, extractCharsWithAnsi, etc.) moved to utils.go
-
 func main() {
+	// Subcommands are handled before flag parsing - the greeter's flags do not apply to them
+	if code, ok := runSubcommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
 	// CHANGED 2025-10-01 - Removed SetColorProfile - not available in lipgloss v2
 	// Color profile is now automatically detected via colorprofile package
 	// CHANGED 2025-10-14 - Removed sysc-greet.conf loading - hardcoded sessionPalettes provide all needed palettes
@@ -2405,28 +2398,50 @@ func main() {
 	flag.BoolVar(&screensaverTestMode, "screensaver", false, "Start directly in screensaver mode for testing")
 	flag.StringVar(&config.ThemeName, "theme", "", "Theme name (dracula, gruvbox, material, nord, tokyo-night, catppuccin, solarized, monochrome, transishardjob, eldritch)")
 	flag.BoolVar(&config.RememberUsername, "remember-username", true, "Remember last logged in username")
+	flag.BoolVar(&config.UsernameOnly, "username-only", false, "Log in with just a username and let PAM ask for anything else")
+	flag.StringVar(&config.AutologinUser, "autologin", "", "Log in as this user automatically after the autologin delay")
+	flag.IntVar(&config.AutologinDelay, "autologin-delay", 5, "Seconds to wait before autologin (any key cancels)")
+	flag.IntVar(&config.IPCTimeout, "ipc-timeout", 60, "Seconds to wait for each greetd response (0 waits forever)")
+	flag.BoolVar(&config.UserList, "user-list", false, "Show a list of user accounts to pick from")
+	flag.StringVar(&config.ConfigPath, "config", sysconfig.DefaultPath, "System configuration file")
 	flag.BoolVar(&config.ShowTime, "time", false, "") // Hidden flag - not shown in help
 
 	// Add help text
 	// CHANGED 2025-10-12 - Updated help text to reflect sysc-greet branding
 	// CHANGED 2025-10-14 - Removed sysc-greet.conf references
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s audit tail [-n N] [-f] [-json]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s check [-config PATH] [-data-dir DIR] [-strict]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s doctor [-greetd-config PATH] [-config PATH]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "sysc-greet - A terminal greeter for greetd\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		// Manually print flags (excluding hidden ones)
+		fmt.Fprintf(os.Stderr, "  -autologin string\n")
+		fmt.Fprintf(os.Stderr, "    	Log in as this user automatically after the autologin delay\n")
+		fmt.Fprintf(os.Stderr, "  -autologin-delay int\n")
+		fmt.Fprintf(os.Stderr, "    	Seconds to wait before autologin, any key cancels (default 5)\n")
+		fmt.Fprintf(os.Stderr, "  -config string\n")
+		fmt.Fprintf(os.Stderr, "    	System configuration file (default %s)\n", sysconfig.DefaultPath)
 		fmt.Fprintf(os.Stderr, "  -debug\n")
 		fmt.Fprintf(os.Stderr, "    	Enable debug output\n")
+		fmt.Fprintf(os.Stderr, "  -ipc-timeout int\n")
+		fmt.Fprintf(os.Stderr, "    	Seconds to wait for each greetd response, 0 waits forever (default 60)\n")
 		fmt.Fprintf(os.Stderr, "  -screensaver\n")
 		fmt.Fprintf(os.Stderr, "    	Start directly in screensaver mode for testing\n")
 		fmt.Fprintf(os.Stderr, "  -test\n")
 		fmt.Fprintf(os.Stderr, "    	Enable test mode (no actual authentication)\n")
 		fmt.Fprintf(os.Stderr, "  -theme string\n")
 		fmt.Fprintf(os.Stderr, "    	Theme name (dracula, gruvbox, material, nord, tokyo-night, catppuccin, solarized, monochrome, transishardjob, eldritch)\n")
+		fmt.Fprintf(os.Stderr, "  -user-list\n")
+		fmt.Fprintf(os.Stderr, "    	Show a list of user accounts to pick from\n")
+		fmt.Fprintf(os.Stderr, "  -username-only\n")
+		fmt.Fprintf(os.Stderr, "    	Log in with just a username and let PAM ask for anything else\n")
 		fmt.Fprintf(os.Stderr, "  -v	Show version information (shorthand)\n")
 		fmt.Fprintf(os.Stderr, "  -version\n")
 		fmt.Fprintf(os.Stderr, "    	Show version information\n")
 		fmt.Fprintf(os.Stderr, "\nConfiguration:\n")
+		fmt.Fprintf(os.Stderr, "  System config: %s (and %s/*.toml next to it)\n", sysconfig.DefaultPath, sysconfig.DropInDir)
 		fmt.Fprintf(os.Stderr, "  ASCII configs: %s/ascii_configs/\n", dataDir)
 		fmt.Fprintf(os.Stderr, "\nKey Bindings:\n")
 		fmt.Fprintf(os.Stderr, "  Tab       Cycle focus between elements\n")
@@ -2456,21 +2471,39 @@ func main() {
 		os.Exit(1)
 	}
 
-	// CHANGED 2025-10-06 - Initialize debug logging
-	initDebugLog()
-	logDebug("=== sysc-greet started ===")
-	logDebug("Version: sysc-greet greeter")
-	logDebug("Test mode: %v", config.TestMode)
-	logDebug("Debug mode: %v", config.Debug)
-	logDebug("Theme: %s", config.ThemeName)
-	logDebug("GREETD_SOCK: %s", os.Getenv("GREETD_SOCK"))
-	logDebug("WAYLAND_DISPLAY: %s", os.Getenv("WAYLAND_DISPLAY"))
-	logDebug("XDG_RUNTIME_DIR: %s", os.Getenv("XDG_RUNTIME_DIR"))
+	// A broken config file must never block login - warn and carry on with what could be read
+	// (the [log] section is needed before logging starts, so this goes to stderr)
+	// CHANGED 2026-10-16 - config.d/ drop-ins and [greeter] settings, flags given on the command line win
+	setFlags := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	configErr := loadSystemConfig(&config, setFlags)
+	for _, problem := range configProblems(configErr) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", problem)
+	}
+
+	// CHANGED 2025-10-06 - Initialize logging (see logging.go)
+	logCloser, err := setupLogging(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: logging disabled: %v\n", err)
+	}
+	defer logCloser.Close()
+	greeterLog.Info("sysc-greet started", "version", Version, "test_mode", config.TestMode, "debug", config.Debug)
+	greeterLog.Debug("Environment",
+		"theme", config.ThemeName,
+		"greetd_sock", os.Getenv("GREETD_SOCK"),
+		"wayland_display", os.Getenv("WAYLAND_DISPLAY"),
+		"xdg_runtime_dir", os.Getenv("XDG_RUNTIME_DIR"))
+	for _, problem := range configProblems(configErr) {
+		logWarn("System config: %s", problem)
+	}
 
-	if config.Debug {
-		fmt.Printf("Debug mode enabled\n")
-		fmt.Printf("Debug log: /tmp/sysc-greet-debug.log\n")
+	// Login audit trail (see audit.go) - a broken audit log must not block login either
+	auditLog, err := openAudit(config)
+	if err != nil {
+		greeterLog.Error("Audit log disabled", "err", err)
 	}
+	config.Audit = auditLog
+	defer auditLog.Close()
 
 	// Initialize Bubble Tea program with proper screen management
 	// CHANGED 2025-09-29 - Handle TTY access gracefully for different environments
@@ -2491,10 +2524,16 @@ func main() {
 		}
 	}
 
+	// Pick up edits to the config, themes and ASCII art without a restart (see reload.go)
+	config.Watcher = startConfigWatcher(config)
+	defer config.Watcher.Close()
+
 	p := tea.NewProgram(initialModel(config, screensaverTestMode), opts...)
 
 	if _, err := p.Run(); err != nil {
+		greeterLog.Error("Greeter exited with an error", "err", err)
 		fmt.Printf("Error: %v\n", err)
+		logCloser.Close()
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+// password_change.go - change-password view for expired credentials
+// When PAM reports an expired password it asks for the current password, a new one
+// and a confirmation, all as secret prompts. The new and confirm prompts are answered
+// from two masked fields that are compared locally before anything is sent
+
+// changeStep is the prompt the change-password view is answering
+type changeStep int
+
+const (
+	changeCurrent changeStep = iota // "Current password:" (or any other prompt during the change)
+	changeNew                       // "New password:" - shows the new and confirm fields
+)
+
+var (
+	// pam_unix: "You are required to change your password immediately (administrator enforced)",
+	// "Your password has expired; ..."; sssd: "Password expired. Change your password now."
+	expiryNoticeRe = regexp.MustCompile(`(?i)password (?:has )?expired|change your password|password change required`)
+	// "New password:", "Enter new UNIX password:"
+	newPromptRe = regexp.MustCompile(`(?i)\bnew\b.*password`)
+	// "Retype new password:", "Re-enter new password:", "Confirm new password:"
+	confirmPromptRe = regexp.MustCompile(`(?i)retype|re-type|re-enter|reenter|confirm|repeat|again|verify`)
+)
+
+// notePasswordExpiry switches the conversation to the change-password view when
+// a PAM notice says the password expired
+func (m *model) notePasswordExpiry(text string) {
+	if expiryNoticeRe.MatchString(text) {
+		m.passwordChange = true
+	}
+}
+
+// isPasswordChangePrompt reports whether a secret prompt belongs to a password change
+func (m model) isPasswordChangePrompt(prompt string) bool {
+	return m.passwordChange || newPromptRe.MatchString(prompt)
+}
+
+// handlePasswordChangePrompt shows the change-password view for a PAM prompt
+// The confirmation prompt is answered with the new password the user already confirmed
+func (m model) handlePasswordChangePrompt(prompt string) (model, tea.Cmd) {
+	m.passwordChange = true
+	m.authPrompt = prompt
+
+	if confirmPromptRe.MatchString(prompt) && m.pendingConfirm != nil {
+		response := m.pendingConfirm
+		m.pendingConfirm = nil
+		m.authPrompt = ""
+		m.mode = ModeLoading
+		return m, m.respondToAuthMessage(response)
+	}
+
+	// A new prompt after a rejected password starts over
+	m.pendingConfirm.Destroy()
+	m.pendingConfirm = nil
+	m.mode = ModeChangePassword
+	m.usernameInput.Blur()
+	m.passwordInput.Reset()
+	m.newPasswordInput.Reset()
+	m.confirmPasswordInput.Reset()
+	m.passwordInput.Blur()
+	m.newPasswordInput.Blur()
+	m.confirmPasswordInput.Blur()
+
+	if newPromptRe.MatchString(prompt) && !confirmPromptRe.MatchString(prompt) {
+		m.changeStep = changeNew
+		m.focusState = FocusNewPassword
+		m.newPasswordInput.Focus()
+	} else {
+		m.changeStep = changeCurrent
+		m.focusState = FocusPassword
+		m.passwordInput.Focus()
+	}
+	return m, textinput.Blink
+}
+
+// submitPasswordChange answers the current prompt of the change-password view
+func (m model) submitPasswordChange() (model, tea.Cmd) {
+	if m.changeStep == changeCurrent {
+		response := m.passwordInput.Take()
+		m.authPrompt = ""
+		m.mode = ModeLoading
+		return m, m.respondToAuthMessage(response)
+	}
+
+	// Enter in the new password field moves on to the confirmation
+	if m.focusState == FocusNewPassword {
+		return m.focusChangeField(FocusConfirmPassword), textinput.Blink
+	}
+
+	switch {
+	case m.newPasswordInput.Len() == 0:
+		m.errorMessage = "Enter a new password"
+		return m.focusChangeField(FocusNewPassword), textinput.Blink
+	case !m.newPasswordInput.Equal(m.confirmPasswordInput):
+		m.errorMessage = "Passwords do not match"
+		m.confirmPasswordInput.Reset()
+		return m.focusChangeField(FocusConfirmPassword), textinput.Blink
+	}
+
+	// PAM asks for the confirmation next; it is answered from pendingConfirm
+	newPassword := m.newPasswordInput.Take()
+	m.pendingConfirm = newPassword.Clone()
+	m.confirmPasswordInput.Reset()
+	m.errorMessage = ""
+	m.authPrompt = ""
+	m.mode = ModeLoading
+	return m, m.respondToAuthMessage(newPassword)
+}
+
+// focusChangeField moves focus between the new and confirm fields
+func (m model) focusChangeField(target FocusState) model {
+	m.focusState = target
+	if target == FocusNewPassword {
+		m.confirmPasswordInput.Blur()
+		m.newPasswordInput.Focus()
+	} else {
+		m.newPasswordInput.Blur()
+		m.confirmPasswordInput.Focus()
+	}
+	return m
+}
+
+// resetPasswordChange clears the change-password state and fields
+func (m *model) resetPasswordChange() {
+	m.passwordChange = false
+	m.pendingConfirm.Destroy()
+	m.pendingConfirm = nil
+	m.changeStep = changeCurrent
+	m.newPasswordInput.Reset()
+	m.confirmPasswordInput.Reset()
+	m.newPasswordInput.Blur()
+	m.confirmPasswordInput.Blur()
+}
+
+// passwordStrength rates a new password from its length and character classes
+// It is only a hint - the PAM stack (pam_pwquality) has the final say
+func passwordStrength(password []byte) (string, int) {
+	var lower, upper, digit, symbol bool
+	for i := 0; i < len(password); {
+		r, size := utf8.DecodeRune(password[i:])
+		i += size
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+
+	length := utf8.RuneCount(password)
+	score := 0
+	if length >= 8 {
+		score++
+	}
+	if length >= 12 {
+		score++
+	}
+	if classes >= 3 {
+		score++
+	}
+	if classes == 4 {
+		score++
+	}
+	if length < 8 {
+		score = 0
+	}
+
+	labels := []string{"Weak", "Weak", "Fair", "Good", "Strong"}
+	return labels[score], score
+}
+
+// renderChangePasswordForm renders the change-password view below the session selector
+func (m model) renderChangePasswordForm(width int) []string {
+	var parts []string
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(Accent)
+	parts = append(parts, titleStyle.Render("Password change required"), "")
+
+	// PAM notices explain why (expired, administrator enforced, rejected password)
+	if notices := m.renderAuthNotices(width); notices != "" {
+		parts = append(parts, notices, "")
+	}
+
+	inputStyle := lipgloss.NewStyle().
+		Background(BgBase).
+		Padding(0, 1)
+	row := func(label string, target FocusState, input secretInput) string {
+		labelWidth := max(18, lipgloss.Width(label))
+		return lipgloss.JoinHorizontal(
+			lipgloss.Left,
+			lipgloss.NewStyle().
+				Bold(true).
+				Foreground(m.getFocusColor(target)).
+				Width(labelWidth).
+				Render(label),
+			" ",
+			inputStyle.Render(input.View()),
+		)
+	}
+
+	if m.changeStep == changeCurrent {
+		parts = append(parts, row(m.authPromptLabel(), FocusPassword, m.passwordInput))
+	} else {
+		parts = append(parts, row(m.authPromptLabel(), FocusNewPassword, m.newPasswordInput))
+		parts = append(parts, row("Confirm password:", FocusConfirmPassword, m.confirmPasswordInput))
+
+		// Inline hints: strength of the new password and whether the fields match
+		if m.newPasswordInput.Len() > 0 {
+			label, score := passwordStrength(m.newPasswordInput.Bytes())
+			colors := []string{"#FF5555", "#FF5555", "#FFAA00", "#F1FA8C", "#50FA7B"}
+			hint := lipgloss.NewStyle().
+				Foreground(lipgloss.Color(colors[score])).
+				Render(fmt.Sprintf("Strength: %s", label))
+			if m.confirmPasswordInput.Len() > 0 {
+				if m.confirmPasswordInput.Equal(m.newPasswordInput) {
+					hint += lipgloss.NewStyle().Foreground(lipgloss.Color("#50FA7B")).Render("  ✓ Passwords match")
+				} else {
+					hint += lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5555")).Render("  ✗ Passwords do not match")
+				}
+			}
+			parts = append(parts, "", hint)
+		}
+	}
+
+	// CAPS LOCK warning
+	if m.capsLockOn {
+		capsLockStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF5555")).
+			Bold(true).
+			Align(lipgloss.Center).
+			Width(width)
+		parts = append(parts, "", capsLockStyle.Render("⚠ CAPS LOCK ON"))
+	}
+
+	if m.errorMessage != "" {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF5555")).
+			Bold(true)
+		parts = append(parts, "", errorStyle.Render("✗ "+m.errorMessage))
+	}
+	return parts
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Nomadcxx/sysc-greet/internal/ipc"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+)
+
+// expiredUser scripts pam_unix asking alice to change an expired password
+func expiredUser(srv *greetdtest.Server) {
+	srv.AddUser("alice",
+		greetdtest.Secret("Password:", "old-pass"),
+		greetdtest.ErrorMessage("You are required to change your password immediately (administrator enforced)"),
+		greetdtest.Secret("Current password:", "old-pass"),
+		greetdtest.Secret("New password:", "N3w-Passw0rd!"),
+		greetdtest.Secret("Retype new password:", "N3w-Passw0rd!"),
+	)
+}
+
+func TestPasswordChange(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	expiredUser(srv)
+	m := newTestModel(t, srv)
+
+	m, cmd := login(t, m, "alice", "old-pass")
+
+	// The expiry notice is acknowledged, then the current password is asked in the change view
+	msg := awaitAuth(t, cmd)
+	m, cmd = update(t, m, msg)
+	msg = awaitAuth(t, cmd)
+	m, _ = update(t, m, msg)
+	if m.mode != ModeChangePassword || m.changeStep != changeCurrent {
+		t.Fatalf("expected current password step, got mode %s step %d", m.mode, m.changeStep)
+	}
+	if m.authPromptLabel() != "Current password:" {
+		t.Fatalf("unexpected prompt label %q", m.authPromptLabel())
+	}
+	setSecret(m.passwordInput, "old-pass")
+	m, cmd = update(t, m, keyEnter)
+
+	// New and confirm fields
+	msg = awaitAuth(t, cmd)
+	m, _ = update(t, m, msg)
+	if m.mode != ModeChangePassword || m.changeStep != changeNew || m.focusState != FocusNewPassword {
+		t.Fatalf("expected new password step, got mode %s step %d focus %d", m.mode, m.changeStep, m.focusState)
+	}
+
+	// A mismatch is caught locally, nothing is sent to greetd
+	sent := len(srv.Requests())
+	setSecret(m.newPasswordInput, "N3w-Passw0rd!")
+	m, _ = update(t, m, keyEnter)
+	if m.focusState != FocusConfirmPassword {
+		t.Fatalf("expected Enter to move to the confirm field, got focus %d", m.focusState)
+	}
+	setSecret(m.confirmPasswordInput, "typo")
+	m, _ = update(t, m, keyEnter)
+	if m.errorMessage != "Passwords do not match" || m.mode != ModeChangePassword {
+		t.Fatalf("expected mismatch error, got mode %s message %q", m.mode, m.errorMessage)
+	}
+	if len(srv.Requests()) != sent {
+		t.Fatal("mismatched passwords were sent to greetd")
+	}
+
+	// Matching passwords answer both the new and the retype prompt
+	setSecret(m.confirmPasswordInput, "N3w-Passw0rd!")
+	m, cmd = update(t, m, keyEnter)
+	if m.mode != ModeLoading {
+		t.Fatalf("expected loading after confirming, got %s", m.mode)
+	}
+	msg = awaitAuth(t, cmd)
+	if am, ok := msg.(authMessageMsg); !ok || am.AuthMessage != "Retype new password:" {
+		t.Fatalf("expected retype prompt, got %v", msg)
+	}
+	m, cmd = update(t, m, msg)
+	if m.mode != ModeLoading {
+		t.Fatalf("expected retype prompt to be answered automatically, got %s", m.mode)
+	}
+	if msg = awaitAuth(t, cmd); msg != "success" {
+		t.Fatalf("expected success, got %v", msg)
+	}
+
+	var responses []string
+	for _, req := range srv.Requests() {
+		if req.Type == ipc.PostAuthMessageResponseRequest && req.Response != nil {
+			responses = append(responses, *req.Response)
+		}
+	}
+	want := []string{"old-pass", "old-pass", "N3w-Passw0rd!", "N3w-Passw0rd!"}
+	if len(responses) != len(want) {
+		t.Fatalf("responses = %q, want %q", responses, want)
+	}
+	for i := range want {
+		if responses[i] != want[i] {
+			t.Fatalf("responses = %q, want %q", responses, want)
+		}
+	}
+}
+
+func TestPasswordStrength(t *testing.T) {
+	tests := []struct {
+		password string
+		want     string
+	}{
+		{"", "Weak"},
+		{"Ab1!", "Weak"},
+		{"password", "Weak"},
+		{"Password1", "Fair"},
+		{"password12345", "Fair"},
+		{"Password12345", "Good"},
+		{"Password-12345", "Strong"},
+	}
+
+	for _, tt := range tests {
+		if got, _ := passwordStrength([]byte(tt.password)); got != tt.want {
+			t.Errorf("passwordStrength(%q) = %q, want %q", tt.password, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/Nomadcxx/sysc-greet/internal/cache"
+)
+
+// preferences.go - per-user preferences restored when a known username is entered
+
+// restoreUserPreferences applies the theme, background, border, session and ASCII
+// variant username used last time, if the cache knows that user
+func (m *model) restoreUserPreferences(username string) {
+	if m.config.TestMode || username == "" || username == m.prefsUser {
+		return
+	}
+	m.prefsUser = username
+
+	prefs, err := cache.LoadUserPreferences(username)
+	if err != nil {
+		greeterLog.Warn("Failed to load preferences", "user", username, "err", err)
+		return
+	}
+	if prefs == nil {
+		return
+	}
+
+	if prefs.Theme != "" && prefs.Theme != m.currentTheme {
+		m.currentTheme = prefs.Theme
+		applyTheme(prefs.Theme, m.config.TestMode)
+	}
+	if prefs.Background != "" {
+		m.selectedBackground = prefs.Background
+	}
+	if prefs.BorderStyle != "" {
+		m.selectedBorderStyle = prefs.BorderStyle
+	}
+	if prefs.Session != "" {
+		for i, s := range m.sessions {
+			if s.Name == prefs.Session {
+				m.selectedSession = &m.sessions[i]
+				m.sessionIndex = i
+				break
+			}
+		}
+	}
+	m.asciiArtIndex = prefs.ASCIIIndex
+	m.initASCIIEffects()
+	greeterLog.Debug("Restored preferences", "user", username, "session", prefs.Session, "theme", prefs.Theme)
+}
+
+// savePreferences changes the cached preferences with update, for username too
+// when it is not empty. Only the fields update sets are written, so saving one
+// choice never wipes another (see cache.UpdatePreferences)
+func (m model) savePreferences(username string, update func(*cache.UserPreferences)) {
+	if m.config.TestMode {
+		return
+	}
+	if err := cache.UpdatePreferences(username, update); err != nil {
+		greeterLog.Warn("Failed to save preferences", "user", username, "err", err)
+	}
+}
+
+// rememberedUsername is the username in the form when usernames are remembered
+func (m model) rememberedUsername() string {
+	if !m.config.RememberUsername {
+		return ""
+	}
+	return m.usernameInput.Value()
+}
+
+// currentPreferences sets every preference to what the greeter shows now
+func (m model) currentPreferences(p *cache.UserPreferences) {
+	p.Theme = m.currentTheme
+	p.Background = m.selectedBackground
+	p.Wallpaper = m.selectedWallpaper
+	p.BorderStyle = m.selectedBorderStyle
+	if m.selectedSession != nil {
+		p.Session = m.selectedSession.Name
+	}
+	p.ASCIIIndex = m.asciiArtIndex
+}
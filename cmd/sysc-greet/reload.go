@@ -0,0 +1,130 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+	"github.com/Nomadcxx/sysc-greet/internal/themes"
+	"github.com/Nomadcxx/sysc-greet/internal/watch"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+// reload.go - applying config, theme and ASCII art edits while the greeter runs
+// The directories are watched with inotify (internal/watch). Each batch of
+// changes is parsed once and applied to the model; the form, the selected
+// session and the mode stay as they are
+
+// configChangedMsg carries a batch of changed files
+type configChangedMsg struct {
+	paths []string
+}
+
+// configWatchDirs returns the directories the greeter reads its files from
+func configWatchDirs(config Config) []string {
+	configDir := filepath.Dir(config.ConfigPath)
+	dirs := []string{configDir, filepath.Join(configDir, "config.d"), filepath.Join(dataDir, "ascii_configs")}
+	return append(dirs, customThemeDirs(dataDir)...)
+}
+
+// startConfigWatcher starts watching the config directories
+// Returns nil when inotify is not available; the greeter works as before
+func startConfigWatcher(config Config) *watch.Watcher {
+	w, err := watch.New(configWatchDirs(config))
+	if err != nil {
+		configLog.Warn("Not watching config files for changes", "err", err)
+		return nil
+	}
+	return w
+}
+
+// waitForConfigChange waits for the next batch of changes
+func waitForConfigChange(w *watch.Watcher) tea.Cmd {
+	if w == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		paths, err := w.Next()
+		if err != nil {
+			if err != watch.ErrClosed {
+				configLog.Warn("Stopped watching config files", "err", err)
+			}
+			return nil
+		}
+		return configChangedMsg{paths}
+	}
+}
+
+// applyConfigChanges reparses what changed in paths and applies it
+// The system config is reloaded as a whole, but only settings read while the
+// greeter runs take effect; those applied at startup, such as [greeter] and
+// [paths], need a restart
+func (m *model) applyConfigChanges(paths []string) {
+	configDir := filepath.Dir(m.config.ConfigPath)
+	dropInDir := filepath.Join(configDir, "config.d")
+	asciiDir := filepath.Join(dataDir, "ascii_configs")
+	themeDirs := customThemeDirs(dataDir)
+
+	var system, screensaver, ascii, customThemes bool
+	for _, path := range paths {
+		dir, name := filepath.Dir(path), filepath.Base(path)
+		switch {
+		// A directory is reported when anything in it may have changed
+		case path == configDir, path == dropInDir:
+			system = true
+		case path == asciiDir:
+			screensaver, ascii = true, true
+		case slices.Contains(themeDirs, path):
+			customThemes = true
+
+		case path == m.config.ConfigPath, dir == dropInDir && strings.HasSuffix(name, ".toml"):
+			system = true
+		case dir == asciiDir && name == "screensaver.conf":
+			screensaver = true
+		case dir == asciiDir && strings.HasSuffix(name, ".conf"):
+			ascii = true
+		case slices.Contains(themeDirs, dir) && strings.HasSuffix(name, ".toml"):
+			customThemes = true
+		}
+	}
+
+	if system {
+		file, err := sysconfig.Load(m.config.ConfigPath)
+		for _, problem := range configProblems(err) {
+			configLog.Warn("System config: " + problem)
+		}
+		if len(file.Broken) > 0 {
+			// A half-saved edit must not replace a working [access] policy with
+			// nothing; the next save that parses is applied
+			configLog.Warn("Keeping the previous system config", "broken", file.Broken)
+		} else {
+			m.config.System = file
+			screensaver = true // [screensaver] overrides screensaver.conf
+			configLog.Info("Reloaded system config", "path", m.config.ConfigPath)
+		}
+	}
+
+	if screensaver {
+		m.screensaverConfig = loadScreensaverConfig(m.config.System.Screensaver)
+		configLog.Info("Reloaded screensaver config")
+	}
+
+	if customThemes {
+		// Themes that were removed or broken must not linger
+		clear(themes.CustomThemes)
+		names := themes.ScanCustomThemes(themeDirs)
+		m.availableThemes = append(themes.GetAvailableThemes(), names...)
+		if _, ok := themes.CustomThemes[strings.ToLower(m.currentTheme)]; ok {
+			setThemeColors(m.currentTheme)
+			ascii = true // Effects take their colors from the theme
+		}
+		configLog.Info("Reloaded custom themes", "themes", len(names))
+	}
+
+	if ascii {
+		// The art itself is read when rendering; effects keep a copy of it
+		m.initASCIIEffects()
+		configLog.Info("Reloaded ASCII art")
+	}
+}
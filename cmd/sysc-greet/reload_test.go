@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+	"github.com/Nomadcxx/sysc-greet/internal/themes"
+	"github.com/Nomadcxx/sysc-greet/internal/watch"
+)
+
+// useDataDir points dataDir at a temporary directory for one test
+func useDataDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	old := dataDir
+	dataDir = dir
+	t.Cleanup(func() {
+		dataDir = old
+		clear(themes.CustomThemes)
+	})
+	return dir
+}
+
+func TestConfigChangesKeepInput(t *testing.T) {
+	data := useDataDir(t)
+	etc := t.TempDir()
+	m := newTestModel(t, greetdtest.NewServer(t))
+	m.config.ConfigPath = filepath.Join(etc, "config.toml")
+	m.usernameInput.SetValue("ali")
+
+	writeFiles(t, data, map[string]string{
+		"ascii_configs/screensaver.conf": "idle_timeout=9\n",
+		"themes/harbor.toml":             validTheme,
+	})
+	writeFiles(t, etc, map[string]string{
+		"config.toml":            "[appearance]\ntheme = \"harbor\"\n",
+		"config.d/10-site.toml":  "[screensaver]\nclock_style = \"plain\"\n",
+		"config.d/notes.txt":     "ignored",
+		"config.d/20-other.toml": "[screensaver]\ntime_format = \"15:04\"\n",
+	})
+
+	m, _ = update(t, m, configChangedMsg{paths: []string{
+		filepath.Join(data, "ascii_configs", "screensaver.conf"),
+		filepath.Join(data, "themes", "harbor.toml"),
+		filepath.Join(etc, "config.d", "10-site.toml"),
+	}})
+
+	if m.usernameInput.Value() != "ali" || m.mode != ModeLogin || m.focusState != FocusUsername {
+		t.Fatalf("expected the form to be untouched, got %q in mode %v", m.usernameInput.Value(), m.mode)
+	}
+	if m.config.System.Appearance.Theme != "harbor" {
+		t.Errorf("expected the system config to be reloaded, got %+v", m.config.System.Appearance)
+	}
+	if got := m.screensaverConfig; got.IdleTimeout != 9 || got.ClockStyle != "plain" || got.TimeFormat != "15:04" {
+		t.Errorf("expected screensaver.conf and the drop-ins to apply, got %+v", got)
+	}
+	if !slices.Contains(m.availableThemes, "Harbor") {
+		t.Errorf("expected the new custom theme to be listed, got %v", m.availableThemes)
+	}
+
+	// A removed theme disappears from the list
+	os.Remove(filepath.Join(data, "themes", "harbor.toml"))
+	m, _ = update(t, m, configChangedMsg{paths: []string{filepath.Join(data, "themes")}})
+	if slices.Contains(m.availableThemes, "Harbor") {
+		t.Errorf("expected the removed theme to be gone, got %v", m.availableThemes)
+	}
+}
+
+func TestConfigWatcherReportsEdits(t *testing.T) {
+	data := useDataDir(t)
+	etc := t.TempDir()
+	os.Mkdir(filepath.Join(data, "ascii_configs"), 0755)
+	config := Config{ConfigPath: filepath.Join(etc, "config.toml")}
+
+	w, err := watch.New(configWatchDirs(config))
+	if err != nil {
+		t.Skipf("inotify not available: %v", err)
+	}
+	defer w.Close()
+
+	path := filepath.Join(data, "ascii_configs", "screensaver.conf")
+	os.WriteFile(path, []byte("clock_style=plain\n"), 0644)
+	msg, ok := waitForConfigChange(w)().(configChangedMsg)
+	if !ok || !slices.Contains(msg.paths, path) {
+		t.Fatalf("expected %s to be reported, got %+v", path, msg)
+	}
+
+	w.Close()
+	if msg := waitForConfigChange(w)(); msg != nil {
+		t.Fatalf("expected no message once closed, got %+v", msg)
+	}
+	if waitForConfigChange(nil) != nil {
+		t.Fatal("expected no command without a watcher")
+	}
+	if !strings.HasSuffix(configWatchDirs(config)[1], "config.d") {
+		t.Fatalf("expected config.d to be watched, got %v", configWatchDirs(config))
+	}
+}
+
+func TestBrokenConfigEditKeepsPolicy(t *testing.T) {
+	useDataDir(t)
+	etc := t.TempDir()
+	m := newTestModel(t, greetdtest.NewServer(t))
+	m.config.ConfigPath = filepath.Join(etc, "config.toml")
+	changed := configChangedMsg{paths: []string{m.config.ConfigPath}}
+
+	writeFiles(t, etc, map[string]string{"config.toml": "[access]\ndeny = [\"root\"]\n"})
+	m, _ = update(t, m, changed)
+
+	// Saved halfway through an edit
+	writeFiles(t, etc, map[string]string{"config.toml": "[access]\ndeny = [\"root\"\n"})
+	m, _ = update(t, m, changed)
+	if !slices.Equal(m.config.System.Access.Deny, []string{"root"}) || len(m.config.System.Broken) != 0 {
+		t.Fatalf("expected the previous config to be kept, got %+v", m.config.System)
+	}
+
+	writeFiles(t, etc, map[string]string{"config.toml": "[access]\ndeny = [\"root\", \"guest\"]\n"})
+	m, _ = update(t, m, changed)
+	if !slices.Equal(m.config.System.Access.Deny, []string{"root", "guest"}) {
+		t.Fatalf("expected the fixed config to be applied, got %+v", m.config.System.Access)
+	}
+}
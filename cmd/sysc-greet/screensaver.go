@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/Nomadcxx/sysc-greet/internal/animations"
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss/v2"
 )
@@ -25,7 +26,8 @@ type ScreensaverConfig struct {
 }
 
 // loadScreensaverConfig loads screensaver configuration
-func loadScreensaverConfig() ScreensaverConfig {
+// Settings from the [screensaver] section of the system config override the file
+func loadScreensaverConfig(overrides sysconfig.Screensaver) ScreensaverConfig {
 	// Default config with one ASCII variant
 	defaultASCII := `▄▀▀▀▀ █   █ ▄▀▀▀▀ ▄▀▀▀▀    ▄▀    ▄▀
  ▀▀▀▄ ▀▀▀▀█  ▀▀▀▄ █      ▄▀    ▄▀
@@ -60,7 +62,7 @@ func loadScreensaverConfig() ScreensaverConfig {
 	}
 
 	if err != nil {
-		return config
+		return config.apply(overrides)
 	}
 	defer file.Close()
 
@@ -151,7 +153,53 @@ func loadScreensaverConfig() ScreensaverConfig {
 		config.ASCIIVariants = config.ASCIIVariants[1:] // Remove default, keep loaded variants
 	}
 
-	return config
+	return config.apply(overrides)
+}
+
+// apply returns c with the settings given in the [screensaver] section
+func (c ScreensaverConfig) apply(o sysconfig.Screensaver) ScreensaverConfig {
+	if o.IdleTimeout != nil {
+		c.IdleTimeout = *o.IdleTimeout
+	}
+	if o.TimeFormat != "" {
+		c.TimeFormat = o.TimeFormat
+	}
+	if o.DateFormat != "" {
+		c.DateFormat = o.DateFormat
+	}
+	if o.ClockStyle != "" {
+		c.ClockStyle = o.ClockStyle
+	}
+	if o.AnimateOnStart != nil {
+		c.AnimateOnStart = *o.AnimateOnStart
+	}
+	if o.AnimationType != "" {
+		c.AnimationType = o.AnimationType
+	}
+	if o.AnimationSpeed != nil {
+		c.AnimationSpeed = *o.AnimationSpeed
+	}
+	if len(o.ASCII) > 0 {
+		c.ASCIIVariants = o.ASCII
+	}
+	return c
+}
+
+// activateScreensaver switches to the screensaver, starting the print animation if enabled
+// The form is reset per the [idle] policy; the returned command cancels an open greetd session
+func (m *model) activateScreensaver(ssConfig ScreensaverConfig) tea.Cmd {
+	// Nobody is at the keyboard - don't keep a half-typed password around
+	cancelCmd := m.resetForScreensaver()
+	m.mode = ModeScreensaver
+	m.screensaverActive = true // CHANGED 2025-10-11 - Mark screensaver as just activated
+
+	// CHANGED 2025-10-11 - Initialize print effect animation if enabled
+	if ssConfig.AnimateOnStart && ssConfig.AnimationType == "print" && len(ssConfig.ASCIIVariants) > 0 {
+		selectedASCII := ssConfig.ASCIIVariants[0] // Start with first variant
+		charDelay := time.Duration(ssConfig.AnimationSpeed) * time.Millisecond
+		m.screensaverPrint = animations.NewPrintEffect(selectedASCII, charDelay)
+	}
+	return cancelCmd
 }
 
 // renderStyledClock renders time string using the specified clock style
@@ -191,7 +239,7 @@ func renderStyledClock(timeStr string, style string) []string {
 
 // renderScreensaverView renders the screensaver with ASCII art, clock, and date
 func renderScreensaverView(m model, termWidth, termHeight int) string {
-	config := loadScreensaverConfig()
+	config := m.screensaverConfig
 
 	// Get theme-specific color palette
 	palette := animations.GetScreensaverPalette(m.currentTheme)
@@ -279,7 +327,6 @@ func renderScreensaverView(m model, termWidth, termHeight int) string {
 // handleScreensaverInput handles input in screensaver mode
 func handleScreensaverInput(m model, msg tea.KeyMsg) (model, tea.Cmd) {
 	// Exit screensaver on any key press
-	m.mode = ModeLogin
-	m.idleTimer = time.Now()
+	m.exitScreensaver()
 	return m, nil
 }
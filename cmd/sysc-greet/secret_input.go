@@ -0,0 +1,150 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/Nomadcxx/sysc-greet/internal/secret"
+	"github.com/charmbracelet/bubbles/v2/textinput"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+// secret_input.go - password field backed by a wipeable buffer
+// textinput.Model keeps its value in Go strings and rune slices that are copied on
+// every keystroke and can never be cleared. secretInput edits a secret.Buffer in
+// place instead, so the password exists once in (mlocked) memory and is zeroed by
+// Reset. Editing is append-only: Backspace deletes the last character, Ctrl+U
+// clears the field
+
+// secretInput is a masked input field
+// Copies of a secretInput share its buffer, like the model copies Bubble Tea makes
+type secretInput struct {
+	buf     *secret.Buffer
+	focused bool
+
+	Echo          bool // Show the typed characters (visible PAM prompts such as OTP codes)
+	EchoCharacter rune // Mask character shown per typed character
+	Styles        textinput.Styles
+}
+
+// newSecretInput returns an empty, blurred field using the form's input styles
+func newSecretInput() secretInput {
+	s := secretInput{
+		buf:           secret.New(),
+		EchoCharacter: '*',
+		Styles:        textinput.DefaultDarkStyles(),
+	}
+	s.Styles.Focused.Prompt = lipgloss.NewStyle().Foreground(Primary).Bold(true)
+	s.Styles.Focused.Text = lipgloss.NewStyle().Foreground(FgPrimary)
+	s.Styles.Focused.Placeholder = lipgloss.NewStyle().Foreground(FgMuted).Italic(true)
+	return s
+}
+
+// Focus focuses the field
+func (s *secretInput) Focus() tea.Cmd {
+	s.focused = true
+	return nil
+}
+
+// Blur removes focus from the field
+func (s *secretInput) Blur() {
+	s.focused = false
+}
+
+// Focused reports whether the field has focus
+func (s secretInput) Focused() bool {
+	return s.focused
+}
+
+// Len returns the number of typed characters
+func (s secretInput) Len() int {
+	return s.buf.RuneCount()
+}
+
+// Bytes returns the typed secret without copying it (valid until the next change)
+func (s secretInput) Bytes() []byte {
+	return s.buf.Bytes()
+}
+
+// Equal reports whether both fields hold the same text
+func (s secretInput) Equal(o secretInput) bool {
+	return s.buf.Equal(o.buf)
+}
+
+// Take hands the typed secret over to the caller and clears the field
+// The caller owns the returned buffer and must Destroy it once sent
+func (s secretInput) Take() *secret.Buffer {
+	taken := s.buf.Clone()
+	s.buf.Wipe()
+	return taken
+}
+
+// Reset zeroes the field
+func (s secretInput) Reset() {
+	s.buf.Wipe()
+}
+
+// Update handles typing into the focused field
+func (s secretInput) Update(msg tea.Msg) (secretInput, tea.Cmd) {
+	if !s.focused {
+		return s, nil
+	}
+
+	switch msg := msg.(type) {
+	case tea.KeyPressMsg:
+		switch msg.String() {
+		case "backspace", "ctrl+h":
+			s.buf.DeleteLastRune()
+		case "ctrl+u", "ctrl+w":
+			s.buf.Wipe()
+		default:
+			s.insert(msg.Key().Text)
+		}
+	case tea.PasteMsg:
+		s.insert(string(msg))
+	}
+	return s, nil
+}
+
+// insert appends printable characters, ignoring the rest of text once the buffer is full
+func (s secretInput) insert(text string) {
+	for _, r := range text {
+		if r < ' ' || r == 0x7f {
+			continue
+		}
+		if !s.buf.AppendRune(r) {
+			return
+		}
+	}
+}
+
+// View renders the masked text followed by a block cursor when focused
+func (s secretInput) View() string {
+	styles := s.Styles.Blurred
+	if s.focused {
+		styles = s.Styles.Focused
+	}
+
+	var text string
+	if s.Echo {
+		// Visible prompts are not secret by definition, so a string copy is fine here
+		text = string(s.buf.Bytes())
+	} else {
+		text = strings.Repeat(string(s.EchoCharacter), s.buf.RuneCount())
+	}
+
+	v := styles.Text.Inline(true).Render(text)
+	if s.focused {
+		v += lipgloss.NewStyle().Reverse(true).Foreground(s.Styles.Cursor.Color).Render(" ")
+	}
+	return v
+}
+
+// wipeSecrets zeroes every password field and the pending confirmation
+func (m *model) wipeSecrets() {
+	m.passwordInput.Reset()
+	m.newPasswordInput.Reset()
+	m.confirmPasswordInput.Reset()
+	m.pendingConfirm.Destroy()
+	m.pendingConfirm = nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+	"github.com/Nomadcxx/sysc-greet/internal/secret"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+func TestSecretInputEditing(t *testing.T) {
+	tests := []struct {
+		name string
+		msgs []tea.Msg
+		want string
+	}{
+		{"typing", []tea.Msg{
+			tea.KeyPressMsg{Code: 'a', Text: "a"},
+			tea.KeyPressMsg{Code: 'B', Text: "B"},
+			tea.KeyPressMsg{Code: tea.KeySpace, Text: " "},
+			tea.KeyPressMsg{Code: '1', Text: "1"},
+		}, "aB 1"},
+		{"backspace", []tea.Msg{
+			tea.PasteMsg("pässwörd"),
+			tea.KeyPressMsg{Code: tea.KeyBackspace},
+			tea.KeyPressMsg{Code: tea.KeyBackspace},
+		}, "pässwö"},
+		{"ctrl+u clears", []tea.Msg{
+			tea.PasteMsg("hunter2"),
+			tea.KeyPressMsg{Code: 'u', Mod: tea.ModCtrl},
+			tea.KeyPressMsg{Code: 'x', Text: "x"},
+		}, "x"},
+		{"control characters ignored", []tea.Msg{
+			tea.PasteMsg("line\none\ttab"),
+		}, "lineonetab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSecretInput()
+			s.Focus()
+			for _, msg := range tt.msgs {
+				s, _ = s.Update(msg)
+			}
+			if got := string(s.Bytes()); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSecretInputView(t *testing.T) {
+	s := newSecretInput()
+	s.Focus()
+	s, _ = s.Update(tea.PasteMsg("hunter2"))
+	if view := s.View(); strings.Contains(view, "hunter2") || !strings.Contains(view, "*******") {
+		t.Fatalf("expected masked view, got %q", view)
+	}
+	s.Echo = true
+	if view := s.View(); !strings.Contains(view, "hunter2") {
+		t.Fatalf("expected visible prompt to echo, got %q", view)
+	}
+
+	s.Blur()
+	s, _ = s.Update(tea.KeyPressMsg{Code: 'x', Text: "x"})
+	if s.Len() != 7 {
+		t.Fatal("expected a blurred field to ignore keys")
+	}
+}
+
+func TestSecretsWiped(t *testing.T) {
+	t.Run("after the response is sent", func(t *testing.T) {
+		srv := greetdtest.NewServer(t)
+		srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+		m := newTestModel(t, srv)
+
+		password := secret.New()
+		password.Append([]byte("hunter2"))
+		if msg := awaitAuth(t, m.authenticate("alice", password)); msg != "success" {
+			t.Fatalf("expected success, got %v", msg)
+		}
+		if password.Len() != 0 {
+			t.Fatal("expected the password to be wiped once sent")
+		}
+		if resp := srv.Requests()[1].Response; resp == nil || *resp != "hunter2" {
+			t.Fatalf("expected the password to reach greetd, got %v", resp)
+		}
+	})
+
+	t.Run("when submitted", func(t *testing.T) {
+		srv := greetdtest.NewServer(t)
+		srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+		m := newTestModel(t, srv)
+
+		m, cmd := login(t, m, "alice", "hunter2")
+		if m.passwordInput.Len() != 0 {
+			t.Fatal("expected the field to be cleared on submit")
+		}
+		awaitAuth(t, cmd)
+	})
+
+	t.Run("on Esc", func(t *testing.T) {
+		srv := greetdtest.NewServer(t)
+		m := newTestModel(t, srv)
+
+		m.usernameInput.SetValue("alice")
+		m, _ = update(t, m, keyEnter)
+		setSecret(m.passwordInput, "hunter2")
+		m, _ = update(t, m, tea.KeyPressMsg{Code: tea.KeyEsc})
+		if m.mode != ModeLogin || m.passwordInput.Len() != 0 {
+			t.Fatalf("expected Esc to wipe the password, mode %s length %d", m.mode, m.passwordInput.Len())
+		}
+	})
+
+	t.Run("when the screensaver starts", func(t *testing.T) {
+		srv := greetdtest.NewServer(t)
+		m := newTestModel(t, srv)
+
+		setSecret(m.passwordInput, "hunter2")
+		setSecret(m.newPasswordInput, "N3w-Passw0rd!")
+		m.pendingConfirm = secret.New()
+		m.pendingConfirm.Append([]byte("N3w-Passw0rd!"))
+		m.activateScreensaver(ScreensaverConfig{})
+		if m.passwordInput.Len() != 0 || m.newPasswordInput.Len() != 0 || m.pendingConfirm != nil {
+			t.Fatal("expected the screensaver to wipe all password fields")
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/Nomadcxx/sysc-greet/internal/cache"
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+)
+
+// session_list.go - session list ordering, favorites and usage counts
+
+// sessionArrangement combines the configured order with the cached usage and favorites
+func sessionArrangement(config Config, stats cache.SessionStats) sessions.Arrangement {
+	return sessions.Arrangement{
+		Order:     config.System.Sessions.Order,
+		Pinned:    config.System.Sessions.Pinned,
+		Usage:     stats.Usage,
+		Favorites: stats.Favorites,
+	}
+}
+
+// arrangeSessions orders list by the configured arrangement, keeping the guest
+// entry first and the custom command entry last
+func arrangeSessions(list []sessions.Session, config Config, stats cache.SessionStats) []sessions.Session {
+	list = sessions.Arrange(list, sessionArrangement(config, stats))
+	for i := range list {
+		if isGuestSession(&list[i]) {
+			guest := list[i]
+			copy(list[1:i+1], list[:i])
+			list[0] = guest
+			break
+		}
+	}
+	for i := range list {
+		if isCustomSession(&list[i]) {
+			custom := list[i]
+			copy(list[i:], list[i+1:])
+			list[len(list)-1] = custom
+			break
+		}
+	}
+	return list
+}
+
+// rearrangeSessions re-sorts the session list, keeping the highlighted session selected
+func (m *model) rearrangeSessions() {
+	var current string
+	if m.sessionIndex >= 0 && m.sessionIndex < len(m.sessions) {
+		current = m.sessions[m.sessionIndex].Key()
+	}
+
+	m.sessions = arrangeSessions(m.sessions, m.config, m.sessionStats)
+	for i, s := range m.sessions {
+		if s.Key() == current {
+			m.sessionIndex = i
+			break
+		}
+	}
+}
+
+// toggleFavorite stars or unstars the session highlighted in the dropdown
+func (m *model) toggleFavorite() {
+	if m.sessionIndex < 0 || m.sessionIndex >= len(m.sessions) {
+		return
+	}
+	m.sessionStats.ToggleFavorite(m.sessions[m.sessionIndex].Key())
+	m.rearrangeSessions()
+
+	if !m.config.TestMode {
+		if err := cache.SaveSessionStats(m.sessionStats); err != nil {
+			logWarn("Failed to save session favorites: %v", err)
+		}
+	}
+}
+
+// recordSessionUse counts a successful start of the selected session for frequency ordering
+func (m *model) recordSessionUse() {
+	if m.config.TestMode || m.selectedSession == nil {
+		return
+	}
+	m.sessionStats.RecordUse(m.selectedSession.Key())
+	if err := cache.SaveSessionStats(m.sessionStats); err != nil {
+		logWarn("Failed to save session usage: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/Nomadcxx/sysc-greet/internal/cache"
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+)
+
+// system_config.go - applying /etc/sysc-greet/config.toml and config.d/*.toml
+// Precedence: built-in defaults < config.toml < config.d/ drop-ins < command
+// line flags. Problems in the files are reported but never stop the greeter:
+// it always comes up with whatever could be read
+
+// loadSystemConfig reads the system configuration into config
+// set holds the names of the flags given on the command line, which keep their
+// value. The returned error lists every problem found in the files
+func loadSystemConfig(config *Config, set map[string]bool) error {
+	system, err := sysconfig.Load(config.ConfigPath)
+	config.System = system
+
+	g := system.Greeter
+	if g.RememberUsername != nil && !set["remember-username"] {
+		config.RememberUsername = *g.RememberUsername
+	}
+	if g.UsernameOnly != nil && !set["username-only"] {
+		config.UsernameOnly = *g.UsernameOnly
+	}
+	if g.Autologin != "" && !set["autologin"] {
+		config.AutologinUser = g.Autologin
+	}
+	if g.AutologinDelay != nil && !set["autologin-delay"] {
+		config.AutologinDelay = *g.AutologinDelay
+	}
+	if g.IPCTimeout != nil && !set["ipc-timeout"] {
+		config.IPCTimeout = *g.IPCTimeout
+	}
+	if g.ShowTime != nil && !set["time"] {
+		config.ShowTime = *g.ShowTime
+	}
+	if g.Debug != nil && !set["debug"] {
+		config.Debug = *g.Debug
+	}
+	if system.Appearance.Theme != "" && !set["theme"] {
+		config.ThemeName = system.Appearance.Theme
+	}
+	if system.Users.List && !set["user-list"] {
+		config.UserList = true
+	}
+
+	if dir := system.Paths.DataDir; dir != "" {
+		dataDir = strings.TrimRight(dir, "/")
+	}
+	if len(system.Paths.WallpaperDirs) > 0 {
+		wallpaperDirs = system.Paths.WallpaperDirs
+	}
+	if dir := system.Paths.StateDir; dir != "" {
+		cache.SetDir(dir)
+	}
+	return err
+}
+
+// brokenConfigWarning returns the line shown in the form while system config
+// files are skipped because they could not be read or parsed, or ""
+// Logins still work, with the settings of the remaining files
+func (m model) brokenConfigWarning() string {
+	broken := m.config.System.Broken
+	if len(broken) == 0 {
+		return ""
+	}
+	names := make([]string, len(broken))
+	for i, path := range broken {
+		names[i] = filepath.Base(path)
+	}
+	return "Config not applied: " + strings.Join(names, ", ") + ", see the greeter log"
+}
+
+// configProblems splits the error from loadSystemConfig into one message per problem
+func configProblems(err error) []string {
+	if err == nil {
+		return nil
+	}
+	return strings.Split(err.Error(), "\n")
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Nomadcxx/sysc-greet/internal/cache"
+	sysconfig "github.com/Nomadcxx/sysc-greet/internal/config"
+)
+
+func TestLoadSystemConfigPrecedence(t *testing.T) {
+	origData, origWallpapers := dataDir, wallpaperDirs
+	defer func() { dataDir, wallpaperDirs = origData, origWallpapers }()
+	defer cache.SetDir("")
+
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "config.d"), 0755)
+	os.WriteFile(filepath.Join(dir, "config.toml"), []byte(`
+[greeter]
+remember_username = false
+ipc_timeout = 30
+autologin = "kiosk"
+
+[appearance]
+theme = "gruvbox"
+
+[paths]
+data_dir = "/opt/sysc-greet/"
+wallpaper_dirs = ["/srv/wallpapers"]
+state_dir = "/var/lib/sysc-greet"
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "config.d", "50-site.toml"), []byte(`
+[greeter]
+ipc_timeout = 90
+debug = true
+`), 0644)
+
+	// Defaults as set up by the flags
+	config := Config{RememberUsername: true, AutologinDelay: 5, IPCTimeout: 60, ThemeName: "nord", ConfigPath: filepath.Join(dir, "config.toml")}
+	set := map[string]bool{"theme": true, "debug": true}
+
+	if err := loadSystemConfig(&config, set); err != nil {
+		t.Fatalf("loadSystemConfig: %v", err)
+	}
+	if config.RememberUsername {
+		t.Error("expected the file to turn off remember_username")
+	}
+	if config.IPCTimeout != 90 {
+		t.Errorf("expected the drop-in to override the file, got ipc_timeout %d", config.IPCTimeout)
+	}
+	if config.AutologinUser != "kiosk" || config.AutologinDelay != 5 {
+		t.Errorf("expected autologin from the file with the default delay, got %q after %d", config.AutologinUser, config.AutologinDelay)
+	}
+	if config.ThemeName != "nord" || config.Debug {
+		t.Errorf("expected flags given on the command line to win, got theme %q debug %v", config.ThemeName, config.Debug)
+	}
+	if dataDir != "/opt/sysc-greet" || !reflect.DeepEqual(wallpaperDirectories(), []string{"/srv/wallpapers"}) {
+		t.Errorf("expected [paths] to apply, got %q and %v", dataDir, wallpaperDirectories())
+	}
+	if dir, _ := cache.Dir(); dir != "/var/lib/sysc-greet" {
+		t.Errorf("expected state_dir to move the cache, got %q", dir)
+	}
+}
+
+func TestLoadSystemConfigBroken(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "config.toml"), []byte("[greeter\nautologin = \"kiosk\"\n"), 0644)
+
+	config := Config{RememberUsername: true, IPCTimeout: 60, ConfigPath: filepath.Join(dir, "config.toml")}
+	err := loadSystemConfig(&config, nil)
+	if len(configProblems(err)) != 1 {
+		t.Fatalf("expected one problem, got %v", err)
+	}
+	if !config.RememberUsername || config.IPCTimeout != 60 || config.AutologinUser != "" {
+		t.Fatalf("expected the defaults to stay, got %+v", config)
+	}
+}
+
+func TestScreensaverOverrides(t *testing.T) {
+	origData := dataDir
+	defer func() { dataDir = origData }()
+	dataDir = t.TempDir()
+
+	idle, animate := 12, false
+	c := loadScreensaverConfig(sysconfig.Screensaver{
+		IdleTimeout:    &idle,
+		ClockStyle:     "plain",
+		AnimateOnStart: &animate,
+		ASCII:          []string{"ZZZ"},
+	})
+	if c.IdleTimeout != 12 || c.ClockStyle != "plain" || c.AnimateOnStart || !reflect.DeepEqual(c.ASCIIVariants, []string{"ZZZ"}) {
+		t.Fatalf("expected the [screensaver] settings to apply, got %+v", c)
+	}
+	if c.TimeFormat != "3:04:05 PM" || c.AnimationSpeed != 20 {
+		t.Fatalf("expected unset keys to keep their defaults, got %+v", c)
+	}
+}
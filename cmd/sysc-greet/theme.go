@@ -5,6 +5,7 @@ import (
 	"image/color"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -21,6 +22,15 @@ import (
 // CHANGED 2025-10-11 - Added testMode parameter
 // CHANGED 2025-12-28 - Added custom theme support
 func applyTheme(themeName string, testMode bool) {
+	setThemeColors(themeName)
+
+	// CHANGED 2025-10-10 - Set theme-aware wallpaper via swww
+	setThemeWallpaper(themeName, testMode)
+}
+
+// setThemeColors sets the color variables for themeName, leaving the wallpaper alone
+// CHANGED 2026-10-16 - Split from applyTheme so an edited custom theme can be reloaded
+func setThemeColors(themeName string) {
 	// Check if this is a custom theme
 	if theme, ok := themes.CustomThemes[strings.ToLower(themeName)]; ok {
 		// Apply custom theme colors
@@ -42,8 +52,7 @@ func applyTheme(themeName string, testMode bool) {
 		BorderDefault = theme.BorderDefault
 		BorderFocus = theme.BorderFocus
 
-		// Set wallpaper for custom theme
-		setThemeWallpaper(themeName, testMode)
+		themeLog.Debug("Applied custom theme", "theme", themeName)
 		return
 	}
 
@@ -209,9 +218,15 @@ func applyTheme(themeName string, testMode bool) {
 
 	// Update border colors based on new primary
 	BorderFocus = Primary
+}
 
-	// CHANGED 2025-10-10 - Set theme-aware wallpaper via swww
-	setThemeWallpaper(themeName, testMode)
+// customThemeDirs returns the directories scanned for custom theme files, the
+// user's own last so that they override the system ones
+func customThemeDirs(dir string) []string {
+	return []string{
+		dir + "/themes",
+		filepath.Join(os.Getenv("HOME"), ".config/sysc-greet/themes"),
+	}
 }
 
 // setThemeWallpaper sets a theme-specific wallpaper using gSlapper (preferred) or swww (fallback)
@@ -235,10 +250,12 @@ func setThemeWallpaper(themeName string, testMode bool) {
 		// Try gSlapper first (preferred)
 		if wallpaper.IsGSlapperRunning() {
 			// Use IPC to change wallpaper (flicker-free)
-			if err := wallpaper.ChangeWallpaper(wallpaperPath); err == nil {
+			err := wallpaper.ChangeWallpaper(wallpaperPath)
+			if err == nil {
 				return // Success via IPC
 			}
 			// IPC failed, fall through to restart gSlapper
+			wallpaperLog.Warn("gSlapper IPC failed, restarting gslapper", "path", wallpaperPath, "err", err)
 		}
 
 		// Check if gSlapper is available
@@ -253,9 +270,11 @@ func setThemeWallpaper(themeName string, testMode bool) {
 			cmd := exec.Command("gslapper", "-f", "-I", wallpaper.GSlapperSocket, "*", wallpaperPath)
 			cmd.Stdout = nil
 			cmd.Stderr = nil
-			if err := cmd.Start(); err == nil {
+			err := cmd.Start()
+			if err == nil {
 				return // Success
 			}
+			wallpaperLog.Warn("Failed to start gslapper", "path", wallpaperPath, "err", err)
 		}
 
 		// Fallback to swww if gSlapper unavailable/failed
@@ -277,7 +296,9 @@ func setThemeWallpaper(themeName string, testMode bool) {
 		cmd := exec.Command("swww", "img", wallpaperPath, "--transition-type", "fade", "--transition-duration", "0.5")
 		cmd.Stdout = nil
 		cmd.Stderr = nil
-		_ = cmd.Run()
+		if err := cmd.Run(); err != nil {
+			wallpaperLog.Warn("swww failed to set wallpaper", "path", wallpaperPath, "err", err)
+		}
 	}()
 }
 
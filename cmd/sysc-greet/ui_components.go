@@ -76,9 +76,27 @@ func (m model) renderMainForm(width int) string {
 	// Add spacing
 	parts = append(parts, "")
 
+	// Why the greeter is back after a failed session
+	if m.lastSession != nil && (m.mode == ModeLogin || m.mode == ModePassword) {
+		parts = append(parts, m.renderLastSession(width), "")
+	}
+
+	// Config files that are skipped, so the admin notices before the next reboot
+	if warning := m.brokenConfigWarning(); warning != "" && (m.mode == ModeLogin || m.mode == ModePassword) {
+		parts = append(parts, lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FFAA00")).
+			Width(width).
+			Render("⚠ "+warning), "")
+	}
+
 	// Current input based on mode
 	switch m.mode {
 	case ModeLogin:
+		// The custom command entry asks for the command above the username
+		if m.customSelected() {
+			parts = append(parts, m.renderCommandRow())
+		}
+
 		usernameLabel := lipgloss.NewStyle().
 			Bold(true).
 			Foreground(m.getFocusColor(FocusUsername)).
@@ -98,8 +116,33 @@ func (m model) renderMainForm(width int) string {
 			" ",
 			usernameInput,
 		)
+		if m.guestSelected() {
+			// The guest entry replaces the username field
+			usernameRow = lipgloss.NewStyle().
+				Bold(true).
+				Foreground(m.getFocusColor(FocusUsername)).
+				Render("Press Enter to start a guest session")
+		}
 		parts = append(parts, usernameRow)
 
+		// User picker
+		if m.userPickerActive() {
+			parts = append(parts, m.renderUserList(width))
+		}
+
+		// Autologin countdown
+		if !m.autologinAt.IsZero() {
+			countdownStyle := lipgloss.NewStyle().
+				Foreground(Accent).
+				Bold(true)
+			countdown := fmt.Sprintf("Logging in as %s in %ds, press any key to cancel", m.config.AutologinUser, m.autologinRemaining())
+			if m.guestSelected() {
+				countdown = fmt.Sprintf("Starting %s session in %ds, press any key to cancel", m.selectedSession.Name, m.autologinRemaining())
+			}
+			parts = append(parts, "")
+			parts = append(parts, countdownStyle.Render(countdown))
+		}
+
 		// Display error message and failed attempt counter on login screen
 		if m.errorMessage != "" {
 			errorStyle := lipgloss.NewStyle().
@@ -128,12 +171,24 @@ func (m model) renderMainForm(width int) string {
 			parts = append(parts, attemptStyle.Render(fmt.Sprintf("Failed attempts: %d", m.failedAttempts)))
 		}
 
+		// Countdown while the login backoff disables input
+		if backoff := m.renderBackoff(); backoff != "" {
+			parts = append(parts, "", backoff)
+		}
+
 	case ModePassword:
+		// PAM info/error notices received so far in the conversation
+		if notices := m.renderAuthNotices(width); notices != "" {
+			parts = append(parts, notices, "")
+		}
+
+		// Label shows the PAM prompt text during multi-step conversations
+		promptLabel := m.authPromptLabel()
 		passwordLabel := lipgloss.NewStyle().
 			Bold(true).
 			Foreground(m.getFocusColor(FocusPassword)).
-			Width(10).
-			Render("Password:")
+			Width(max(10, lipgloss.Width(promptLabel))).
+			Render(promptLabel)
 
 		// Remove Foreground, use BgBase only
 		inputStyle := lipgloss.NewStyle().
@@ -190,6 +245,14 @@ func (m model) renderMainForm(width int) string {
 			parts = append(parts, attemptStyle.Render(fmt.Sprintf("Failed attempts: %d", m.failedAttempts)))
 		}
 
+		// Countdown while the login backoff disables input
+		if backoff := m.renderBackoff(); backoff != "" {
+			parts = append(parts, "", backoff)
+		}
+
+	case ModeChangePassword:
+		parts = append(parts, m.renderChangePasswordForm(width)...)
+
 	case ModeLoading:
 		loadingStyle := lipgloss.NewStyle().
 			Bold(true).
@@ -197,6 +260,11 @@ func (m model) renderMainForm(width int) string {
 			Align(lipgloss.Center).
 			Width(width)
 
+		// Show PAM notices (e.g. "touch your security key") above the spinner
+		if notices := m.renderAuthNotices(width); notices != "" {
+			parts = append(parts, notices, "")
+		}
+
 		// Show animated spinner
 		loadingText := loadingStyle.Render("Authenticating... " + m.spinner.View())
 		parts = append(parts, loadingText)
@@ -205,6 +273,27 @@ func (m model) renderMainForm(width int) string {
 	return lipgloss.JoinVertical(lipgloss.Left, parts...)
 }
 
+// renderAuthNotices renders PAM info and error messages received during authentication
+func (m model) renderAuthNotices(width int) string {
+	if len(m.authNotices) == 0 {
+		return ""
+	}
+
+	var lines []string
+	for _, notice := range m.authNotices {
+		style := lipgloss.NewStyle().
+			Foreground(Secondary).
+			Width(width)
+		prefix := "ℹ "
+		if notice.IsError {
+			style = style.Foreground(lipgloss.Color("#FF5555")).Bold(true)
+			prefix = "✗ "
+		}
+		lines = append(lines, style.Render(prefix+notice.Text))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
 // renderSessionSelector renders the session selector with dropdown indicator
 func (m model) renderSessionSelector(width int) string {
 	// Session label with focus indication
@@ -299,6 +388,11 @@ func (m model) renderSessionDropdown(width int) string {
 	for i := start; i < end; i++ {
 		session := m.sessions[i]
 		sessionText := fmt.Sprintf("%s (%s)", session.Name, session.Type)
+		if m.sessionStats.IsFavorite(session.Key()) {
+			sessionText = "★ " + sessionText
+		} else {
+			sessionText = "  " + sessionText
+		}
 
 		var sessionStyle lipgloss.Style
 		if i == m.sessionIndex {
@@ -351,12 +445,25 @@ func (m model) renderMainHelp() string {
 	case ModeLogin, ModePassword:
 		// Reorder function keys to F1-F4 logical sequence
 		// Add Page Up/Down navigation for ASCII variants
+		// Function keys locked by [guest] are left out
+		keys := m.functionKeyHelp()
 		if m.sessionDropdownOpen {
-			return "↑↓ Navigate • ⇞⇟ ASCII • Enter Select • Esc Close • Tab Focus • F1 Menu • F2 Sessions • F3 Notes • F4 Power"
+			return "↑↓ Navigate • Ctrl+F Favorite • ⇞⇟ ASCII • Enter Select • Esc Close • Tab Focus • " + keys
+		}
+		if m.userPickerActive() {
+			return "↑↓ Users • Enter Select • Tab Focus • " + keys
+		}
+		if m.mode == ModeLogin && m.focusState == FocusCommand {
+			return "↑↓ History • Tab Focus • " + keys + " • Enter Continue"
+		}
+		if m.mode == ModeLogin && (m.config.UsernameOnly || m.guestSelected()) {
+			return "Tab Focus • ↑↓ Sessions • ⇞⇟ ASCII • " + keys + " • Enter Login"
 		}
-		return "Tab Focus • ↑↓ Sessions • ⇞⇟ ASCII • F1 Menu • F2 Sessions • F3 Notes • F4 Power • Enter Continue"
+		return "Tab Focus • ↑↓ Sessions • ⇞⇟ ASCII • " + keys + " • Enter Continue"
+	case ModeChangePassword:
+		return "Tab Next Field • Enter Continue • Esc Cancel"
 	case ModeLoading:
-		return "Please wait..."
+		return "Please wait... • Esc Cancel"
 	default:
 		return "Ctrl+C Quit"
 	}
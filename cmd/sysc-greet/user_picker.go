@@ -0,0 +1,111 @@
+package main
+
+import (
+	"github.com/Nomadcxx/sysc-greet/internal/users"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+// user_picker.go - optional list of login accounts below the username field
+// Typing filters the list, ↑↓ moves the highlight and Enter picks the highlighted user.
+// Enter without ↑↓ logs in as typed: the list matches prefixes and real names, and
+// accounts not in the list (LDAP, [users] hide) must still be reachable
+
+// loadUserList reads the accounts offered by the picker (nil when the picker is off)
+func loadUserList(config Config) []users.User {
+	if !config.UserList {
+		return nil
+	}
+	source := users.FileSource{
+		PasswdPath: config.System.Users.Passwd,
+		Hide:       config.System.Users.Hide,
+	}
+	list, err := source.Users()
+	if err != nil {
+		logWarn("Failed to load user list: %v", err)
+		return nil
+	}
+	// Accounts the [access] policy rejects are not offered
+	list = accessPolicy(config).Filter(list)
+	logDebug("Loaded %d users for the user picker", len(list))
+	return list
+}
+
+// userPickerActive reports whether the user list is shown and takes ↑↓ and Enter
+func (m model) userPickerActive() bool {
+	return m.config.UserList &&
+		m.mode == ModeLogin &&
+		m.focusState == FocusUsername &&
+		!m.sessionDropdownOpen &&
+		!m.guestSelected() &&
+		len(m.matchingUsers()) > 0
+}
+
+// matchingUsers returns the accounts matching what has been typed so far
+func (m model) matchingUsers() []users.User {
+	return users.Match(m.users, m.usernameInput.Value())
+}
+
+// moveUserSelection moves the highlight by delta, staying inside the list
+func (m *model) moveUserSelection(delta int) {
+	matches := m.matchingUsers()
+	m.userIndex = max(0, min(len(matches)-1, m.userIndex+delta))
+	m.userPicked = true
+}
+
+// pickedUser returns the highlighted account when Enter should log in as it:
+// the highlight was moved with ↑↓, or nothing was typed
+func (m model) pickedUser() (users.User, bool) {
+	if !m.userPickerActive() || (!m.userPicked && m.usernameInput.Value() != "") {
+		return users.User{}, false
+	}
+	matches := m.matchingUsers()
+	if m.userIndex >= len(matches) {
+		return matches[0], true
+	}
+	return matches[m.userIndex], true
+}
+
+// renderUserList renders the user picker, styled like renderSessionDropdown
+func (m model) renderUserList(width int) string {
+	maxListHeight := 6
+	matches := m.matchingUsers()
+	index := min(m.userIndex, len(matches)-1)
+
+	start := 0
+	end := len(matches)
+	if len(matches) > maxListHeight {
+		start = max(0, index-maxListHeight/2)
+		end = start + maxListHeight
+		if end > len(matches) {
+			end = len(matches)
+			start = end - maxListHeight
+		}
+	}
+
+	rows := make([]string, 0, end-start+2)
+	if start > 0 {
+		rows = append(rows, lipgloss.NewStyle().Foreground(FgMuted).Render("  ↑ more above"))
+	}
+	for i := start; i < end; i++ {
+		style := lipgloss.NewStyle().
+			Foreground(FgSecondary).
+			Background(BgBase).
+			Padding(0, 1)
+		if i == index {
+			style = style.Foreground(Primary).Bold(true)
+		}
+		rows = append(rows, style.Render(matches[i].DisplayName()))
+	}
+	if end < len(matches) {
+		rows = append(rows, lipgloss.NewStyle().Foreground(FgMuted).Render("  ↓ more below"))
+	}
+
+	return lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(BorderFocus).
+		Background(BgBase).
+		Padding(0, 1).
+		MarginLeft(11). // "Username:" label is 10 chars wide + 1 space
+		MaxWidth(width).
+		Render(lipgloss.JoinVertical(lipgloss.Left, rows...))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+	"github.com/Nomadcxx/sysc-greet/internal/users"
+	tea "github.com/charmbracelet/bubbletea/v2"
+)
+
+func TestUserPicker(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	m := newTestModel(t, srv)
+	m.config.UserList = true
+	m.users = []users.User{
+		{Name: "alice", RealName: "Alice Liddell"},
+		{Name: "bert", RealName: "Bert"},
+		{Name: "bob"},
+	}
+
+	if !m.userPickerActive() {
+		t.Fatal("expected the user picker to be shown on the login form")
+	}
+
+	// Typing filters, ↓ moves to the second match
+	m, _ = update(t, m, tea.KeyPressMsg{Code: 'b', Text: "b"})
+	if got := len(m.matchingUsers()); got != 2 {
+		t.Fatalf("expected 2 matches for %q, got %d", m.usernameInput.Value(), got)
+	}
+	m, _ = update(t, m, tea.KeyPressMsg{Code: tea.KeyDown})
+	m, _ = update(t, m, tea.KeyPressMsg{Code: tea.KeyDown})
+	if m.userIndex != 1 {
+		t.Fatalf("expected the highlight to stop at the last match, got %d", m.userIndex)
+	}
+
+	m, _ = update(t, m, keyEnter)
+	if m.mode != ModePassword {
+		t.Fatalf("expected password mode after picking a user, got %s", m.mode)
+	}
+	if m.usernameInput.Value() != "bob" {
+		t.Fatalf("expected bob to be picked, got %q", m.usernameInput.Value())
+	}
+	if m.userPickerActive() {
+		t.Fatal("the picker must not be shown in password mode")
+	}
+}
+
+func TestUserPickerKeepsTypedName(t *testing.T) {
+	tests := []struct {
+		name  string
+		typed string
+		keys  []tea.KeyPressMsg
+		want  string
+	}{
+		{name: "prefix of a listed user", typed: "bob", want: "bob"},
+		{name: "real name", typed: "Rob", want: "Rob"},
+		{name: "moved highlight", typed: "bob", keys: []tea.KeyPressMsg{{Code: tea.KeyDown}}, want: "bobby"},
+		{name: "nothing typed", want: "bobby"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, greetdtest.NewServer(t))
+			m.config.UserList = true
+			m.users = []users.User{{Name: "bobby", RealName: "Robert"}}
+
+			for _, r := range tt.typed {
+				m, _ = update(t, m, tea.KeyPressMsg{Code: r, Text: string(r)})
+			}
+			for _, k := range tt.keys {
+				m, _ = update(t, m, k)
+			}
+			m, _ = update(t, m, keyEnter)
+			if m.usernameInput.Value() != tt.want {
+				t.Fatalf("expected to log in as %q, got %q", tt.want, m.usernameInput.Value())
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/Nomadcxx/sysc-greet/internal/cache"
@@ -13,32 +14,40 @@ import (
 
 // Created wallpaper.go for wallpaper/gslapper handling
 
-// navigateToWallpaperSubmenu scans wallpapers directory and builds menu
-func (m model) navigateToWallpaperSubmenu() (tea.Model, tea.Cmd) {
+// wallpaperDirs replaces the default wallpaper directories ([paths] wallpaper_dirs)
+var wallpaperDirs []string
+
+// wallpaperDirectories returns the directories searched for wallpapers, in order
+func wallpaperDirectories() []string {
+	if len(wallpaperDirs) > 0 {
+		return wallpaperDirs
+	}
 	// CHANGED 2025-10-06 - Use /var/lib/greeter/Pictures/wallpapers for greeter user - Problem: $HOME is greeter's home in production
 	// Try greeter's wallpaper directory first (for production), then fallback to user's home (for testing)
-	wallpaperDirs := []string{
+	return []string{
 		"/var/lib/greeter/Pictures/wallpapers",
 		filepath.Join(os.Getenv("HOME"), "Pictures", "wallpapers"),
 	}
+}
+
+// isWallpaperFile reports whether name has a video or static image extension
+func isWallpaperFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	validExts := []string{".mp4", ".mkv", ".webm", ".avi", ".mov", ".png", ".jpg", ".jpeg", ".webp", ".gif"}
+	return slices.Contains(validExts, ext)
+}
 
+// navigateToWallpaperSubmenu scans wallpapers directory and builds menu
+func (m model) navigateToWallpaperSubmenu() (tea.Model, tea.Cmd) {
 	m.menuOptions = []string{"← Back", "Stop Video Wallpaper"}
 
 	// Try each directory until we find one that exists
-	for _, wallpaperDir := range wallpaperDirs {
+	for _, wallpaperDir := range wallpaperDirectories() {
 		files, err := os.ReadDir(wallpaperDir)
 		if err == nil {
 			for _, file := range files {
-				if !file.IsDir() {
-					// Check for video and static image extensions
-					ext := strings.ToLower(filepath.Ext(file.Name()))
-					validExts := []string{".mp4", ".mkv", ".webm", ".avi", ".mov", ".png", ".jpg", ".jpeg", ".webp", ".gif"}
-					for _, validExt := range validExts {
-						if ext == validExt {
-							m.menuOptions = append(m.menuOptions, file.Name())
-							break
-						}
-					}
+				if !file.IsDir() && isWallpaperFile(file.Name()) {
+					m.menuOptions = append(m.menuOptions, file.Name())
 				}
 			}
 			// If we found wallpaper files (beyond the 2 default menu items), break
@@ -63,9 +72,9 @@ func stopGslapper() {
 // launchGslapperWallpaper changes wallpaper via gSlapper IPC, falling back to process restart if needed
 func launchGslapperWallpaper(wallpaperFilename string) {
 	// CHANGED 2025-12-24 - Use IPC for wallpaper changes (no flicker), fallback to restart
-	wallpaperPaths := []string{
-		filepath.Join("/var/lib/greeter/Pictures/wallpapers", wallpaperFilename),
-		filepath.Join(os.Getenv("HOME"), "Pictures", "wallpapers", wallpaperFilename),
+	var wallpaperPaths []string
+	for _, dir := range wallpaperDirectories() {
+		wallpaperPaths = append(wallpaperPaths, filepath.Join(dir, wallpaperFilename))
 	}
 
 	// Find the first existing wallpaper path
@@ -85,9 +94,11 @@ func launchGslapperWallpaper(wallpaperFilename string) {
 	go func() {
 		// Try IPC first (preferred - no flicker)
 		if wallpaper.IsGSlapperRunning() {
-			if err := wallpaper.ChangeWallpaper(wallpaperPath); err == nil {
+			err := wallpaper.ChangeWallpaper(wallpaperPath)
+			if err == nil {
 				return // Success via IPC
 			}
+			wallpaperLog.Warn("gSlapper IPC failed, restarting gslapper", "path", wallpaperPath, "err", err)
 		}
 
 		// Fallback: kill and restart gslapper with IPC socket
@@ -104,7 +115,9 @@ func launchGslapperWallpaper(wallpaperFilename string) {
 			// Static image: fork to background (fill is default for images)
 			cmd = exec.Command("gslapper", "-f", "-I", wallpaper.GSlapperSocket, "*", wallpaperPath)
 		}
-		cmd.Start()
+		if err := cmd.Start(); err != nil {
+			wallpaperLog.Warn("Failed to start gslapper", "path", wallpaperPath, "err", err)
+		}
 	}()
 }
 
@@ -115,6 +128,7 @@ func (m model) handleWallpaperSelection(selectedOption string) (tea.Model, tea.C
 		if wallpaper.IsGSlapperRunning() {
 			if err := wallpaper.PauseVideo(); err != nil {
 				// IPC pause failed, fall back to stopping gslapper
+				wallpaperLog.Warn("gSlapper pause failed, stopping gslapper", "err", err)
 				stopGslapper()
 			}
 		} else {
@@ -124,19 +138,7 @@ func (m model) handleWallpaperSelection(selectedOption string) (tea.Model, tea.C
 		m.gslapperLaunched = false
 
 		// Save cleared preference to cache
-		if !m.config.TestMode {
-			sessionName := ""
-			if m.selectedSession != nil {
-				sessionName = m.selectedSession.Name
-			}
-			cache.SavePreferences(cache.UserPreferences{
-				Theme:       m.currentTheme,
-				Background:  m.selectedBackground,
-				Wallpaper:   m.selectedWallpaper, // Now empty
-				BorderStyle: m.selectedBorderStyle,
-				Session:     sessionName,
-			})
-		}
+		m.savePreferences("", func(p *cache.UserPreferences) { p.Wallpaper = m.selectedWallpaper })
 	} else if selectedOption != "← Back" {
 		// Launch gslapper with selected wallpaper
 		launchGslapperWallpaper(selectedOption)
@@ -146,19 +148,7 @@ func (m model) handleWallpaperSelection(selectedOption string) (tea.Model, tea.C
 		m.gslapperLaunched = true
 
 		// Save preference
-		if !m.config.TestMode {
-			sessionName := ""
-			if m.selectedSession != nil {
-				sessionName = m.selectedSession.Name
-			}
-			cache.SavePreferences(cache.UserPreferences{
-				Theme:       m.currentTheme,
-				Background:  m.selectedBackground,
-				Wallpaper:   m.selectedWallpaper,
-				BorderStyle: m.selectedBorderStyle,
-				Session:     sessionName,
-			})
-		}
+		m.savePreferences("", func(p *cache.UserPreferences) { p.Wallpaper = m.selectedWallpaper })
 	}
 
 	m.mode = ModeLogin
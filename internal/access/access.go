@@ -0,0 +1,245 @@
+// Package access decides who may log in through the greeter
+// The policy is checked before greetd is contacted, so rejected accounts (root,
+// service accounts, users outside a lab group) never reach PAM
+package access
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Nomadcxx/sysc-greet/internal/logging"
+	"github.com/Nomadcxx/sysc-greet/internal/users"
+)
+
+var logger = logging.For("access")
+
+// DefaultGroupPath is the group database used for group membership
+const DefaultGroupPath = "/etc/group"
+
+// Reasons a login is denied
+const (
+	ReasonDenied     = "deny"    // Listed in Deny
+	ReasonNotAllowed = "allow"   // Allow is set and the user is not in it
+	ReasonGroup      = "group"   // Not a member of any of Groups
+	ReasonUID        = "uid"     // UID below MinUID
+	ReasonUnknown    = "unknown" // Account not found, so UID and groups cannot be checked
+)
+
+// Policy restricts who may log in
+// Empty fields impose no restriction; all set restrictions must pass
+type Policy struct {
+	Allow  []string // Only these users may log in
+	Deny   []string // These users may never log in (checked first)
+	Groups []string // Users must be a member of at least one of these groups
+	MinUID int      // UIDs below this are rejected (e.g. 1000 keeps root and service accounts out)
+
+	PasswdPath string // Defaults to /etc/passwd
+	GroupPath  string // Defaults to /etc/group
+}
+
+// Denial is the error returned for a rejected login
+type Denial struct {
+	User   string
+	Reason string // One of the Reason constants
+}
+
+func (d Denial) Error() string {
+	switch d.Reason {
+	case ReasonGroup:
+		return fmt.Sprintf("%s is not in a group allowed to log in here", d.User)
+	case ReasonUID:
+		return "System accounts cannot log in here"
+	}
+	return fmt.Sprintf("%s is not allowed to log in here", d.User)
+}
+
+// Empty reports whether the policy lets everyone in
+func (p Policy) Empty() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0 && len(p.Groups) == 0 && p.MinUID <= 0
+}
+
+// Check returns a Denial if username may not log in
+// Other errors mean the account databases could not be read; the login should
+// then be refused too, since the policy cannot be enforced
+func (p Policy) Check(username string) error {
+	return p.check(username, sync.OnceValues(p.readPasswd), sync.OnceValues(p.readGroups))
+}
+
+// check is Check with the account databases read by passwd and groups
+func (p Policy) check(username string, passwd func() ([]users.User, error), groups func() ([]Group, error)) error {
+	if contains(p.Deny, username) {
+		return Denial{User: username, Reason: ReasonDenied}
+	}
+	if len(p.Allow) > 0 && !contains(p.Allow, username) {
+		return Denial{User: username, Reason: ReasonNotAllowed}
+	}
+	if len(p.Groups) == 0 && p.MinUID <= 0 {
+		return nil
+	}
+
+	all, err := passwd()
+	if err != nil {
+		return err
+	}
+	account, found := p.lookupUser(all, username)
+	if !found {
+		return Denial{User: username, Reason: ReasonUnknown}
+	}
+	if p.MinUID > 0 && account.UID < p.MinUID {
+		return Denial{User: username, Reason: ReasonUID}
+	}
+	if len(p.Groups) > 0 {
+		all, err := groups()
+		if err != nil {
+			return err
+		}
+		if !p.inGroups(all, username, account.GID) {
+			return Denial{User: username, Reason: ReasonGroup}
+		}
+	}
+	return nil
+}
+
+// Filter returns the users the policy lets in
+// The account databases are read once for the whole list
+func (p Policy) Filter(list []users.User) []users.User {
+	if p.Empty() {
+		return list
+	}
+	passwd, groups := sync.OnceValues(p.readPasswd), sync.OnceValues(p.readGroups)
+	var allowed []users.User
+	for _, u := range list {
+		if p.check(u.Name, passwd, groups) == nil {
+			allowed = append(allowed, u)
+		}
+	}
+	return allowed
+}
+
+// readPasswd reads the passwd file
+// Malformed lines are skipped with a warning, like ParseGroup does, so one bad
+// entry does not lock every account out
+func (p Policy) readPasswd() ([]users.User, error) {
+	path := p.PasswdPath
+	if path == "" {
+		path = users.DefaultPasswdPath
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	all, err := users.ParsePasswdLenient(f, func(err error) {
+		logger.Warn("Skipping malformed passwd entry", "path", path, "err", err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return all, nil
+}
+
+// lookupUser finds username in the passwd entries, falling back to the system's
+// user lookup (NSS when built with cgo) for accounts from a directory service
+func (p Policy) lookupUser(all []users.User, username string) (users.User, bool) {
+	for _, u := range all {
+		if u.Name == username {
+			return u, true
+		}
+	}
+
+	if p.PasswdPath == "" {
+		if u, err := user.Lookup(username); err == nil {
+			uid, uidErr := strconv.Atoi(u.Uid)
+			gid, gidErr := strconv.Atoi(u.Gid)
+			if uidErr == nil && gidErr == nil {
+				return users.User{Name: username, UID: uid, GID: gid}, true
+			}
+		}
+	}
+	return users.User{}, false
+}
+
+// Group is a group(5) entry
+type Group struct {
+	Name    string
+	GID     int
+	Members []string
+}
+
+// ParseGroup parses group(5) lines: name:password:GID:member,member
+// Comments, blank lines, NIS entries and malformed lines are skipped
+func ParseGroup(r io.Reader) ([]Group, error) {
+	var groups []Group
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) != 4 {
+			continue
+		}
+		gid, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		g := Group{Name: fields[0], GID: gid}
+		for _, member := range strings.Split(fields[3], ",") {
+			if member = strings.TrimSpace(member); member != "" {
+				g.Members = append(g.Members, member)
+			}
+		}
+		groups = append(groups, g)
+	}
+	return groups, scanner.Err()
+}
+
+// readGroups reads the group file
+func (p Policy) readGroups() ([]Group, error) {
+	path := p.GroupPath
+	if path == "" {
+		path = DefaultGroupPath
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	groups, err := ParseGroup(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return groups, nil
+}
+
+// inGroups reports whether username belongs to one of p.Groups, either as a
+// listed member or through its primary group
+func (p Policy) inGroups(groups []Group, username string, primaryGID int) bool {
+	for _, g := range groups {
+		if !contains(p.Groups, g.Name) {
+			continue
+		}
+		if g.GID == primaryGID || contains(g.Members, username) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, name string) bool {
+	for _, s := range list {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package access
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Nomadcxx/sysc-greet/internal/users"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		user   string
+		reason string // Empty when the login is allowed
+	}{
+		{"empty policy", Policy{}, "root", ""},
+		{"denied", Policy{Deny: []string{"guest"}}, "guest", ReasonDenied},
+		{"not denied", Policy{Deny: []string{"guest"}}, "alice", ""},
+		{"deny beats allow", Policy{Allow: []string{"guest"}, Deny: []string{"guest"}}, "guest", ReasonDenied},
+		{"allowed", Policy{Allow: []string{"alice", "bob"}}, "bob", ""},
+		{"not allowed", Policy{Allow: []string{"alice", "bob"}}, "carol", ReasonNotAllowed},
+		{"root below min uid", Policy{MinUID: 1000}, "root", ReasonUID},
+		{"service below min uid", Policy{MinUID: 1000}, "svc", ReasonUID},
+		{"at min uid", Policy{MinUID: 1000}, "alice", ""},
+		{"unknown with min uid", Policy{MinUID: 1000}, "mallory", ReasonUnknown},
+		{"listed member", Policy{Groups: []string{"wheel"}}, "alice", ""},
+		{"member with spaces", Policy{Groups: []string{"lab"}}, "guest", ""},
+		{"primary group", Policy{Groups: []string{"users"}}, "carol", ""},
+		{"any of groups", Policy{Groups: []string{"wheel", "lab"}}, "bob", ""},
+		{"not a member", Policy{Groups: []string{"wheel"}}, "bob", ReasonGroup},
+		{"all rules apply", Policy{Allow: []string{"bob", "carol"}, Groups: []string{"lab"}, MinUID: 1000}, "carol", ReasonGroup},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.policy.PasswdPath = "testdata/passwd"
+			tt.policy.GroupPath = "testdata/group"
+			err := tt.policy.Check(tt.user)
+
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("expected %s to be allowed, got %v", tt.user, err)
+				}
+				return
+			}
+			var denial Denial
+			if !errors.As(err, &denial) {
+				t.Fatalf("expected a Denial, got %v", err)
+			}
+			if denial.Reason != tt.reason || denial.User != tt.user {
+				t.Fatalf("expected %s denied for %q, got %+v", tt.user, tt.reason, denial)
+			}
+		})
+	}
+}
+
+func TestDenialMessages(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   string
+	}{
+		{ReasonDenied, "guest is not allowed to log in here"},
+		{ReasonNotAllowed, "guest is not allowed to log in here"},
+		{ReasonUnknown, "guest is not allowed to log in here"},
+		{ReasonGroup, "guest is not in a group allowed to log in here"},
+		{ReasonUID, "System accounts cannot log in here"},
+	}
+	for _, tt := range tests {
+		if got := (Denial{User: "guest", Reason: tt.reason}).Error(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.reason, got, tt.want)
+		}
+	}
+}
+
+func TestUnreadableDatabase(t *testing.T) {
+	policy := Policy{MinUID: 1000, PasswdPath: "testdata/missing"}
+	err := policy.Check("alice")
+	var denial Denial
+	if err == nil || errors.As(err, &denial) {
+		t.Fatalf("expected a read error, got %v", err)
+	}
+
+	policy = Policy{Groups: []string{"wheel"}, PasswdPath: "testdata/passwd", GroupPath: "testdata/missing"}
+	if err := policy.Check("alice"); err == nil {
+		t.Fatal("expected a read error for the group file")
+	}
+}
+
+func TestMalformedPasswdLine(t *testing.T) {
+	passwd := filepath.Join(t.TempDir(), "passwd")
+	os.WriteFile(passwd, []byte("root:x:0:0:root:/root:/bin/bash\nbroken:x:1001\nalice:x:1000:1000:Alice:/home/alice:/bin/bash\n"), 0644)
+
+	policy := Policy{MinUID: 1000, PasswdPath: passwd}
+	if err := policy.Check("alice"); err != nil {
+		t.Fatalf("expected the malformed line to be skipped, got %v", err)
+	}
+	var denial Denial
+	if err := policy.Check("root"); !errors.As(err, &denial) || denial.Reason != ReasonUID {
+		t.Fatalf("expected root to still be refused, got %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	list := []users.User{{Name: "alice"}, {Name: "bob"}, {Name: "guest"}}
+	policy := Policy{Deny: []string{"guest"}, Groups: []string{"wheel", "lab"}, PasswdPath: "testdata/passwd", GroupPath: "testdata/group"}
+
+	var got []string
+	for _, u := range policy.Filter(list) {
+		got = append(got, u.Name)
+	}
+	if want := []string{"alice", "bob"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Filter() = %v, want %v", got, want)
+	}
+}
+
+func TestParseGroup(t *testing.T) {
+	groups, err := ParseGroup(strings.NewReader("wheel:x:10:alice,bob\n# comment\n+nis\nempty:x:11:\nbad:x:gid:\n"))
+	if err != nil {
+		t.Fatalf("ParseGroup: %v", err)
+	}
+	want := []Group{
+		{Name: "wheel", GID: 10, Members: []string{"alice", "bob"}},
+		{Name: "empty", GID: 11},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Fatalf("ParseGroup() = %+v, want %+v", groups, want)
+	}
+}
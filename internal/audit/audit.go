@@ -0,0 +1,277 @@
+// Package audit keeps an append-only record of login activity at the greeter
+// Each event is one JSON line; events can also be forwarded to the local
+// syslog daemon (authpriv facility) over /dev/log
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultPath is where the audit log is written unless configured otherwise
+const DefaultPath = "/var/log/sysc-greet/audit.log"
+
+// SyslogSocket is the local syslog socket
+const SyslogSocket = "/dev/log"
+
+// Event kinds
+const (
+	Attempt = "attempt" // A login was submitted to greetd
+	Success = "success" // Authentication succeeded and the session was started
+	Failure = "failure" // Authentication or session start failed
+	Cancel  = "cancel"  // The conversation was abandoned (Esc, idle reset) before it finished
+)
+
+// Event is one audit record
+type Event struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`                // Attempt, Success, Failure or Cancel
+	User      string    `json:"user"`                 // Attempted username, or its hash
+	Session   string    `json:"session,omitempty"`    // Chosen session
+	ErrorType string    `json:"error_type,omitempty"` // greetd error type (auth_error, error)
+	Error     string    `json:"error,omitempty"`      // Error description
+}
+
+// Options configures Open
+type Options struct {
+	Path          string // Audit log file (default DefaultPath)
+	HashUsernames bool   // Record sha256 hashes instead of usernames
+	Syslog        bool   // Also forward events to SyslogSocket
+	SyslogSocket  string // Overrides SyslogSocket (tests)
+}
+
+// Logger appends events to the audit log
+// A nil *Logger records nothing, so callers need no checks when auditing is off
+type Logger struct {
+	opts Options
+
+	mu     sync.Mutex
+	file   *os.File
+	syslog net.Conn
+}
+
+// Open opens (creating 0600 if needed) the audit log described by opts
+func Open(opts Options) (*Logger, error) {
+	if opts.Path == "" {
+		opts.Path = DefaultPath
+	}
+	if opts.SyslogSocket == "" {
+		opts.SyslogSocket = SyslogSocket
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %v", err)
+	}
+	file, err := os.OpenFile(opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND|syscall.O_NOFOLLOW, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+
+	l := &Logger{opts: opts, file: file}
+	if opts.Syslog {
+		conn, err := net.Dial("unixgram", opts.SyslogSocket)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to connect to syslog: %v", err)
+		}
+		l.syslog = conn
+	}
+	return l, nil
+}
+
+// HashUsername returns the hash recorded instead of a username
+func HashUsername(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Record appends e, filling in the time and hashing the username if configured
+func (l *Logger) Record(e Event) error {
+	if l == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if l.opts.HashUsernames {
+		e.User = HashUsername(e.User)
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %v", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// One write per line keeps lines whole with O_APPEND
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit event: %v", err)
+	}
+	if l.syslog != nil {
+		if _, err := l.syslog.Write(syslogMessage(e, line)); err != nil {
+			return fmt.Errorf("failed to forward audit event to syslog: %v", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the audit log
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.syslog != nil {
+		l.syslog.Close()
+	}
+	return l.file.Close()
+}
+
+// syslogMessage formats an RFC 3164 message for the authpriv facility
+func syslogMessage(e Event, line []byte) []byte {
+	const authpriv = 10
+	severity := 6 // info
+	switch e.Event {
+	case Success:
+		severity = 5 // notice
+	case Failure:
+		severity = 4 // warning
+	}
+	return fmt.Appendf(nil, "<%d>%s sysc-greet[%d]: %s", authpriv*8+severity, e.Time.Format(time.Stamp), os.Getpid(), line)
+}
+
+// Read decodes the events in r, skipping lines that are not valid events
+func Read(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// Tail returns the last n events of the audit log at path (all of them if n <= 0)
+func Tail(path string, n int) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	events, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	if n > 0 && len(events) > n {
+		events = events[len(events)-n:]
+	}
+	return events, nil
+}
+
+// Follower reads the events appended to an audit log since it last looked
+// The log is only ever appended to, so only new bytes are read; when it is
+// rotated (a new file at the path) or truncated, reading starts over
+type Follower struct {
+	path    string
+	file    *os.File
+	offset  int64  // Bytes of file read so far
+	partial []byte // Start of a line whose newline has not been written yet
+}
+
+// Follow opens the audit log at path; the first Next returns every event in it
+func Follow(path string) (*Follower, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Follower{path: path, file: file}, nil
+}
+
+// Next returns the events appended since the last call
+func (f *Follower) Next() ([]Event, error) {
+	// Whatever was appended to the current file comes before a rotation
+	events, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := f.file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	info, err := os.Stat(f.path)
+	switch {
+	case err != nil:
+		// Between moving the old log away and creating the new one
+		return events, nil
+	case !os.SameFile(current, info):
+		file, err := os.Open(f.path)
+		if err != nil {
+			return events, nil
+		}
+		f.file.Close()
+		f.file, f.offset, f.partial = file, 0, nil
+	case info.Size() < f.offset:
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %v", err)
+		}
+		f.offset, f.partial = 0, nil
+	default:
+		return events, nil
+	}
+
+	more, err := f.read()
+	return append(events, more...), err
+}
+
+// read decodes the complete lines appended to the current file
+func (f *Follower) read() ([]Event, error) {
+	data, err := io.ReadAll(f.file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	f.offset += int64(len(data))
+	data = append(f.partial, data...)
+
+	end := bytes.LastIndexByte(data, '\n') + 1
+	f.partial = append([]byte(nil), data[end:]...)
+	return Read(bytes.NewReader(data[:end]))
+}
+
+// Close closes the audit log
+func (f *Follower) Close() error {
+	return f.file.Close()
+}
+
+// String formats e for display
+func (e Event) String() string {
+	s := fmt.Sprintf("%s  %-7s  %s", e.Time.Local().Format("2006-01-02 15:04:05"), e.Event, e.User)
+	if e.Session != "" {
+		s += "  session=" + e.Session
+	}
+	if e.ErrorType != "" {
+		s += "  error_type=" + e.ErrorType
+	}
+	if e.Error != "" {
+		s += fmt.Sprintf("  error=%q", e.Error)
+	}
+	return s
+}
@@ -0,0 +1,224 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordAppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+	for round := 0; round < 2; round++ {
+		// Reopening must append, not truncate
+		l, err := Open(Options{Path: path})
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		if err := l.Record(Event{Event: Attempt, User: "alice", Session: "Sway"}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+		if err := l.Record(Event{Event: Failure, User: "alice", Session: "Sway", ErrorType: "auth_error", Error: "Authentication failure"}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+		l.Close()
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("expected mode 0600, got %o", perm)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d:\n%s", len(lines), data)
+	}
+	for _, line := range lines {
+		var raw map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		if _, ok := raw["time"]; !ok {
+			t.Fatalf("missing time in %q", line)
+		}
+	}
+
+	events, err := Tail(path, 1)
+	if err != nil {
+		t.Fatalf("Tail: %v", err)
+	}
+	want := Event{Event: Failure, User: "alice", Session: "Sway", ErrorType: "auth_error", Error: "Authentication failure"}
+	got := events[0]
+	got.Time = time.Time{}
+	if len(events) != 1 || got != want {
+		t.Fatalf("unexpected tail: %+v", events)
+	}
+}
+
+func TestHashUsernames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(Options{Path: path, HashUsernames: true})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer l.Close()
+	l.Record(Event{Event: Attempt, User: "alice"})
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "alice") {
+		t.Fatalf("username written in clear: %s", data)
+	}
+	events, _ := Tail(path, 0)
+	if len(events) != 1 || events[0].User != HashUsername("alice") {
+		t.Fatalf("expected hashed username, got %+v", events)
+	}
+	if HashUsername("alice") == HashUsername("bob") {
+		t.Fatal("expected different hashes for different names")
+	}
+}
+
+func TestRefusesSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	os.WriteFile(target, nil, 0600)
+	link := filepath.Join(dir, "audit.log")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	if _, err := Open(Options{Path: link}); err == nil {
+		t.Fatal("expected Open to refuse a symlink")
+	}
+}
+
+func TestSyslogForwarding(t *testing.T) {
+	dir := t.TempDir()
+	sock := filepath.Join(dir, "log")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+
+	l, err := Open(Options{Path: filepath.Join(dir, "audit.log"), Syslog: true, SyslogSocket: sock})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer l.Close()
+
+	tests := []struct {
+		event string
+		pri   string
+	}{
+		{Attempt, "<86>"}, // authpriv.info
+		{Success, "<85>"}, // authpriv.notice
+		{Failure, "<84>"}, // authpriv.warning
+	}
+	buf := make([]byte, 4096)
+	for _, tt := range tests {
+		if err := l.Record(Event{Event: tt.event, User: "alice"}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		msg := buf[:n]
+		if !bytes.HasPrefix(msg, []byte(tt.pri)) {
+			t.Errorf("%s: expected priority %s, got %q", tt.event, tt.pri, msg)
+		}
+		if !bytes.Contains(msg, []byte(" sysc-greet[")) || !bytes.Contains(msg, []byte(`"event":"`+tt.event+`"`)) {
+			t.Errorf("%s: unexpected message %q", tt.event, msg)
+		}
+	}
+}
+
+func TestReadSkipsInvalidLines(t *testing.T) {
+	input := `{"time":"2026-01-02T03:04:05Z","event":"attempt","user":"alice"}
+not json
+{"time":"2026-01-02T03:04:06Z","event":"success","user":"alice","session":"Sway"}
+`
+	events, err := Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(events) != 2 || events[1].Session != "Sway" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	var l *Logger
+	if err := l.Record(Event{Event: Attempt, User: "alice"}); err != nil {
+		t.Fatalf("expected nil logger to ignore events, got %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFollower(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	appendLine := func(line string) {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(line)
+		f.Close()
+	}
+	event := func(user string) string {
+		return `{"time":"2026-01-02T03:04:05Z","event":"attempt","user":"` + user + `"}` + "\n"
+	}
+
+	appendLine(event("alice"))
+	f, err := Follow(path)
+	if err != nil {
+		t.Fatalf("Follow: %v", err)
+	}
+	defer f.Close()
+
+	steps := []struct {
+		name   string
+		change func()
+		want   []string
+	}{
+		{"existing events", func() {}, []string{"alice"}},
+		{"nothing new", func() {}, nil},
+		{"appended", func() { appendLine(event("bob")) }, []string{"bob"}},
+		{"half a line", func() { appendLine(event("carol")[:20]) }, nil},
+		{"rest of the line", func() { appendLine(event("carol")[20:]) }, []string{"carol"}},
+		{"rotated", func() {
+			appendLine(event("dave"))
+			os.Rename(path, path+".1")
+			appendLine(event("erin"))
+		}, []string{"dave", "erin"}},
+		{"truncated", func() { os.Truncate(path, 0) }, nil},
+		{"after truncation", func() { appendLine(event("frank")) }, []string{"frank"}},
+	}
+	for _, step := range steps {
+		step.change()
+		events, err := f.Next()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		var users []string
+		for _, e := range events {
+			users = append(users, e.User)
+		}
+		if strings.Join(users, ",") != strings.Join(step.want, ",") {
+			t.Fatalf("%s: expected %v, got %v", step.name, step.want, users)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,27 +13,95 @@ import (
 const cacheDir = ".cache/sysc-greet"
 const sessionFile = "session"
 const preferencesFile = "preferences"
+const sessionStatsFile = "session_stats"
+const commandHistoryFile = "command_history"
+const sessionUserFile = "session_user"
 
-// SaveSelectedSession saves the selected session to cache
-func SaveSelectedSession(session sessions.Session) error {
+// MaxCommandHistory is the number of custom session commands remembered
+const MaxCommandHistory = 10
+
+// stateDir replaces ~/.cache/sysc-greet when set ([paths] state_dir)
+var stateDir string
+
+// SetDir makes the cache live in dir instead of ~/.cache/sysc-greet, for
+// systems where the greeter user's home is missing or read-only
+// An empty dir restores the default
+func SetDir(dir string) {
+	stateDir = dir
+}
+
+// Dir returns the directory the cache is kept in
+func Dir() (string, error) {
+	if stateDir != "" {
+		return stateDir, nil
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %v", err)
+		return "", fmt.Errorf("failed to get home directory: %v", err)
+	}
+	return filepath.Join(home, cacheDir), nil
+}
+
+// readFile reads the cache file name; a missing file yields nil data and no error
+func readFile(name string) ([]byte, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
 	}
+	return data, err
+}
 
-	cachePath := filepath.Join(home, cacheDir)
-	if err := os.MkdirAll(cachePath, 0755); err != nil {
+// writeFile replaces the cache file name with data
+// CHANGED 2026-10-16 - Write to a temporary file and rename it over the old one,
+// so a crash or full disk never leaves a truncated file. Everything in the cache
+// is private to the greeter user (0600 files in a 0700 directory)
+// CHANGED 2026-10-16 - A file written by a newer sysc-greet is left alone
+func writeFile(name string, data []byte) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	existing, _ := os.ReadFile(filepath.Join(dir, name))
+	if err := checkNotNewer(name, existing); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
 		return fmt.Errorf("failed to create cache directory: %v", err)
 	}
 
-	filePath := filepath.Join(cachePath, sessionFile)
-	data, err := json.Marshal(session)
+	tmp, err := os.CreateTemp(dir, "."+name+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+// SaveSelectedSession saves the selected session to cache
+func SaveSelectedSession(session sessions.Session) error {
+	data, err := encode(sessionFile, session)
 	if err != nil {
 		return fmt.Errorf("failed to marshal session: %v", err)
 	}
 
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write session file: %v", err)
+	if err := writeFile(sessionFile, data); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
 	}
 
 	return nil
@@ -40,24 +109,17 @@ func SaveSelectedSession(session sessions.Session) error {
 
 // LoadSelectedSession loads the selected session from cache
 func LoadSelectedSession() (*sessions.Session, error) {
-	home, err := os.UserHomeDir()
+	data, err := readFile(sessionFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %v", err)
+		return nil, fmt.Errorf("failed to read session file: %v", err)
 	}
-
-	filePath := filepath.Join(home, cacheDir, sessionFile)
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	if data == nil {
 		return nil, nil // No cached session
 	}
 
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read session file: %v", err)
-	}
-
 	var session sessions.Session
-	if err := json.Unmarshal(data, &session); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal session: %v", err)
+	if err := decode(sessionFile, data, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
 	}
 
 	return &session, nil
@@ -74,52 +136,318 @@ type UserPreferences struct {
 	ASCIIIndex  int    `json:"ascii_index"`  // Last selected ASCII variant index
 }
 
-// SavePreferences saves user preferences to cache
-func SavePreferences(prefs UserPreferences) error {
-	home, err := os.UserHomeDir()
+// preferencesStore is the on-disk layout of the preferences file
+// Defaults always holds the most recent choices, so the greeter looks the same
+// after a restart; Users keeps each person's own choices, keyed by username
+type preferencesStore struct {
+	LastUser string                     `json:"last_user,omitempty"`
+	Defaults UserPreferences            `json:"defaults"`
+	Users    map[string]UserPreferences `json:"users,omitempty"`
+}
+
+// migratePreferencesV0 moves the single, shared preferences of version 0 into
+// Defaults, and into Users for the user they were saved for
+func migratePreferencesV0(data json.RawMessage) (json.RawMessage, error) {
+	var legacy UserPreferences
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, err
+	}
+
+	var store preferencesStore
+	if legacy.Username != "" {
+		store.LastUser = legacy.Username
+		store.Users = map[string]UserPreferences{legacy.Username: legacy}
+	}
+	legacy.Username = ""
+	store.Defaults = legacy
+	return json.Marshal(store)
+}
+
+// UpdatePreferences changes the cached preferences with update, leaving the
+// fields it does not set as they were. update is applied to the most recent
+// preferences, and to username's own preferences when username is not empty
+// The last-user pointer is only moved by SaveLastUser
+func UpdatePreferences(username string, update func(*UserPreferences)) error {
+	return updatePreferencesStore(func(store *preferencesStore) {
+		update(&store.Defaults)
+		store.Defaults.Username = ""
+
+		if username == "" {
+			return
+		}
+		if store.Users == nil {
+			store.Users = make(map[string]UserPreferences)
+		}
+		prefs, ok := store.Users[username]
+		if !ok {
+			// A new user starts from what is on screen
+			prefs = store.Defaults
+		}
+		update(&prefs)
+		prefs.Username = username
+		store.Users[username] = prefs
+	})
+}
+
+// SaveLastUser records username as the user to pre-fill on the next start
+// An empty username clears the pointer
+func SaveLastUser(username string) error {
+	return updatePreferencesStore(func(store *preferencesStore) {
+		store.LastUser = username
+	})
+}
+
+// LoadPreferences loads the most recent preferences from cache
+// Username is set to the last user that logged in, if one was recorded
+func LoadPreferences() (*UserPreferences, error) {
+	store, err := loadPreferencesStore()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %v", err)
+		return nil, err
+	}
+	if store.Defaults == (UserPreferences{}) && store.LastUser == "" {
+		return nil, nil // No cached preferences
 	}
 
-	cachePath := filepath.Join(home, cacheDir)
-	if err := os.MkdirAll(cachePath, 0755); err != nil {
-		return fmt.Errorf("failed to create cache directory: %v", err)
+	prefs := store.Defaults
+	prefs.Username = store.LastUser
+	return &prefs, nil
+}
+
+// LoadUserPreferences loads the preferences saved for username
+// Returns nil if that user has none
+func LoadUserPreferences(username string) (*UserPreferences, error) {
+	store, err := loadPreferencesStore()
+	if err != nil {
+		return nil, err
+	}
+	prefs, ok := store.Users[username]
+	if !ok {
+		return nil, nil
+	}
+	return &prefs, nil
+}
+
+// updatePreferencesStore reads the preferences file, changes it with update
+// and writes it back
+func updatePreferencesStore(update func(*preferencesStore)) error {
+	store, err := loadPreferencesStore()
+	var newer *NewerVersionError
+	var syntax *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &newer):
+		return err
+	case errors.As(err, &syntax), errors.As(err, &typeErr):
+		// Never let a corrupt cache block saving fresh preferences
+		store = preferencesStore{}
+	case err != nil:
+		return err
 	}
 
-	filePath := filepath.Join(cachePath, preferencesFile)
-	data, err := json.Marshal(prefs)
+	update(&store)
+	data, err := encode(preferencesFile, store)
 	if err != nil {
 		return fmt.Errorf("failed to marshal preferences: %v", err)
 	}
+	if err := writeFile(preferencesFile, data); err != nil {
+		return fmt.Errorf("failed to write preferences file: %w", err)
+	}
+	return nil
+}
 
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write preferences file: %v", err)
+// loadPreferencesStore reads the preferences file, migrating older versions
+func loadPreferencesStore() (preferencesStore, error) {
+	data, err := readFile(preferencesFile)
+	if err != nil {
+		return preferencesStore{}, fmt.Errorf("failed to read preferences file: %w", err)
+	}
+	if data == nil {
+		return preferencesStore{}, nil // No cached preferences
+	}
+
+	var store preferencesStore
+	if err := decode(preferencesFile, data, &store); err != nil {
+		return preferencesStore{}, fmt.Errorf("failed to unmarshal preferences: %w", err)
+	}
+	return store, nil
+}
+
+// SessionStats holds how often each session was started and which are starred
+// Sessions are identified by sessions.Session.Key
+type SessionStats struct {
+	Usage     map[string]int `json:"usage"`     // Successful starts per session
+	Favorites []string       `json:"favorites"` // Starred sessions, kept on top of the list
+}
+
+// IsFavorite reports whether the session with key is starred
+func (s SessionStats) IsFavorite(key string) bool {
+	for _, fav := range s.Favorites {
+		if fav == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ToggleFavorite stars or unstars the session with key
+func (s *SessionStats) ToggleFavorite(key string) {
+	for i, fav := range s.Favorites {
+		if fav == key {
+			s.Favorites = append(s.Favorites[:i:i], s.Favorites[i+1:]...)
+			return
+		}
+	}
+	s.Favorites = append(s.Favorites, key)
+}
+
+// RecordUse counts a successful start of the session with key
+func (s *SessionStats) RecordUse(key string) {
+	if s.Usage == nil {
+		s.Usage = make(map[string]int)
+	}
+	s.Usage[key]++
+}
+
+// SaveSessionStats saves session usage and favorites to cache
+func SaveSessionStats(stats SessionStats) error {
+	data, err := encode(sessionStatsFile, stats)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session stats: %v", err)
+	}
+
+	if err := writeFile(sessionStatsFile, data); err != nil {
+		return fmt.Errorf("failed to write session stats file: %w", err)
 	}
 
 	return nil
 }
 
-// LoadPreferences loads user preferences from cache
-func LoadPreferences() (*UserPreferences, error) {
-	home, err := os.UserHomeDir()
+// LoadSessionStats loads session usage and favorites from cache
+// A missing file yields empty stats
+func LoadSessionStats() (SessionStats, error) {
+	data, err := readFile(sessionStatsFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %v", err)
+		return SessionStats{}, fmt.Errorf("failed to read session stats file: %v", err)
+	}
+	if data == nil {
+		return SessionStats{}, nil
 	}
 
-	filePath := filepath.Join(home, cacheDir, preferencesFile)
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, nil // No cached preferences
+	var stats SessionStats
+	if err := decode(sessionStatsFile, data, &stats); err != nil {
+		return SessionStats{}, fmt.Errorf("failed to unmarshal session stats: %w", err)
+	}
+	return stats, nil
+}
+
+// AddCommand puts command at the front of history, dropping an older copy and
+// anything past MaxCommandHistory
+func AddCommand(history []string, command string) []string {
+	updated := []string{command}
+	for _, c := range history {
+		if c != command && len(updated) < MaxCommandHistory {
+			updated = append(updated, c)
+		}
 	}
+	return updated
+}
+
+// commandHistoryStore is the on-disk layout of the command history file
+// CHANGED 2026-10-16 - Each user has their own history
+type commandHistoryStore struct {
+	Users map[string][]string `json:"users"`
+}
 
-	data, err := os.ReadFile(filePath)
+// migrateCommandHistoryV0 drops the single list shared by everyone of version
+// 0, since nobody knows whose commands are in it
+func migrateCommandHistoryV0(json.RawMessage) (json.RawMessage, error) {
+	return json.Marshal(commandHistoryStore{})
+}
+
+// loadCommandHistoryStore reads the command history file
+func loadCommandHistoryStore() (commandHistoryStore, error) {
+	data, err := readFile(commandHistoryFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read preferences file: %v", err)
+		return commandHistoryStore{}, fmt.Errorf("failed to read command history file: %v", err)
+	}
+	if data == nil {
+		return commandHistoryStore{}, nil
+	}
+
+	var store commandHistoryStore
+	if err := decode(commandHistoryFile, data, &store); err != nil {
+		return commandHistoryStore{}, fmt.Errorf("failed to unmarshal command history: %w", err)
 	}
+	return store, nil
+}
 
-	var prefs UserPreferences
-	if err := json.Unmarshal(data, &prefs); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal preferences: %v", err)
+// SaveCommandHistory saves username's recent custom session commands, most recent first
+func SaveCommandHistory(username string, history []string) error {
+	store, err := loadCommandHistoryStore()
+	var newer *NewerVersionError
+	if errors.As(err, &newer) {
+		return err
+	} else if err != nil {
+		// Never let a corrupt file block saving
+		store = commandHistoryStore{}
 	}
+	if store.Users == nil {
+		store.Users = make(map[string][]string)
+	}
+	store.Users[username] = history
 
-	return &prefs, nil
+	data, err := encode(commandHistoryFile, store)
+	if err != nil {
+		return fmt.Errorf("failed to marshal command history: %v", err)
+	}
+	if err := writeFile(commandHistoryFile, data); err != nil {
+		return fmt.Errorf("failed to write command history file: %w", err)
+	}
+	return nil
+}
+
+// LoadCommandHistory loads username's recent custom session commands, most recent first
+// A missing file or an unknown user yields an empty history
+func LoadCommandHistory(username string) ([]string, error) {
+	store, err := loadCommandHistoryStore()
+	if err != nil {
+		return nil, err
+	}
+	history := store.Users[username]
+	if len(history) > MaxCommandHistory {
+		history = history[:MaxCommandHistory]
+	}
+	return history, nil
+}
+
+// SaveSessionUser records the account the greeter started a session for
+// Session reports ([crash_report]) are only believed when written by it
+func SaveSessionUser(username string) error {
+	data, err := encode(sessionUserFile, map[string]string{"user": username})
+	if err != nil {
+		return fmt.Errorf("failed to marshal session user: %v", err)
+	}
+	if err := writeFile(sessionUserFile, data); err != nil {
+		return fmt.Errorf("failed to write session user file: %w", err)
+	}
+	return nil
+}
+
+// LoadSessionUser returns the account of the last session the greeter started,
+// or "" if none was recorded
+func LoadSessionUser() (string, error) {
+	data, err := readFile(sessionUserFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read session user file: %v", err)
+	}
+	if data == nil {
+		return "", nil
+	}
+	var v struct {
+		User string `json:"user"`
+	}
+	if err := decode(sessionUserFile, data, &v); err != nil {
+		return "", fmt.Errorf("failed to unmarshal session user: %w", err)
+	}
+	return v.User, nil
 }
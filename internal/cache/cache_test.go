@@ -0,0 +1,269 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Nomadcxx/sysc-greet/internal/sessions"
+)
+
+// useDir points the cache at a temporary directory for one test
+func useDir(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "state")
+	SetDir(dir)
+	t.Cleanup(func() { SetDir("") })
+	return dir
+}
+
+func TestUpdatePreferencesKeepsOtherFields(t *testing.T) {
+	useDir(t)
+
+	full := UserPreferences{Theme: "nord", Background: "fire", Wallpaper: "beach.mp4", BorderStyle: "wave", Session: "Sway", ASCIIIndex: 3}
+	if err := UpdatePreferences("alice", func(p *UserPreferences) { *p = full }); err != nil {
+		t.Fatalf("UpdatePreferences: %v", err)
+	}
+	if err := UpdatePreferences("", func(p *UserPreferences) { p.Wallpaper = "" }); err != nil {
+		t.Fatalf("UpdatePreferences: %v", err)
+	}
+
+	prefs, err := LoadPreferences()
+	if err != nil || prefs == nil {
+		t.Fatalf("LoadPreferences: %+v, %v", prefs, err)
+	}
+	want := full
+	want.Wallpaper = ""
+	if *prefs != want {
+		t.Fatalf("expected only the wallpaper to change, got %+v", *prefs)
+	}
+	alice, _ := LoadUserPreferences("alice")
+	if alice == nil || alice.Wallpaper != "beach.mp4" || alice.Username != "alice" {
+		t.Fatalf("expected alice's own preferences to be untouched, got %+v", alice)
+	}
+
+	// A new user starts from the most recent preferences
+	if err := UpdatePreferences("bob", func(p *UserPreferences) { p.Theme = "gruvbox" }); err != nil {
+		t.Fatalf("UpdatePreferences: %v", err)
+	}
+	bob, _ := LoadUserPreferences("bob")
+	if bob == nil || bob.Theme != "gruvbox" || bob.Session != "Sway" || bob.ASCIIIndex != 3 {
+		t.Fatalf("unexpected preferences for bob: %+v", bob)
+	}
+}
+
+func TestLoadPreferencesMigrations(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		lastUser string
+		defaults UserPreferences
+		users    map[string]UserPreferences
+	}{
+		{
+			name:     "version 0",
+			file:     `{"theme":"nord","session":"Sway","username":"alice","ascii_index":2}`,
+			lastUser: "alice",
+			defaults: UserPreferences{Theme: "nord", Session: "Sway", ASCIIIndex: 2},
+			users:    map[string]UserPreferences{"alice": {Theme: "nord", Session: "Sway", Username: "alice", ASCIIIndex: 2}},
+		},
+		{
+			name:     "version 1",
+			file:     `{"version":1,"data":{"last_user":"bob","defaults":{"theme":"gruvbox"},"users":{"bob":{"theme":"gruvbox","username":"bob"}}}}`,
+			lastUser: "bob",
+			defaults: UserPreferences{Theme: "gruvbox"},
+			users:    map[string]UserPreferences{"bob": {Theme: "gruvbox", Username: "bob"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := useDir(t)
+			os.MkdirAll(dir, 0700)
+			os.WriteFile(filepath.Join(dir, preferencesFile), []byte(tt.file), 0644)
+
+			store, err := loadPreferencesStore()
+			if err != nil {
+				t.Fatalf("loadPreferencesStore: %v", err)
+			}
+			if store.LastUser != tt.lastUser || store.Defaults != tt.defaults || !reflect.DeepEqual(store.Users, tt.users) {
+				t.Fatalf("unexpected store: %+v", store)
+			}
+		})
+	}
+}
+
+func TestNewerPreferencesAreLeftAlone(t *testing.T) {
+	dir := useDir(t)
+	os.MkdirAll(dir, 0700)
+	newer := `{"version":99,"data":{"defaults":{"theme":"nord"}}}`
+	os.WriteFile(filepath.Join(dir, preferencesFile), []byte(newer), 0600)
+
+	err := UpdatePreferences("", func(p *UserPreferences) { p.Theme = "gruvbox" })
+	var nv *NewerVersionError
+	if !errors.As(err, &nv) || nv.Version != 99 || nv.File != preferencesFile {
+		t.Fatalf("expected a NewerVersionError, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, preferencesFile)); string(data) != newer {
+		t.Fatalf("expected the file to stay as it was, got %s", data)
+	}
+}
+
+func TestCorruptPreferencesAreReplaced(t *testing.T) {
+	dir := useDir(t)
+	os.MkdirAll(dir, 0700)
+	os.WriteFile(filepath.Join(dir, preferencesFile), []byte(`{"defaults":`), 0644)
+
+	if _, err := LoadPreferences(); err == nil {
+		t.Fatal("expected the corrupt file to be reported")
+	}
+	if err := SaveLastUser("alice"); err != nil {
+		t.Fatalf("expected saving to start over, got %v", err)
+	}
+	if prefs, err := LoadPreferences(); err != nil || prefs == nil || prefs.Username != "alice" {
+		t.Fatalf("unexpected preferences: %+v, %v", prefs, err)
+	}
+}
+
+func TestWriteFileIsPrivateAndAtomic(t *testing.T) {
+	dir := useDir(t)
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, sessionStatsFile), []byte("{}"), 0644)
+
+	if err := SaveSessionStats(SessionStats{Usage: map[string]int{"sway": 1}}); err != nil {
+		t.Fatalf("SaveSessionStats: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dir, sessionStatsFile))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected a 0600 file, got %v, %v", info.Mode(), err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected no temporary files to be left behind, got %v", entries)
+	}
+	if stats, err := LoadSessionStats(); err != nil || stats.Usage["sway"] != 1 {
+		t.Fatalf("LoadSessionStats: %+v, %v", stats, err)
+	}
+}
+
+func TestCommandHistoryPerUser(t *testing.T) {
+	dir := useDir(t)
+	os.MkdirAll(dir, 0700)
+	// The shared list of earlier versions belongs to nobody
+	os.WriteFile(filepath.Join(dir, commandHistoryFile), []byte(`["sway -d"]`), 0600)
+
+	if history, err := LoadCommandHistory("alice"); err != nil || len(history) != 0 {
+		t.Fatalf("expected the shared list to be ignored, got %q, %v", history, err)
+	}
+	if err := SaveCommandHistory("alice", []string{"zsh"}); err != nil {
+		t.Fatalf("SaveCommandHistory: %v", err)
+	}
+	if err := SaveCommandHistory("bob", []string{"startx"}); err != nil {
+		t.Fatalf("SaveCommandHistory: %v", err)
+	}
+
+	tests := []struct {
+		user string
+		want []string
+	}{
+		{"alice", []string{"zsh"}},
+		{"bob", []string{"startx"}},
+		{"carol", nil},
+	}
+	for _, tt := range tests {
+		if got, err := LoadCommandHistory(tt.user); err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LoadCommandHistory(%q) = %q, %v, want %q", tt.user, got, err, tt.want)
+		}
+	}
+}
+
+func TestCacheFilesAreVersioned(t *testing.T) {
+	session := sessions.Session{Name: "Sway", Exec: "sway", Type: "Wayland"}
+	stats := SessionStats{Usage: map[string]int{"Wayland:sway": 2}, Favorites: []string{"Wayland:sway"}}
+
+	tests := []struct {
+		file   string
+		legacy string // Written before versioning
+		save   func() error
+		check  func() error
+	}{
+		{
+			file:   sessionFile,
+			legacy: `{"Name":"Sway","Exec":"sway","Type":"Wayland"}`,
+			save:   func() error { return SaveSelectedSession(session) },
+			check: func() error {
+				got, err := LoadSelectedSession()
+				if err == nil && (got == nil || !reflect.DeepEqual(*got, session)) {
+					err = fmt.Errorf("got %+v", got)
+				}
+				return err
+			},
+		},
+		{
+			file:   sessionStatsFile,
+			legacy: `{"usage":{"Wayland:sway":2},"favorites":["Wayland:sway"]}`,
+			save:   func() error { return SaveSessionStats(stats) },
+			check: func() error {
+				got, err := LoadSessionStats()
+				if err == nil && !reflect.DeepEqual(got, stats) {
+					err = fmt.Errorf("got %+v", got)
+				}
+				return err
+			},
+		},
+		{
+			file:   sessionUserFile,
+			legacy: `{"user":"alice"}`,
+			save:   func() error { return SaveSessionUser("alice") },
+			check: func() error {
+				got, err := LoadSessionUser()
+				if err == nil && got != "alice" {
+					err = fmt.Errorf("got %q", got)
+				}
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			dir := useDir(t)
+			os.MkdirAll(dir, 0700)
+			path := filepath.Join(dir, tt.file)
+
+			os.WriteFile(path, []byte(tt.legacy), 0600)
+			if err := tt.check(); err != nil {
+				t.Fatalf("reading version 0: %v", err)
+			}
+
+			if err := tt.save(); err != nil {
+				t.Fatalf("saving: %v", err)
+			}
+			data, _ := os.ReadFile(path)
+			var env envelope
+			if err := json.Unmarshal(data, &env); err != nil || env.Version != len(migrations[tt.file]) {
+				t.Fatalf("expected version %d, got %s", len(migrations[tt.file]), data)
+			}
+			if err := tt.check(); err != nil {
+				t.Fatalf("reading the saved file: %v", err)
+			}
+
+			// Newer files are neither read nor replaced
+			newer := `{"version":99,"data":{}}`
+			os.WriteFile(path, []byte(newer), 0600)
+			var nv *NewerVersionError
+			if err := tt.check(); !errors.As(err, &nv) {
+				t.Fatalf("expected a NewerVersionError when reading, got %v", err)
+			}
+			if err := tt.save(); !errors.As(err, &nv) {
+				t.Fatalf("expected a NewerVersionError when saving, got %v", err)
+			}
+			if data, _ := os.ReadFile(path); string(data) != newer {
+				t.Fatalf("expected the newer file to stay as it was, got %s", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// version.go - schema versions of the cache files
+// Every file is written as {"version": N, "data": ...}. Files from before
+// versioning hold the data alone and are version 0. Reading a file runs the
+// migrations from its version up to the current one, so a layout change only
+// needs a new entry in migrations
+
+// migration turns the data of a version i file into that of version i+1
+// A nil migration means the layout did not change
+type migration func(data json.RawMessage) (json.RawMessage, error)
+
+// migrations[name][i] upgrades version i of the cache file name
+// The current version of a file is len(migrations[name])
+var migrations = map[string][]migration{
+	sessionFile:        {nil},
+	preferencesFile:    {migratePreferencesV0},
+	sessionStatsFile:   {nil},
+	commandHistoryFile: {migrateCommandHistoryV0},
+	sessionUserFile:    {nil},
+}
+
+// envelope is the on-disk layout of a versioned cache file
+type envelope struct {
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// NewerVersionError is returned for a cache file written by a newer
+// sysc-greet. It is left alone rather than downgraded
+type NewerVersionError struct {
+	File    string
+	Version int
+}
+
+func (e *NewerVersionError) Error() string {
+	return fmt.Sprintf("%s file has schema version %d, this sysc-greet only knows up to %d", e.File, e.Version, len(migrations[e.File]))
+}
+
+// encode wraps v in the envelope of the current version of the file name
+func encode(name string, v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope{Version: len(migrations[name]), Data: data})
+}
+
+// decode reads the file name's data into v, migrating older versions
+func decode(name string, data []byte, v any) error {
+	env, err := parseEnvelope(data)
+	if err != nil {
+		return err
+	}
+	steps := migrations[name]
+	if env.Version > len(steps) {
+		return &NewerVersionError{File: name, Version: env.Version}
+	}
+	for version := env.Version; version < len(steps); version++ {
+		if steps[version] == nil {
+			continue
+		}
+		if env.Data, err = steps[version](env.Data); err != nil {
+			return fmt.Errorf("failed to migrate from version %d: %w", version, err)
+		}
+	}
+	return json.Unmarshal(env.Data, v)
+}
+
+// parseEnvelope splits a cache file into its version and data
+func parseEnvelope(data []byte) (envelope, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return envelope{}, err
+		}
+		env = envelope{} // Version 0 data that is not an object, such as a list
+	}
+	if env.Version == 0 {
+		env.Data = data
+	}
+	return env, nil
+}
+
+// checkNotNewer refuses to replace the file name when a newer sysc-greet wrote it
+// Unreadable or corrupt files may be replaced
+func checkNotNewer(name string, data []byte) error {
+	if data == nil {
+		return nil
+	}
+	env, err := parseEnvelope(data)
+	if err == nil && env.Version > len(migrations[name]) {
+		return &NewerVersionError{File: name, Version: env.Version}
+	}
+	return nil
+}
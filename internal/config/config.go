@@ -0,0 +1,417 @@
+// Package config loads the system-wide sysc-greet configuration file
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultPath is where administrators put the greeter configuration
+const DefaultPath = "/etc/sysc-greet/config.toml"
+
+// DropInDir is the directory next to the configuration file whose *.toml files
+// are applied after it, in lexical order (e.g. /etc/sysc-greet/config.d)
+const DropInDir = "config.d"
+
+// File is the parsed configuration
+//
+//	[greeter]
+//	remember_username = true
+//	ipc_timeout = 30
+//
+//	[appearance]
+//	theme = "nord"
+//	background = "matrix"
+//	border = "rounded"
+//
+//	[screensaver]
+//	idle_timeout = 10
+//	clock_style = "plain"
+//
+//	[paths]
+//	data_dir = "/usr/local/share/sysc-greet"
+//	wallpaper_dirs = ["/srv/wallpapers"]
+//
+//	[env]
+//	MOZ_ENABLE_WAYLAND = "1"
+//
+//	[session_env.hyprland]
+//	WLR_NO_HARDWARE_CURSORS = "1"
+//
+//	[session_args]
+//	sway = ["--unsupported-gpu"]
+//
+//	[x11]
+//	launch = "startx"
+//
+//	[crash_report]
+//	enabled = true
+//	fallback = "sway"
+//
+//	[sessions]
+//	order = "pinned"
+//	pinned = ["hyprland", "sway"]
+//	custom_command = true
+//
+//	[[sessions.extra]]
+//	name = "Shell"
+//	command = "bash -l"
+//	fallback = true
+//
+//	[users]
+//	list = true
+//	hide = ["guest"]
+//
+//	[login]
+//	backoff_after = 3
+//	max_attempts = 10
+//	max_attempts_action = "screensaver"
+//
+//	[access]
+//	groups = ["wheel", "lab"]
+//	min_uid = 1000
+//	deny = ["backup"]
+//
+//	# The guest account is checked against [access] too: here it must be in "lab"
+//	[guest]
+//	enabled = true
+//	command = "cage -- firefox --kiosk https://catalog.example.org"
+//	autostart = 30
+//	lock_menu = true
+//
+//	[idle]
+//	clear_password_after = 30
+//	forget_username = true
+//
+//	[log]
+//	level = "info"
+//	sink = "journald"
+//
+//	[audit]
+//	enabled = true
+//	hash_usernames = true
+type File struct {
+	Greeter     Greeter     `toml:"greeter"`
+	Appearance  Appearance  `toml:"appearance"`
+	Screensaver Screensaver `toml:"screensaver"`
+	Paths       Paths       `toml:"paths"`
+	Sessions    Sessions    `toml:"sessions"`
+	Users       Users       `toml:"users"`
+	Login       Login       `toml:"login"`
+	Access      Access      `toml:"access"`
+	Guest       Guest       `toml:"guest"`
+	Idle        Idle        `toml:"idle"`
+	Log         Log         `toml:"log"`
+	Audit       Audit       `toml:"audit"`
+	X11         X11         `toml:"x11"`
+	CrashReport CrashReport `toml:"crash_report"`
+
+	// Env is added to every session's environment
+	Env map[string]string `toml:"env"`
+	// SessionEnv is added for one session, keyed by desktop file ID or session name
+	SessionEnv map[string]map[string]string `toml:"session_env"`
+	// SessionArgs are inserted after a session's program, keyed by desktop file ID or session name
+	SessionArgs map[string][]string `toml:"session_args"`
+
+	// Broken lists the files that exist but could not be read or parsed. Their
+	// settings are missing; the greeter names them in the form until fixed
+	Broken []string `toml:"-"`
+}
+
+// Greeter holds the settings that can also be given as command line flags
+// Unset values leave the flag's default; a flag given on the command line wins
+type Greeter struct {
+	RememberUsername *bool  `toml:"remember_username"` // Remember the last logged in username (default true)
+	UsernameOnly     *bool  `toml:"username_only"`     // Submit on username Enter and let PAM ask for anything else
+	Autologin        string `toml:"autologin"`         // Log in as this user automatically after autologin_delay
+	AutologinDelay   *int   `toml:"autologin_delay"`   // Autologin countdown in seconds (default 5)
+	IPCTimeout       *int   `toml:"ipc_timeout"`       // Seconds to wait for each greetd response, 0 waits forever (default 60)
+	ShowTime         *bool  `toml:"show_time"`         // Show the clock in the border
+	Debug            *bool  `toml:"debug"`             // Enable debug output
+}
+
+// Appearance sets the look used until a user picks another in the F1 menu
+type Appearance struct {
+	Theme      string `toml:"theme"`      // Built-in or custom theme name (default "dracula")
+	Background string `toml:"background"` // Background effect: "none" (default), "fire", "matrix", ...
+	Border     string `toml:"border"`     // Border style (default "classic")
+}
+
+// Screensaver overrides the settings of ascii_configs/screensaver.conf
+type Screensaver struct {
+	IdleTimeout    *int     `toml:"idle_timeout"`     // Minutes without input before the screensaver starts (default 5)
+	TimeFormat     string   `toml:"time_format"`      // Go time layout for the clock
+	DateFormat     string   `toml:"date_format"`      // Go time layout for the date
+	ClockStyle     string   `toml:"clock_style"`      // "kompaktblk" (default), "delta_corp", "phmvga", "dos_rebel" or "plain"
+	AnimateOnStart *bool    `toml:"animate_on_start"` // Animate the ASCII art when the screensaver starts (default true)
+	AnimationType  string   `toml:"animation_type"`   // "print" (default) or "none"
+	AnimationSpeed *int     `toml:"animation_speed"`  // Milliseconds per character (default 20)
+	ASCII          []string `toml:"ascii"`            // ASCII art variants, replacing those of screensaver.conf
+}
+
+// Paths overrides where the greeter looks for its files
+type Paths struct {
+	DataDir       string   `toml:"data_dir"`       // ASCII configs, themes, fonts and wallpapers (default /usr/share/sysc-greet)
+	WallpaperDirs []string `toml:"wallpaper_dirs"` // Searched in order for the wallpaper menu (default /var/lib/greeter/Pictures/wallpapers, ~/Pictures/wallpapers)
+	StateDir      string   `toml:"state_dir"`      // Preferences, last session and history (default ~/.cache/sysc-greet of the greeter user)
+}
+
+// Sessions controls the session list
+type Sessions struct {
+	Order         string         `toml:"order"`          // "alphabetical" (default), "frequency" or "pinned"
+	Pinned        []string       `toml:"pinned"`         // Desktop file IDs or names listed first with order = "pinned"
+	CustomCommand bool           `toml:"custom_command"` // Offer a "Custom command" entry to type any command
+	Extra         []ExtraSession `toml:"extra"`          // Sessions without a desktop file
+}
+
+// ExtraSession is a session defined in the config instead of a desktop file
+type ExtraSession struct {
+	Name     string `toml:"name"`
+	Command  string `toml:"command"`  // Quoted like a desktop file Exec= line
+	Type     string `toml:"type"`     // "tty" (default), "wayland" or "x11"
+	Comment  string `toml:"comment"`  // Shown in the session list
+	Fallback bool   `toml:"fallback"` // Only listed when no desktop sessions are installed
+}
+
+// Users controls the user picker
+type Users struct {
+	List   bool     `toml:"list"`   // Show a user picker below the username field
+	Hide   []string `toml:"hide"`   // Accounts never offered in the picker
+	Passwd string   `toml:"passwd"` // Account database, defaults to /etc/passwd
+}
+
+// Login controls rate limiting of failed logins
+// Zero values select the greeter's defaults
+type Login struct {
+	BackoffAfter      int    `toml:"backoff_after"`       // Consecutive failures before input is disabled (default 3)
+	BackoffSeconds    int    `toml:"backoff_seconds"`     // First delay, doubled per further failure (default 5)
+	BackoffMaxSeconds int    `toml:"backoff_max_seconds"` // Upper bound for the delay (default 60)
+	MaxAttempts       int    `toml:"max_attempts"`        // Failures that trigger MaxAttemptsAction (0 disables)
+	MaxAttemptsAction string `toml:"max_attempts_action"` // "clear-username" (default) or "screensaver"
+}
+
+// Access restricts who may log in (see internal/access)
+// Checked before greetd is contacted; all set restrictions must pass
+type Access struct {
+	Allow  []string `toml:"allow"`   // Only these users may log in
+	Deny   []string `toml:"deny"`    // These users may never log in
+	Groups []string `toml:"groups"`  // Users must be a member of one of these groups (from /etc/group)
+	MinUID int      `toml:"min_uid"` // Reject accounts with a lower UID (0 disables)
+}
+
+// Guest adds a passwordless guest entry to the session list and locks the greeter down for kiosks
+type Guest struct {
+	Enabled   bool   `toml:"enabled"`    // Offer the guest entry
+	User      string `toml:"user"`       // Account the guest session runs as (default "guest")
+	Name      string `toml:"name"`       // Label in the session list (default "Guest")
+	Command   string `toml:"command"`    // Session command, quoted like a desktop file Exec= line
+	Type      string `toml:"type"`       // "wayland" (default), "x11" or "tty"
+	Autostart int    `toml:"autostart"`  // Seconds before the guest session starts by itself (0 disables)
+	LockMenu  bool   `toml:"lock_menu"`  // Disable the F1 menu (themes, backgrounds, wallpaper)
+	LockPower bool   `toml:"lock_power"` // Disable the F4 power menu
+}
+
+// Account returns the account the guest session runs as
+func (g Guest) Account() string {
+	if g.User == "" {
+		return "guest"
+	}
+	return g.User
+}
+
+// Label returns the name of the guest entry
+func (g Guest) Label() string {
+	if g.Name == "" {
+		return "Guest"
+	}
+	return g.Name
+}
+
+// Idle controls what happens to credentials left in an unattended form
+type Idle struct {
+	ClearPasswordAfter int   `toml:"clear_password_after"` // Seconds without keystrokes before a typed password is cleared (0 disables)
+	ResetOnScreensaver *bool `toml:"reset_on_screensaver"` // Cancel the greetd session and return to the username field (default true)
+	ForgetUsername     bool  `toml:"forget_username"`      // Also clear the username when the screensaver resets the form
+}
+
+// ResetsOnScreensaver reports whether starting the screensaver resets the form
+func (i Idle) ResetsOnScreensaver() bool {
+	return i.ResetOnScreensaver == nil || *i.ResetOnScreensaver
+}
+
+// Log controls the greeter's log (see internal/logging)
+type Log struct {
+	Level        string `toml:"level"`         // "debug", "info", "warn" (default) or "error"
+	Sink         string `toml:"sink"`          // "auto" (default), "file", "journald", "stderr" or "none"
+	Path         string `toml:"path"`          // Log file for the file sink
+	MaxSizeKB    int    `toml:"max_size_kb"`   // Rotate the log file at this size (default 1024)
+	MaxFiles     int    `toml:"max_files"`     // Rotated log files kept (default 3)
+	LogUsernames bool   `toml:"log_usernames"` // Write usernames in clear instead of a short hash
+}
+
+// Audit controls the login audit trail (see internal/audit)
+type Audit struct {
+	Enabled       bool   `toml:"enabled"`        // Record login attempts
+	Path          string `toml:"path"`           // Audit log, defaults to /var/log/sysc-greet/audit.log
+	HashUsernames bool   `toml:"hash_usernames"` // Record sha256 hashes instead of usernames
+	Syslog        bool   `toml:"syslog"`         // Also forward events to syslog over /dev/log
+}
+
+// X11 controls how sessions from xsessions/ are started
+type X11 struct {
+	Launch  string `toml:"launch"`  // "startx" (default), "xorg", "wrapper" or "direct"
+	Wrapper string `toml:"wrapper"` // Command for launch = "wrapper": %s is the session command, %v the VT argument
+	VT      int    `toml:"vt"`      // VT for the X server (default: the greeter's, from $XDG_VTNR)
+}
+
+// CrashReport records how sessions end, to explain a failure when the greeter returns
+type CrashReport struct {
+	Enabled    bool   `toml:"enabled"`     // Run sessions through the "sysc-greet run-session" wrapper
+	Dir        string `toml:"dir"`         // Report directory, defaults to /var/lib/sysc-greet/sessions
+	Lines      int    `toml:"lines"`       // Lines of the session's stderr kept (default 20)
+	MinRuntime *int   `toml:"min_runtime"` // Seconds; sessions ending sooner are reported even if they exit with 0 (default 10)
+	Fallback   string `toml:"fallback"`    // Session offered instead of the failed one (default: the next in the list)
+}
+
+// MinimumRuntime returns how long a session must run for a clean exit not to be reported
+func (c CrashReport) MinimumRuntime() time.Duration {
+	if c.MinRuntime == nil {
+		return 10 * time.Second
+	}
+	return time.Duration(*c.MinRuntime) * time.Second
+}
+
+// Load reads the configuration at path, then the drop-ins in DropInDir next to
+// it. Each file overrides the keys it sets; lists and [[tables]] are replaced,
+// key/value tables such as [env] are merged. Missing files are not an error.
+// Problems never make Load give up: a file that cannot be parsed is skipped and
+// recorded in Broken, unknown keys are ignored, and all of them are returned
+// joined in the error alongside the configuration built from the remaining files
+func Load(path string) (File, error) {
+	var f File
+	var errs []error
+	for _, file := range Files(path) {
+		ok, err := decodeFile(file, &f)
+		if !ok {
+			f.Broken = append(f.Broken, file)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return f, errors.Join(errs...)
+}
+
+// Files returns the files Load reads for path, in the order they are applied
+// Drop-ins that cannot be listed are left out
+func Files(path string) []string {
+	files := []string{path}
+	dropIns, _ := filepath.Glob(filepath.Join(filepath.Dir(path), DropInDir, "*.toml"))
+	sort.Strings(dropIns)
+	return append(files, dropIns...)
+}
+
+// decodeFile applies the file at path to f
+// The file is checked on its own first, so a broken file leaves f untouched.
+// ok is false when the file exists but could not be read or parsed
+func decodeFile(path string, f *File) (ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var scratch File
+	md, err := toml.Decode(string(data), &scratch)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if _, err := toml.Decode(string(data), f); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var unknown []error
+	for _, key := range md.Undecoded() {
+		unknown = append(unknown, fmt.Errorf("%s: unknown key %s", path, key))
+	}
+	return true, errors.Join(unknown...)
+}
+
+// SessionEnvironment returns the overrides for a session: global [env] first,
+// then the [session_env.*] table matching the session name and finally the one
+// matching the desktop file ID. Keys are matched case-insensitively
+func (f File) SessionEnvironment(id, name string) map[string]string {
+	env := make(map[string]string, len(f.Env))
+	for k, v := range f.Env {
+		env[k] = v
+	}
+	for _, want := range []string{name, id} {
+		for key, overrides := range f.SessionEnv {
+			if !strings.EqualFold(key, want) {
+				continue
+			}
+			for k, v := range overrides {
+				env[k] = v
+			}
+		}
+	}
+	return env
+}
+
+// SessionArguments returns the [session_args] entry for a session, preferring
+// the one matching the desktop file ID over the one matching the name. ok is
+// false when neither is configured, so that an empty list can switch off defaults
+func (f File) SessionArguments(id, name string) (args []string, ok bool) {
+	for _, want := range []string{id, name} {
+		for key, list := range f.SessionArgs {
+			if strings.EqualFold(key, want) {
+				return list, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// MergeEnv applies overrides to a KEY=VALUE list
+// Overridden keys are replaced in place, new keys are appended in sorted order
+// and an empty override value removes the variable
+func MergeEnv(base []string, overrides map[string]string) []string {
+	merged := make([]string, 0, len(base)+len(overrides))
+	seen := make(map[string]bool, len(overrides))
+	for _, kv := range base {
+		key, _, _ := strings.Cut(kv, "=")
+		value, ok := overrides[key]
+		if !ok {
+			merged = append(merged, kv)
+			continue
+		}
+		seen[key] = true
+		if value != "" {
+			merged = append(merged, key+"="+value)
+		}
+	}
+
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value := overrides[key]; value != "" {
+			merged = append(merged, key+"="+value)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes files (relative path to contents) below a temporary
+// directory and returns the path of its config.toml
+func writeConfig(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "config.toml")
+}
+
+func TestLoadDropIns(t *testing.T) {
+	path := writeConfig(t, map[string]string{
+		"config.toml": `
+[appearance]
+theme = "dracula"
+border = "rounded"
+
+[env]
+MOZ_ENABLE_WAYLAND = "1"
+
+[users]
+hide = ["guest", "kiosk"]
+`,
+		"config.d/20-site.toml": `
+[appearance]
+theme = "nord"
+
+[env]
+QT_QPA_PLATFORM = "wayland"
+`,
+		"config.d/10-vendor.toml": `
+[appearance]
+theme = "gruvbox"
+background = "fire"
+
+[users]
+hide = ["backup"]
+`,
+		"config.d/README": "not a drop-in",
+	})
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := Appearance{Theme: "nord", Background: "fire", Border: "rounded"}
+	if f.Appearance != want {
+		t.Fatalf("Appearance = %+v, want %+v (drop-ins applied in lexical order)", f.Appearance, want)
+	}
+	if len(f.Env) != 2 {
+		t.Fatalf("expected [env] tables to be merged, got %v", f.Env)
+	}
+	if !reflect.DeepEqual(f.Users.Hide, []string{"backup"}) {
+		t.Fatalf("expected lists to be replaced, got %v", f.Users.Hide)
+	}
+}
+
+func TestLoadProblems(t *testing.T) {
+	path := writeConfig(t, map[string]string{
+		"config.toml": `
+[appearance]
+theme = "nord"
+colour = "red"
+`,
+		"config.d/10-broken.toml": `
+[appearance]
+theme = "gruvbox"
+border =
+`,
+		"config.d/20-types.toml": `
+[greeter]
+ipc_timeout = "soon"
+`,
+		"config.d/30-ok.toml": `
+[greeter]
+autologin = "kiosk"
+`,
+	})
+
+	f, err := Load(path)
+	if err == nil {
+		t.Fatal("expected the problems to be reported")
+	}
+	msg := err.Error()
+	for _, want := range []string{"unknown key appearance.colour", "10-broken.toml", "20-types.toml"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in the error, got:\n%s", want, msg)
+		}
+	}
+	if f.Appearance.Theme != "nord" || f.Greeter.IPCTimeout != nil {
+		t.Fatalf("expected broken files to be skipped entirely, got %+v, ipc_timeout %v", f.Appearance, f.Greeter.IPCTimeout)
+	}
+	if f.Greeter.Autologin != "kiosk" {
+		t.Fatalf("expected later drop-ins to still apply, got %+v", f.Greeter)
+	}
+	// Unknown keys are harmless, files that did not parse are not
+	dir := filepath.Dir(path)
+	want := []string{filepath.Join(dir, "config.d/10-broken.toml"), filepath.Join(dir, "config.d/20-types.toml")}
+	if !reflect.DeepEqual(f.Broken, want) {
+		t.Fatalf("Broken = %v, want %v", f.Broken, want)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	f, err := Load(filepath.Join(t.TempDir(), "config.toml"))
+	if err != nil {
+		t.Fatalf("expected a missing config to be fine, got %v", err)
+	}
+	if !reflect.DeepEqual(f, File{}) {
+		t.Fatalf("expected an empty config, got %+v", f)
+	}
+}
+
+func TestLoadDropInsWithoutMainFile(t *testing.T) {
+	path := writeConfig(t, map[string]string{
+		"config.d/50-theme.toml": "[appearance]\ntheme = \"nord\"\n",
+	})
+	f, err := Load(path)
+	if err != nil || f.Appearance.Theme != "nord" {
+		t.Fatalf("expected drop-ins to apply without config.toml, got %+v, %v", f.Appearance, err)
+	}
+}
@@ -0,0 +1,279 @@
+// Package crash records how the last session ended so the greeter can explain
+// why it is back. A wrapper started by greetd in place of the session command
+// runs it, keeps the last lines of its stderr and writes a Report to a shared
+// directory; the greeter shows the newest report when it starts again
+package crash
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode"
+)
+
+// DefaultDir is where reports are written unless configured otherwise
+// It should be owned by the greeter user with mode 1733, so that sessions can
+// add reports but not read or remove those of other users
+const DefaultDir = "/var/lib/sysc-greet/sessions"
+
+// DefaultLines is how many lines of stderr a report keeps
+const DefaultLines = 20
+
+// MaxAge is how old a report may be and still be shown, so a report left
+// behind before a reboot does not greet the next user
+const MaxAge = 10 * time.Minute
+
+// Report describes how a session ended
+type Report struct {
+	Session  string    `json:"session"`          // Session name, e.g. "Hyprland"
+	Key      string    `json:"key,omitempty"`    // Session key (type and desktop file ID)
+	User     string    `json:"user"`             // Account the session ran as
+	Command  []string  `json:"command"`          // Command the wrapper ran
+	Started  time.Time `json:"started"`          // When the command was started
+	Ended    time.Time `json:"ended"`            // When it exited
+	ExitCode int       `json:"exit_code"`        // Exit status, -1 when killed by a signal
+	Signal   string    `json:"signal,omitempty"` // Signal that killed it, e.g. "segmentation fault"
+	Error    string    `json:"error,omitempty"`  // Why the command could not be started
+	Stderr   []string  `json:"stderr,omitempty"` // Last lines written to stderr
+}
+
+// Runtime returns how long the session ran
+func (r Report) Runtime() time.Duration {
+	return r.Ended.Sub(r.Started)
+}
+
+// Failed reports whether the session ended abnormally: a non-zero exit status,
+// a signal, or an exit within minRuntime of starting
+func (r Report) Failed(minRuntime time.Duration) bool {
+	return r.ExitCode != 0 || r.Signal != "" || r.Error != "" || r.Runtime() < minRuntime
+}
+
+// Summary describes the end of the session in one line, e.g.
+// "Last session (Hyprland) exited with code 1 after 2s"
+func (r Report) Summary() string {
+	runtime := r.Runtime().Round(time.Second)
+	switch {
+	case r.Error != "":
+		return fmt.Sprintf("Last session (%s) failed to start: %s", r.Session, r.Error)
+	case r.Signal != "":
+		return fmt.Sprintf("Last session (%s) was killed (%s) after %s", r.Session, r.Signal, runtime)
+	}
+	return fmt.Sprintf("Last session (%s) exited with code %d after %s", r.Session, r.ExitCode, runtime)
+}
+
+// Write stores r as a new file in dir
+// Each report gets a random name: the directory is shared by all users and not
+// listable by them, so they cannot find each other's reports
+func Write(dir string, r Report) error {
+	f, err := os.CreateTemp(dir, "session-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create session report: %w", err)
+	}
+	defer f.Close()
+	// Readable by the greeter user, which only owns the directory
+	if err := f.Chmod(0644); err != nil {
+		return fmt.Errorf("failed to set session report permissions: %w", err)
+	}
+	if err := json.NewEncoder(f).Encode(r); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write session report: %w", err)
+	}
+	return nil
+}
+
+// Latest returns the newest report in dir that ended within MaxAge of now and
+// removes every report, so that each is shown once. It returns nil when there
+// is none; a missing directory is not an error
+// Every user can add files to dir, so only reports written by uid (the account
+// the greeter last started a session for) are considered, and their text is
+// stripped of control characters before it reaches the login screen
+func Latest(dir string, now time.Time, uid int) (*Report, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read session reports: %w", err)
+	}
+
+	var latest *Report
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		info, err := e.Info()
+		var data []byte
+		if err == nil {
+			data, err = os.ReadFile(path)
+		}
+		if err == nil {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); !ok || int(st.Uid) != uid {
+			continue
+		}
+		var r Report
+		if err := json.Unmarshal(data, &r); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		if now.Sub(r.Ended) > MaxAge {
+			continue
+		}
+		r.sanitize()
+		if latest == nil || r.Ended.After(latest.Ended) {
+			latest = &r
+		}
+	}
+	return latest, errors.Join(errs...)
+}
+
+// sanitize makes every text field of r safe to show in the greeter
+func (r *Report) sanitize() {
+	for _, s := range []*string{&r.Session, &r.Key, &r.User, &r.Signal, &r.Error} {
+		*s = printable(*s)
+	}
+	for i := range r.Command {
+		r.Command[i] = printable(r.Command[i])
+	}
+	for i := range r.Stderr {
+		r.Stderr[i] = printable(r.Stderr[i])
+	}
+}
+
+// Run starts argv with stderr copied to stderr and returns the report once it
+// exits; stdin and stdout are inherited and the last lines of stderr are kept.
+// started, when not nil, receives the process once it runs so the caller can
+// forward signals to it. Session and User are left for the caller to fill in
+func Run(argv []string, stderr io.Writer, lines int, started func(*os.Process)) Report {
+	if lines <= 0 {
+		lines = DefaultLines
+	}
+	r := Report{Command: argv, Started: time.Now()}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	pr, pw, err := os.Pipe()
+	if err == nil {
+		defer pr.Close()
+		cmd.Stderr = pw
+		err = cmd.Start()
+		pw.Close()
+	}
+	if err != nil {
+		r.Ended = time.Now()
+		r.ExitCode = 127
+		r.Error = err.Error()
+		fmt.Fprintf(stderr, "%v\n", err)
+		return r
+	}
+	if started != nil {
+		started(cmd.Process)
+	}
+
+	tail := newTail(lines)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			fmt.Fprintln(stderr, scanner.Text())
+			tail.add(printable(scanner.Text()))
+		}
+		// A line too long for the scanner ends the tail, but the session
+		// must never block on a full pipe
+		io.Copy(stderr, pr)
+	}()
+
+	err = cmd.Wait()
+	r.Ended = time.Now()
+	// Programs the session left running may hold stderr open; the greeter
+	// must not wait for them
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		pr.Close()
+	}
+	r.Stderr = tail.lines()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		r.ExitCode = exitErr.ExitCode()
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			r.Signal = status.Signal().String()
+		}
+	} else if err != nil {
+		r.ExitCode = 1
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// tail keeps the last n lines added
+type tail struct {
+	mu   sync.Mutex
+	n    int
+	buf  []string
+	next int
+}
+
+func newTail(n int) *tail {
+	return &tail{n: n}
+}
+
+func (t *tail) add(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.buf) < t.n {
+		t.buf = append(t.buf, line)
+		return
+	}
+	t.buf[t.next] = line
+	t.next = (t.next + 1) % t.n
+}
+
+func (t *tail) lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append(append([]string(nil), t.buf[t.next:]...), t.buf[:t.next]...)
+}
+
+// printable drops terminal escape sequences (colored log output) and other
+// control characters, C1 controls and invalid UTF-8 included, from a line, so
+// it can be shown in the greeter
+func printable(line string) string {
+	runes := []rune(strings.ToValidUTF8(line, "\uFFFD"))
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == 0x1b && i+1 < len(runes) && runes[i+1] == '[':
+			// CSI: parameters up to a final byte in @..~
+			for i += 2; i < len(runes) && (runes[i] < 0x40 || runes[i] > 0x7e); i++ {
+			}
+		case c == '\t':
+			b.WriteByte(' ')
+		case unicode.IsControl(c):
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
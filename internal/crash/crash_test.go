@@ -0,0 +1,159 @@
+package crash
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		argv       []string
+		wantCode   int
+		wantSignal string
+		wantError  bool
+		wantStderr []string
+	}{
+		{"exit code", []string{"sh", "-c", "echo one >&2; echo two >&2; echo three >&2; exit 3"}, 3, "", false, []string{"two", "three"}},
+		{"success", []string{"true"}, 0, "", false, nil},
+		{"signal", []string{"sh", "-c", "echo dying >&2; kill -SEGV $$"}, -1, "segmentation fault", false, []string{"dying"}},
+		{"escape sequences", []string{"sh", "-c", `printf '\033[1;31mERROR\033[0m\tno outputs\r\n' >&2; exit 1`}, 1, "", false, []string{"ERROR no outputs"}},
+		{"missing program", []string{"/nonexistent/sway"}, 127, "", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			r := Run(tt.argv, &stderr, 2, nil)
+			if r.ExitCode != tt.wantCode || r.Signal != tt.wantSignal || (r.Error != "") != tt.wantError {
+				t.Fatalf("unexpected report %+v", r)
+			}
+			if !reflect.DeepEqual(r.Stderr, tt.wantStderr) {
+				t.Fatalf("Stderr = %q, want %q", r.Stderr, tt.wantStderr)
+			}
+			if tt.wantStderr != nil && !strings.Contains(printable(stderr.String()), tt.wantStderr[len(tt.wantStderr)-1]) {
+				t.Fatalf("expected stderr to be passed through, got %q", stderr.String())
+			}
+			if r.Ended.Before(r.Started) {
+				t.Fatalf("ended before it started: %+v", r)
+			}
+		})
+	}
+}
+
+func TestRunDoesNotWaitForLeftoverProcesses(t *testing.T) {
+	start := time.Now()
+	r := Run([]string{"sh", "-c", "sleep 5 >/dev/null & echo bye >&2"}, &bytes.Buffer{}, 5, nil)
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Fatalf("Run waited %s for a background process holding stderr", elapsed)
+	}
+	if !reflect.DeepEqual(r.Stderr, []string{"bye"}) {
+		t.Fatalf("Stderr = %q", r.Stderr)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		report Report
+		want   string
+	}{
+		{Report{Session: "Hyprland", ExitCode: 1, Started: start, Ended: start.Add(2300 * time.Millisecond)},
+			"Last session (Hyprland) exited with code 1 after 2s"},
+		{Report{Session: "Sway", ExitCode: -1, Signal: "segmentation fault", Started: start, Ended: start.Add(90 * time.Second)},
+			"Last session (Sway) was killed (segmentation fault) after 1m30s"},
+		{Report{Session: "i3", ExitCode: 127, Error: `exec: "i3": executable file not found in $PATH`, Started: start, Ended: start},
+			`Last session (i3) failed to start: exec: "i3": executable file not found in $PATH`},
+	}
+	for _, tt := range tests {
+		if got := tt.report.Summary(); got != tt.want {
+			t.Errorf("Summary() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestFailed(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name   string
+		report Report
+		want   bool
+	}{
+		{"clean logout", Report{Started: start, Ended: start.Add(time.Hour)}, false},
+		{"quick exit", Report{Started: start, Ended: start.Add(3 * time.Second)}, true},
+		{"error code", Report{ExitCode: 1, Started: start, Ended: start.Add(time.Hour)}, true},
+		{"signal", Report{ExitCode: -1, Signal: "aborted", Started: start, Ended: start.Add(time.Hour)}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.report.Failed(10 * time.Second); got != tt.want {
+			t.Errorf("%s: Failed() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteLatest(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	reports := []Report{
+		{Session: "Sway", Ended: now.Add(-time.Minute)},
+		{Session: "Hyprland", Ended: now.Add(-10 * time.Second), Stderr: []string{"failed to open DRM device"}},
+		{Session: "Stale", Ended: now.Add(-time.Hour)},
+	}
+	for _, r := range reports {
+		if err := Write(dir, r); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("kept"), 0644)
+
+	latest, err := Latest(dir, now, os.Getuid())
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if latest == nil || latest.Session != "Hyprland" || len(latest.Stderr) != 1 {
+		t.Fatalf("expected the Hyprland report, got %+v", latest)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "notes.txt" {
+		t.Fatalf("expected the reports to be removed, found %v", entries)
+	}
+	if latest, err := Latest(dir, now, os.Getuid()); latest != nil || err != nil {
+		t.Fatalf("expected each report to be shown once, got %+v, %v", latest, err)
+	}
+	if latest, err := Latest(filepath.Join(dir, "missing"), now, os.Getuid()); latest != nil || err != nil {
+		t.Fatalf("expected a missing directory to yield nothing, got %+v, %v", latest, err)
+	}
+}
+
+func TestLatestDistrustsOtherUsers(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	forged := Report{
+		Session: "Sway\x1b[2J\x1b]0;title\x07", Error: "bad\u009b31m",
+		Ended: now, Stderr: []string{"Password: \x1b[8mhidden", "\xff\ttab"},
+	}
+	Write(dir, forged)
+
+	if latest, err := Latest(dir, now, os.Getuid()+1); latest != nil || err != nil {
+		t.Fatalf("expected a report of another user to be ignored, got %+v, %v", latest, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected the ignored report to be removed, found %v", entries)
+	}
+
+	Write(dir, forged)
+	latest, err := Latest(dir, now, os.Getuid())
+	if err != nil || latest == nil {
+		t.Fatalf("Latest: %+v, %v", latest, err)
+	}
+	want := Report{Session: "Sway]0;title", Error: "bad31m", Ended: latest.Ended, Stderr: []string{"Password: hidden", "\uFFFD tab"}}
+	if !reflect.DeepEqual(*latest, want) {
+		t.Fatalf("expected the report to be sanitized, got %+q", *latest)
+	}
+}
@@ -1,12 +1,26 @@
 package ipc
 
 import (
+	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"sync"
+	"time"
+
+	"github.com/Nomadcxx/sysc-greet/internal/logging"
+)
+
+var logger = logging.For("ipc")
+
+// Errors reported when greetd does not answer
+var (
+	ErrTimeout        = errors.New("timed out waiting for greetd")
+	ErrConnectionLost = errors.New("lost connection to greetd")
 )
 
 // Message types for requests and responses
@@ -24,6 +38,14 @@ const (
 	AuthMessageResponse ResponseType = "auth_message"
 )
 
+// Auth message types sent by greetd during the PAM conversation
+const (
+	AuthMessageVisible = "visible" // Prompt whose answer may be echoed (e.g. a username or OTP)
+	AuthMessageSecret  = "secret"  // Prompt whose answer must be masked (e.g. a password)
+	AuthMessageInfo    = "info"    // Informational text, answered with a nil response
+	AuthMessageError   = "error"   // Error text, answered with a nil response
+)
+
 // Request structures
 type CreateSession struct {
 	Type     RequestType `json:"type"`
@@ -56,6 +78,11 @@ type Error struct {
 	Description string       `json:"description"`
 }
 
+// Error lets a greetd error response be returned (and matched with errors.As) as an error
+func (e Error) Error() string {
+	return e.ErrorType + " - " + e.Description
+}
+
 type AuthMessage struct {
 	Type            ResponseType `json:"type"`
 	AuthMessageType string       `json:"auth_message_type"`
@@ -63,8 +90,19 @@ type AuthMessage struct {
 }
 
 // Client for communicating with greetd
+// Requests are bounded by Timeout and the caller's context. A connection that
+// times out, is cancelled or drops is closed and transparently re-dialed on the
+// next request; greetd discards the session of a closed connection
 type Client struct {
-	conn net.Conn
+	socketPath string
+
+	// Timeout bounds every request and response (0 disables the per-call timeout)
+	// Acknowledging a PAM notice is not bounded, see timeoutFor
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	conn    net.Conn
+	pending []interface{} // Responses read by SendRequest, oldest first
 }
 
 // NewClient creates a new IPC client connected to greetd
@@ -75,58 +113,241 @@ func NewClient() (*Client, error) {
 		return nil, fmt.Errorf("GREETD_SOCK environment variable not set")
 	}
 
-	conn, err := net.Dial("unix", socketPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to greetd socket at %s: %v", socketPath, err)
+	c := &Client{socketPath: socketPath}
+	if _, err := c.connect(); err != nil {
+		return nil, err
 	}
 
-	return &Client{conn: conn}, nil
+	return c, nil
 }
 
 // Close closes the connection
 func (c *Client) Close() error {
-	return c.conn.Close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
 }
 
-// SendRequest sends a request to greetd
-func (c *Client) SendRequest(req interface{}) error {
+// connect returns the current connection, dialing GREETD_SOCK if there is none
+// Must be called with c.mu held (or before the client is shared)
+func (c *Client) connect() (net.Conn, error) {
+	if c.conn != nil {
+		return c.conn, nil
+	}
+
+	conn, err := net.Dial("unix", c.socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to greetd socket at %s: %v", c.socketPath, err)
+	}
+	logger.Debug("Connected to greetd", "socket", c.socketPath)
+	c.conn = conn
+	return conn, nil
+}
+
+// drop closes a connection whose framing can no longer be trusted
+// Must be called with c.mu held
+func (c *Client) drop() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// timeoutFor returns the per-call timeout of req
+// FIXED 2026-10-16 - A nil response acknowledges an info or error notice, and a
+// module that prints one is often about to wait for the user ("Please touch the
+// device" from pam_u2f). Only the caller's context bounds that wait
+func (c *Client) timeoutFor(req interface{}) time.Duration {
+	if r, ok := req.(PostAuthMessageResponse); ok && r.Response == nil {
+		return 0
+	}
+	return c.Timeout
+}
+
+// bind applies the per-call timeout and ctx cancellation to conn
+// The returned stop function must be called once the I/O is finished
+func (c *Client) bind(ctx context.Context, conn net.Conn, timeout time.Duration) (context.Context, func()) {
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	deadline, _ := ctx.Deadline() // Zero time clears any previous deadline
+	conn.SetDeadline(deadline)
+
+	// Unblock pending reads/writes as soon as ctx is cancelled
+	stopAfter := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+
+	return ctx, func() {
+		stopAfter()
+		cancel()
+	}
+}
+
+// ioError converts a failed read/write into a caller-facing error and drops the connection
+// Must be called with c.mu held
+func (c *Client) ioError(ctx context.Context, op string, err error) error {
+	c.drop()
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		logger.Debug("Request cancelled, connection dropped", "op", op)
+		return fmt.Errorf("%s: %w", op, context.Canceled)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		// The socket deadline can fire just before the context timer does
+		logger.Warn("greetd did not respond in time", "op", op, "timeout", c.Timeout)
+		if c.Timeout > 0 {
+			return fmt.Errorf("%s: %w after %s", op, ErrTimeout, c.Timeout)
+		}
+		return fmt.Errorf("%s: %w", op, ErrTimeout)
+	default:
+		logger.Warn("Lost connection to greetd", "op", op, "err", err)
+		return fmt.Errorf("%s: %w: %v", op, ErrConnectionLost, err)
+	}
+}
+
+// Request sends a request to greetd and returns its response
+func (c *Client) Request(req interface{}) (interface{}, error) {
+	return c.RequestContext(context.Background(), req)
+}
+
+// RequestContext sends a request to greetd, reconnecting if the socket dropped,
+// and returns its response: Success, Error or AuthMessage
+func (c *Client) RequestContext(ctx context.Context, req interface{}) (interface{}, error) {
 	data, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %v", err)
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
 	length := uint32(len(data))
 	lengthBytes := make([]byte, 4)
 	binary.LittleEndian.PutUint32(lengthBytes, length)
+	frame := append(lengthBytes, data...)
+	return c.roundTrip(ctx, frame, requestType(req), c.timeoutFor(req))
+}
 
-	_, err = c.conn.Write(lengthBytes)
+// SendRequest sends a request to greetd and keeps its response for ReceiveResponse
+//
+// Deprecated: use RequestContext, which returns the response directly.
+func (c *Client) SendRequest(req interface{}) error {
+	resp, err := c.Request(req)
 	if err != nil {
-		return fmt.Errorf("failed to write length: %v", err)
+		return err
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = append(c.pending, resp)
+	return nil
+}
 
-	_, err = c.conn.Write(data)
-	if err != nil {
-		return fmt.Errorf("failed to write data: %v", err)
+// ReceiveResponse returns the response to the oldest request sent with SendRequest
+//
+// Deprecated: use RequestContext, which returns the response directly.
+func (c *Client) ReceiveResponse() (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pending) == 0 {
+		return nil, fmt.Errorf("no response pending: SendRequest was not called")
 	}
+	resp := c.pending[0]
+	c.pending[0] = nil
+	c.pending = c.pending[1:]
+	return resp, nil
+}
 
-	return nil
+// roundTrip writes a length-prefixed request to greetd and reads its response
+// c.mu is held for both, so requests made concurrently (a background
+// CancelSession and a new CreateSession) never read each other's responses
+func (c *Client) roundTrip(ctx context.Context, frame []byte, reqType RequestType, timeout time.Duration) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A stale connection is only noticed on write, so retry once on a fresh one
+	for attempt := 0; ; attempt++ {
+		conn, err := c.connect()
+		if err != nil {
+			return nil, err
+		}
+
+		callCtx, stop := c.bind(ctx, conn, timeout)
+		if _, err := conn.Write(frame); err != nil {
+			err = c.ioError(callCtx, "failed to write request", err)
+			stop()
+			if attempt > 0 || !errors.Is(err, ErrConnectionLost) {
+				return nil, err
+			}
+			continue
+		}
+		logger.Debug("Sent request", "type", reqType)
+
+		resp, err := c.receive(callCtx, conn)
+		stop()
+		return resp, err
+	}
 }
 
-// ReceiveResponse receives a response from greetd
-func (c *Client) ReceiveResponse() (interface{}, error) {
+// receive reads one response from conn
+// Must be called with c.mu held
+func (c *Client) receive(ctx context.Context, conn net.Conn) (interface{}, error) {
 	lengthBytes := make([]byte, 4)
-	_, err := io.ReadFull(c.conn, lengthBytes)
+	_, err := io.ReadFull(conn, lengthBytes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read length: %v", err)
+		return nil, c.ioError(ctx, "failed to read length", err)
 	}
 
 	length := binary.LittleEndian.Uint32(lengthBytes)
 	data := make([]byte, length)
-	_, err = io.ReadFull(c.conn, data)
+	_, err = io.ReadFull(conn, data)
+	if err != nil {
+		return nil, c.ioError(ctx, "failed to read data", err)
+	}
+
+	resp, err := decodeResponse(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read data: %v", err)
+		// The framing is intact, but the conversation cannot go on
+		logger.Warn("Invalid response from greetd", "err", err)
+		return nil, err
 	}
+	logResponse(resp)
+	return resp, nil
+}
+
+// requestType returns the type of a request for logging
+func requestType(req interface{}) RequestType {
+	switch r := req.(type) {
+	case CreateSession:
+		return r.Type
+	case PostAuthMessageResponse:
+		return r.Type
+	case StartSession:
+		return r.Type
+	case CancelSession:
+		return r.Type
+	}
+	return RequestType(fmt.Sprintf("%T", req))
+}
+
+// logResponse logs the type of a response - never PAM prompt answers, and only
+// the message type of auth messages
+func logResponse(resp interface{}) {
+	switch r := resp.(type) {
+	case Success:
+		logger.Debug("Received response", "type", r.Type)
+	case Error:
+		logger.Debug("Received response", "type", r.Type, "error_type", r.ErrorType)
+	case AuthMessage:
+		logger.Debug("Received response", "type", r.Type, "auth_message_type", r.AuthMessageType)
+	}
+}
 
+// decodeResponse unmarshals a response into Success, Error or AuthMessage
+func decodeResponse(data []byte) (interface{}, error) {
 	// Determine response type from JSON
 	var raw map[string]interface{}
 	if err := json.Unmarshal(data, &raw); err != nil {
@@ -162,45 +383,97 @@ func (c *Client) ReceiveResponse() (interface{}, error) {
 	}
 }
 
-// CreateSession creates a new session for the given username
-func (c *Client) CreateSession(username string) error {
+// CreateSession creates a new session for the given username and returns
+// greetd's answer: the first auth message, or Success/Error
+func (c *Client) CreateSession(username string) (interface{}, error) {
+	return c.CreateSessionContext(context.Background(), username)
+}
+
+// CreateSessionContext creates a new session for the given username
+func (c *Client) CreateSessionContext(ctx context.Context, username string) (interface{}, error) {
 	req := CreateSession{
 		Type:     CreateSessionRequest,
 		Username: username,
 	}
-	return c.SendRequest(req)
+	return c.RequestContext(ctx, req)
+}
+
+// PostAuthMessageResponse sends a response to an auth message and returns the next one
+// Info and error messages must be acknowledged with a nil response
+func (c *Client) PostAuthMessageResponse(response *string) (interface{}, error) {
+	return c.PostAuthMessageResponseContext(context.Background(), response)
 }
 
-// PostAuthMessageResponse sends a response to an auth message
-func (c *Client) PostAuthMessageResponse(response *string) error {
+// PostAuthMessageResponseContext sends a response to an auth message and returns the next one
+func (c *Client) PostAuthMessageResponseContext(ctx context.Context, response *string) (interface{}, error) {
 	req := PostAuthMessageResponse{
 		Type:     PostAuthMessageResponseRequest,
 		Response: response,
 	}
-	return c.SendRequest(req)
+	return c.RequestContext(ctx, req)
+}
+
+// PostSecretResponse answers an auth message with a secret and returns the next one
+func (c *Client) PostSecretResponse(secret []byte) (interface{}, error) {
+	return c.PostSecretResponseContext(context.Background(), secret)
+}
+
+// PostSecretResponseContext answers an auth message with a secret
+// The request is encoded by hand into one buffer that is zeroed after sending,
+// so no string copies of the secret are left behind (json.Marshal would need
+// the secret as a string and leaves its output to the garbage collector)
+func (c *Client) PostSecretResponseContext(ctx context.Context, secret []byte) (interface{}, error) {
+	const prefix = `{"type":"post_auth_message_response","response":"`
+	const suffix = `"}`
+
+	// Worst case every byte is escaped as \u00XX
+	frame := make([]byte, 4, 4+len(prefix)+6*len(secret)+len(suffix))
+	defer clear(frame[:cap(frame)])
+
+	frame = append(frame, prefix...)
+	frame = appendJSONString(frame, secret)
+	frame = append(frame, suffix...)
+	binary.LittleEndian.PutUint32(frame[:4], uint32(len(frame)-4))
+	return c.roundTrip(ctx, frame, PostAuthMessageResponseRequest, c.Timeout)
+}
+
+// appendJSONString appends s escaped as the contents of a JSON string
+func appendJSONString(dst, s []byte) []byte {
+	const hex = "0123456789abcdef"
+	for _, b := range s {
+		switch {
+		case b == '"' || b == '\\':
+			dst = append(dst, '\\', b)
+		case b < 0x20:
+			dst = append(dst, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xf])
+		default:
+			dst = append(dst, b)
+		}
+	}
+	return dst
 }
 
 // StartSession starts the session with the given command and environment
 // Wait for greetd's response to StartSession
 // According to greetd protocol, we must wait for greetd to confirm session start before greeter exits
 func (c *Client) StartSession(cmd []string, env []string) error {
+	return c.StartSessionContext(context.Background(), cmd, env)
+}
+
+// StartSessionContext starts the session and waits for greetd's confirmation
+func (c *Client) StartSessionContext(ctx context.Context, cmd []string, env []string) error {
 	req := StartSession{
 		Type: StartSessionRequest,
 		Cmd:  cmd,
 		Env:  env,
 	}
 
-	// Send the start session request
-	if err := c.SendRequest(req); err != nil {
-		return err
-	}
-
 	// CRITICAL: Wait for greetd to respond with success or error
 	// This ensures greetd has properly initialized the session before greeter exits
 	// Without this, on slow hardware, greeter exits before greetd finishes setup -> infinite loop
-	resp, err := c.ReceiveResponse()
+	resp, err := c.RequestContext(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to receive start session response: %v", err)
+		return fmt.Errorf("failed to receive start session response: %w", err)
 	}
 
 	// Check if session started successfully
@@ -209,7 +482,7 @@ func (c *Client) StartSession(cmd []string, env []string) error {
 	}
 
 	if errResp, ok := resp.(Error); ok {
-		return fmt.Errorf("failed to start session: %s - %s", errResp.ErrorType, errResp.Description)
+		return fmt.Errorf("failed to start session: %w", errResp)
 	}
 
 	return fmt.Errorf("unexpected response to start_session: %T", resp)
@@ -217,19 +490,21 @@ func (c *Client) StartSession(cmd []string, env []string) error {
 
 // CancelSession cancels the current session and waits for greetd's acknowledgment
 func (c *Client) CancelSession() error {
+	return c.CancelSessionContext(context.Background())
+}
+
+// CancelSessionContext cancels the current session and waits for greetd's acknowledgment
+// If the connection was dropped (timeout, cancellation) the request goes to a fresh
+// connection, where greetd has already discarded the old session
+func (c *Client) CancelSessionContext(ctx context.Context) error {
 	req := CancelSession{
 		Type: CancelSessionRequest,
 	}
 
-	// Send the cancel request
-	if err := c.SendRequest(req); err != nil {
-		return err
-	}
-
-	// Wait for Success response from greetd
-	resp, err := c.ReceiveResponse()
+	// Send the cancel request and wait for Success from greetd
+	resp, err := c.RequestContext(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to receive CancelSession response: %v", err)
+		return fmt.Errorf("failed to receive CancelSession response: %w", err)
 	}
 
 	// Check if we got Success
@@ -0,0 +1,305 @@
+package ipc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nomadcxx/sysc-greet/internal/ipc"
+	"github.com/Nomadcxx/sysc-greet/internal/ipc/greetdtest"
+)
+
+func newClient(t *testing.T, srv *greetdtest.Server) *ipc.Client {
+	t.Helper()
+	t.Setenv("GREETD_SOCK", srv.SocketPath())
+	client, err := ipc.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestNewClientWithoutSocket(t *testing.T) {
+	t.Setenv("GREETD_SOCK", "")
+	if _, err := ipc.NewClient(); err == nil {
+		t.Fatal("expected error when GREETD_SOCK is unset")
+	}
+}
+
+func TestPasswordConversation(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	client := newClient(t, srv)
+
+	resp, err := client.CreateSession("alice")
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	msg, ok := resp.(ipc.AuthMessage)
+	if !ok {
+		t.Fatalf("expected AuthMessage, got %T", resp)
+	}
+	if msg.AuthMessageType != ipc.AuthMessageSecret || msg.AuthMessage != "Password:" {
+		t.Fatalf("unexpected auth message: %+v", msg)
+	}
+
+	password := "hunter2"
+	resp, err = client.PostAuthMessageResponse(&password)
+	if err != nil {
+		t.Fatalf("PostAuthMessageResponse: %v", err)
+	}
+	if _, ok := resp.(ipc.Success); !ok {
+		t.Fatalf("expected Success, got %T", resp)
+	}
+
+	if err := client.StartSession([]string{"sway"}, []string{"XDG_SESSION_TYPE=wayland"}); err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+
+	reqs := srv.Requests()
+	last := reqs[len(reqs)-1]
+	if last.Type != ipc.StartSessionRequest || len(last.Cmd) != 1 || last.Cmd[0] != "sway" {
+		t.Fatalf("unexpected start_session request: %+v", last)
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	client := newClient(t, srv)
+
+	if _, err := client.CreateSession("alice"); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	wrong := "letmein"
+	resp, err := client.PostAuthMessageResponse(&wrong)
+	if err != nil {
+		t.Fatalf("PostAuthMessageResponse: %v", err)
+	}
+	errResp, ok := resp.(ipc.Error)
+	if !ok {
+		t.Fatalf("expected Error, got %T", resp)
+	}
+	if errResp.ErrorType != "auth_error" {
+		t.Fatalf("expected auth_error, got %q", errResp.ErrorType)
+	}
+}
+
+func TestPostSecretResponse(t *testing.T) {
+	secrets := []string{
+		"hunter2",
+		`quo"te\back\\slash`,
+		"tab\tnew\nline\x01",
+		"pässwörd ✓",
+		"",
+	}
+
+	for _, secret := range secrets {
+		srv := greetdtest.NewServer(t)
+		srv.AddUser("alice", greetdtest.Secret("Password:", secret))
+		client := newClient(t, srv)
+
+		if _, err := client.CreateSession("alice"); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+		resp, err := client.PostSecretResponse([]byte(secret))
+		if err != nil {
+			t.Fatalf("PostSecretResponse: %v", err)
+		}
+		if _, ok := resp.(ipc.Success); !ok {
+			t.Errorf("%q: expected Success, got %+v", secret, resp)
+		}
+
+		reqs := srv.Requests()
+		last := reqs[len(reqs)-1]
+		if last.Type != ipc.PostAuthMessageResponseRequest || last.Response == nil || *last.Response != secret {
+			t.Errorf("%q: unexpected request %+v", secret, last)
+		}
+	}
+}
+
+func TestStartSessionFailure(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("kiosk")
+	srv.FailStartSession("error", "exec failed")
+	client := newClient(t, srv)
+
+	resp, err := client.CreateSession("kiosk")
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if _, ok := resp.(ipc.Success); !ok {
+		t.Fatalf("expected Success for passwordless user, got %T", resp)
+	}
+
+	if err := client.StartSession([]string{"cage"}, nil); err == nil {
+		t.Fatal("expected StartSession to fail")
+	}
+}
+
+func TestCancelSession(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	client := newClient(t, srv)
+
+	if _, err := client.CreateSession("alice"); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := client.CancelSession(); err != nil {
+		t.Fatalf("CancelSession: %v", err)
+	}
+
+	// A new session can be created after cancelling
+	resp, err := client.CreateSession("alice")
+	if err != nil {
+		t.Fatalf("CreateSession after cancel: %v", err)
+	}
+	if _, ok := resp.(ipc.AuthMessage); !ok {
+		t.Fatalf("expected AuthMessage after cancel, got %T", resp)
+	}
+}
+
+func TestResponseTimeout(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	srv.SetResponseDelay(time.Second)
+	client := newClient(t, srv)
+	client.Timeout = 50 * time.Millisecond
+
+	_, err := client.CreateSession("alice")
+	if !errors.Is(err, ipc.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+
+	// The next request goes to a fresh connection without a stale response in the way
+	srv.SetResponseDelay(0)
+	resp, err := client.CreateSession("alice")
+	if err != nil {
+		t.Fatalf("CreateSession after timeout: %v", err)
+	}
+	if _, ok := resp.(ipc.AuthMessage); !ok {
+		t.Fatalf("expected AuthMessage after reconnect, got %T", resp)
+	}
+}
+
+func TestNoticeAcknowledgementIsNotTimed(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice",
+		greetdtest.Info("Please touch the device."),
+		greetdtest.Step{Type: ipc.AuthMessageSecret, Message: "Password:", Answer: "hunter2", Delay: 200 * time.Millisecond},
+		greetdtest.Step{Type: ipc.AuthMessageSecret, Message: "PIN:", Answer: "1234", Delay: 200 * time.Millisecond},
+	)
+	client := newClient(t, srv)
+	client.Timeout = 50 * time.Millisecond
+
+	if _, err := client.CreateSession("alice"); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	// PAM waits for the user after the notice, which is not greetd being slow
+	resp, err := client.PostAuthMessageResponse(nil)
+	if err != nil {
+		t.Fatalf("acknowledging the notice: %v", err)
+	}
+	if msg, ok := resp.(ipc.AuthMessage); !ok || msg.AuthMessage != "Password:" {
+		t.Fatalf("expected the password prompt, got %+v", resp)
+	}
+
+	// Answers to prompts are still bounded
+	if _, err := client.PostSecretResponse([]byte("hunter2")); !errors.Is(err, ipc.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestSendRequestReceiveResponse(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	client := newClient(t, srv)
+
+	if _, err := client.ReceiveResponse(); err == nil {
+		t.Fatal("expected an error without a request")
+	}
+	if err := client.SendRequest(ipc.CreateSession{Type: ipc.CreateSessionRequest, Username: "alice"}); err != nil {
+		t.Fatalf("SendRequest: %v", err)
+	}
+	resp, err := client.ReceiveResponse()
+	if err != nil {
+		t.Fatalf("ReceiveResponse: %v", err)
+	}
+	if msg, ok := resp.(ipc.AuthMessage); !ok || msg.AuthMessage != "Password:" {
+		t.Fatalf("expected the password prompt, got %+v", resp)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Step{Type: ipc.AuthMessageSecret, Message: "Password:", Delay: time.Minute})
+	client := newClient(t, srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := client.CreateSessionContext(ctx, "alice")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	// CancelSession reconnects instead of reading the abandoned response
+	if err := client.CancelSession(); err != nil {
+		t.Fatalf("CancelSession after cancellation: %v", err)
+	}
+}
+
+func TestReconnectAfterDrop(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("kiosk")
+	client := newClient(t, srv)
+
+	srv.DropConnections()
+
+	// The write fails on the dropped socket and is retried on a fresh connection
+	resp, err := client.CreateSession("kiosk")
+	if err != nil {
+		t.Fatalf("CreateSession after drop: %v", err)
+	}
+	if _, ok := resp.(ipc.Success); !ok {
+		t.Fatalf("expected Success after reconnect, got %T", resp)
+	}
+}
+
+func TestConnectionLostWhileWaiting(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Step{Type: ipc.AuthMessageSecret, Message: "Password:", Delay: time.Minute})
+	client := newClient(t, srv)
+
+	time.AfterFunc(50*time.Millisecond, srv.DropConnections)
+	if _, err := client.CreateSession("alice"); !errors.Is(err, ipc.ErrConnectionLost) {
+		t.Fatalf("expected ErrConnectionLost, got %v", err)
+	}
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	srv := greetdtest.NewServer(t)
+	srv.AddUser("alice", greetdtest.Secret("Password:", "hunter2"))
+	srv.SetResponseDelay(20 * time.Millisecond)
+	client := newClient(t, srv)
+
+	// A background cancel racing a new conversation must not take its response
+	for range 5 {
+		done := make(chan error, 1)
+		go func() { done <- client.CancelSession() }()
+		resp, err := client.CreateSession("alice")
+		if err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Fatalf("CancelSession: %v", err)
+		}
+		if _, ok := resp.(ipc.AuthMessage); !ok {
+			t.Fatalf("expected the prompt for alice, got %+v", resp)
+		}
+		client.CancelSession()
+	}
+}
@@ -0,0 +1,359 @@
+// Package greetdtest provides an in-process fake greetd for testing greeters
+// The server listens on a temporary unix socket, speaks the same length-prefixed
+// JSON framing as ipc.Client and follows scripted PAM conversations per user
+package greetdtest
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nomadcxx/sysc-greet/internal/ipc"
+)
+
+// Step is one message of a scripted PAM conversation
+type Step struct {
+	Type    string        // ipc.AuthMessageVisible, Secret, Info or Error
+	Message string        // Prompt or notice text sent to the greeter
+	Answer  string        // Expected response for visible and secret prompts
+	Delay   time.Duration // Wait before sending this step (simulates slow PAM modules)
+}
+
+// Secret returns a masked prompt expecting answer
+func Secret(prompt, answer string) Step {
+	return Step{Type: ipc.AuthMessageSecret, Message: prompt, Answer: answer}
+}
+
+// Visible returns an echoed prompt expecting answer
+func Visible(prompt, answer string) Step {
+	return Step{Type: ipc.AuthMessageVisible, Message: prompt, Answer: answer}
+}
+
+// Info returns an informational notice, acknowledged with a nil response
+func Info(text string) Step {
+	return Step{Type: ipc.AuthMessageInfo, Message: text}
+}
+
+// ErrorMessage returns an error notice, acknowledged with a nil response
+func ErrorMessage(text string) Step {
+	return Step{Type: ipc.AuthMessageError, Message: text}
+}
+
+// Request is a request received by the server
+// Only the fields relevant to Type are set
+type Request struct {
+	Type     ipc.RequestType `json:"type"`
+	Username string          `json:"username,omitempty"`
+	Response *string         `json:"response,omitempty"`
+	Cmd      []string        `json:"cmd,omitempty"`
+	Env      []string        `json:"env,omitempty"`
+}
+
+// user is a scripted account
+type user struct {
+	steps []Step
+	fail  *ipc.Error // Returned instead of Success once all steps are answered
+}
+
+// Server is a scriptable fake greetd
+type Server struct {
+	path     string
+	listener net.Listener
+
+	mu            sync.Mutex
+	users         map[string]user
+	requests      []Request
+	responseDelay time.Duration
+	startErr      *ipc.Error
+	conns         map[net.Conn]struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewServer starts a fake greetd on a socket in a temporary directory
+// The server is closed automatically when the test finishes
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "greetd.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("greetdtest: failed to listen on %s: %v", path, err)
+	}
+
+	s := &Server{
+		path:     path,
+		listener: listener,
+		users:    make(map[string]user),
+		conns:    make(map[net.Conn]struct{}),
+		done:     make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SocketPath returns the path to use as GREETD_SOCK
+func (s *Server) SocketPath() string {
+	return s.path
+}
+
+// AddUser scripts the PAM conversation for username
+// A user without steps is authenticated immediately (pam_permit, passwordless kiosks)
+// Unknown users get a password prompt that always fails
+func (s *Server) AddUser(username string, steps ...Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = user{steps: steps}
+}
+
+// AddFailingUser scripts a conversation that ends with err instead of Success
+// (e.g. an account locked by pam_faillock after the password was accepted)
+func (s *Server) AddFailingUser(username string, err ipc.Error, steps ...Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err.Type = ipc.ErrorResponse
+	s.users[username] = user{steps: steps, fail: &err}
+}
+
+// FailStartSession makes every following start_session request fail
+func (s *Server) FailStartSession(errorType, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.startErr = &ipc.Error{Type: ipc.ErrorResponse, ErrorType: errorType, Description: description}
+}
+
+// SetResponseDelay delays every response by d (simulates a hanging PAM stack)
+func (s *Server) SetResponseDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responseDelay = d
+}
+
+// Requests returns a copy of all requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// RequestTypes returns the type of every request received so far
+func (s *Server) RequestTypes() []ipc.RequestType {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	types := make([]ipc.RequestType, 0, len(s.requests))
+	for _, req := range s.requests {
+		types = append(types, req.Type)
+	}
+	return types
+}
+
+// DropConnections closes every open client connection (simulates greetd going away)
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+		delete(s.conns, conn)
+	}
+}
+
+// Close stops the server and closes all connections
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+	s.listener.Close()
+	s.DropConnections()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// session is the per-connection conversation state
+type session struct {
+	username      string
+	user          user
+	step          int
+	active        bool
+	authenticated bool
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	var sess session
+	for {
+		req, err := readRequest(conn)
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		delay := s.responseDelay
+		s.mu.Unlock()
+
+		resp, stepDelay := s.respond(&sess, req)
+		if delay+stepDelay > 0 {
+			select {
+			case <-time.After(delay + stepDelay):
+			case <-s.done:
+				return
+			}
+		}
+		if err := writeResponse(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+// respond advances the conversation for req and returns the reply
+func (s *Server) respond(sess *session, req Request) (interface{}, time.Duration) {
+	switch req.Type {
+	case ipc.CreateSessionRequest:
+		if sess.active {
+			return newError("error", "a session is already being configured"), 0
+		}
+		s.mu.Lock()
+		u, ok := s.users[req.Username]
+		s.mu.Unlock()
+		if !ok {
+			// Unknown users still get a password prompt, like a real PAM stack
+			u = user{
+				steps: []Step{Secret("Password:", "")},
+				fail:  newError("auth_error", "Authentication failure"),
+			}
+		}
+		*sess = session{username: req.Username, user: u, active: true}
+		return s.next(sess)
+
+	case ipc.PostAuthMessageResponseRequest:
+		if !sess.active || sess.step >= len(sess.user.steps) {
+			return newError("error", "no auth message to respond to"), 0
+		}
+		step := sess.user.steps[sess.step]
+		if step.Type == ipc.AuthMessageVisible || step.Type == ipc.AuthMessageSecret {
+			if req.Response == nil || *req.Response != step.Answer {
+				*sess = session{}
+				return newError("auth_error", "Authentication failure"), 0
+			}
+		}
+		sess.step++
+		return s.next(sess)
+
+	case ipc.StartSessionRequest:
+		if !sess.authenticated {
+			return newError("error", "session not yet authenticated"), 0
+		}
+		s.mu.Lock()
+		startErr := s.startErr
+		s.mu.Unlock()
+		if startErr != nil {
+			return *startErr, 0
+		}
+		*sess = session{}
+		return ipc.Success{Type: ipc.SuccessResponse}, 0
+
+	case ipc.CancelSessionRequest:
+		*sess = session{}
+		return ipc.Success{Type: ipc.SuccessResponse}, 0
+
+	default:
+		return newError("error", "unknown request type"), 0
+	}
+}
+
+// next returns the current step's message, or the final result once all steps are answered
+func (s *Server) next(sess *session) (interface{}, time.Duration) {
+	if sess.step < len(sess.user.steps) {
+		step := sess.user.steps[sess.step]
+		return ipc.AuthMessage{
+			Type:            ipc.AuthMessageResponse,
+			AuthMessageType: step.Type,
+			AuthMessage:     step.Message,
+		}, step.Delay
+	}
+
+	if sess.user.fail != nil {
+		fail := *sess.user.fail
+		*sess = session{}
+		return fail, 0
+	}
+
+	sess.authenticated = true
+	return ipc.Success{Type: ipc.SuccessResponse}, 0
+}
+
+func newError(errorType, description string) *ipc.Error {
+	return &ipc.Error{Type: ipc.ErrorResponse, ErrorType: errorType, Description: description}
+}
+
+// readRequest reads one length-prefixed JSON request
+func readRequest(r io.Reader) (Request, error) {
+	var req Request
+
+	lengthBytes := make([]byte, 4)
+	if _, err := io.ReadFull(r, lengthBytes); err != nil {
+		return req, err
+	}
+
+	data := make([]byte, binary.LittleEndian.Uint32(lengthBytes))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return req, err
+	}
+
+	if err := json.Unmarshal(data, &req); err != nil {
+		return req, err
+	}
+	if req.Type == "" {
+		return req, errors.New("request without type")
+	}
+	return req, nil
+}
+
+// writeResponse writes one length-prefixed JSON response
+func writeResponse(w io.Writer, resp interface{}) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	lengthBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(lengthBytes, uint32(len(data)))
+	if _, err := w.Write(append(lengthBytes, data...)); err != nil {
+		return err
+	}
+	return nil
+}
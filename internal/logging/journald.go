@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// JournalSocket is where systemd-journald accepts native protocol datagrams
+const JournalSocket = "/run/systemd/journal/socket"
+
+// SyslogIdentifier tags greeter entries in the journal (journalctl -t sysc-greet)
+const SyslogIdentifier = "sysc-greet"
+
+// journalField is an encoded FIELD=value pair
+type journalField struct {
+	name  string
+	value string
+}
+
+// JournalHandler writes records to systemd-journald using its native protocol:
+// one datagram per entry holding MESSAGE, PRIORITY, SYSLOG_IDENTIFIER and one
+// upper-case field per attribute (subsystem becomes SUBSYSTEM)
+// https://systemd.io/JOURNAL_NATIVE_PROTOCOL/
+type JournalHandler struct {
+	conn   *net.UnixConn
+	level  slog.Leveler
+	prefix string         // Field name prefix from WithGroup
+	fields []journalField // Fields from WithAttrs
+}
+
+// NewJournalHandler connects to the journal socket at path
+func NewJournalHandler(path string, level slog.Leveler) (*JournalHandler, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to journald: %v", err)
+	}
+	return &JournalHandler{conn: conn, level: level}, nil
+}
+
+func (h *JournalHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *JournalHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	appendJournalField(&buf, "MESSAGE", r.Message)
+	appendJournalField(&buf, "PRIORITY", fmt.Sprint(journalPriority(r.Level)))
+	appendJournalField(&buf, "SYSLOG_IDENTIFIER", SyslogIdentifier)
+	for _, f := range h.fields {
+		appendJournalField(&buf, f.name, f.value)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		for _, f := range flattenAttr(h.prefix, a) {
+			appendJournalField(&buf, f.name, f.value)
+		}
+		return true
+	})
+
+	_, err := h.conn.Write(buf.Bytes())
+	return err
+}
+
+func (h *JournalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.fields = append([]journalField(nil), h.fields...)
+	for _, a := range attrs {
+		clone.fields = append(clone.fields, flattenAttr(h.prefix, a)...)
+	}
+	return &clone
+}
+
+func (h *JournalHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.prefix = h.prefix + name + "_"
+	return &clone
+}
+
+// Close closes the journal socket
+func (h *JournalHandler) Close() error {
+	return h.conn.Close()
+}
+
+// journalPriority maps slog levels to syslog priorities
+func journalPriority(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3 // err
+	case level >= slog.LevelWarn:
+		return 4 // warning
+	case level >= slog.LevelInfo:
+		return 6 // info
+	default:
+		return 7 // debug
+	}
+}
+
+// flattenAttr turns an attribute (and the members of a group) into journal fields
+func flattenAttr(prefix string, a slog.Attr) []journalField {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		var fields []journalField
+		for _, member := range a.Value.Group() {
+			fields = append(fields, flattenAttr(prefix+a.Key+"_", member)...)
+		}
+		return fields
+	}
+	if a.Key == "" {
+		return nil
+	}
+	return []journalField{{name: journalFieldName(prefix + a.Key), value: a.Value.String()}}
+}
+
+// journalFieldName converts a key into a valid journal field name:
+// upper-case letters, digits and underscores, not starting with an underscore or digit
+func journalFieldName(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(key) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	name := strings.TrimLeft(b.String(), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "FIELD_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// appendJournalField encodes one field; values containing newlines use the
+// binary form: NAME\n, little-endian uint64 length, value, \n
+func appendJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,197 @@
+// Package logging is the greeter's leveled, structured logger
+// Every subsystem logs through For(name); Setup picks the sink (a rotated file,
+// the systemd journal or stderr) and redacts secrets and usernames before
+// anything is written
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Sinks for Options.Sink
+const (
+	SinkAuto     = "auto"     // journald when the journal socket exists, the log file otherwise
+	SinkFile     = "file"     // Options.Path, rotated by size
+	SinkJournald = "journald" // systemd journal native protocol
+	SinkStderr   = "stderr"   // Only useful when the greeter is not drawing on the terminal
+	SinkNone     = "none"     // Discard everything
+)
+
+// DefaultPath is the log file used by the file sink when no path is configured
+const DefaultPath = "/var/log/sysc-greet/greeter.log"
+
+// Options configures Setup
+type Options struct {
+	Level        string // "debug", "info", "warn" (default) or "error"
+	Sink         string // One of the Sink constants (default SinkAuto)
+	Path         string // Log file for SinkFile (default DefaultPath, then the state dir)
+	MaxSize      int64  // Rotate the log file at this many bytes (default 1 MiB)
+	MaxFiles     int    // Rotated files kept next to the log file (default 3)
+	LogUsernames bool   // Log usernames in clear instead of a short hash
+}
+
+// root routes every logger created by For to the handler installed by Setup,
+// so package-level loggers created before Setup still reach the configured sink
+var root = &switchHandler{}
+
+func init() {
+	var h slog.Handler = slog.DiscardHandler
+	root.current.Store(&h)
+}
+
+// For returns the logger of a subsystem ("ipc", "wallpaper", "themes", ...)
+func For(subsystem string) *slog.Logger {
+	return slog.New(root).With("subsystem", subsystem)
+}
+
+// ParseLevel converts a level name; an empty name is LevelWarn
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "", "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelWarn, fmt.Errorf("unknown log level %q", name)
+}
+
+// Setup installs the sink described by opts and returns a closer for it
+// On error nothing is installed and the previous sink stays active
+func Setup(opts Options) (io.Closer, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+
+	sink := opts.Sink
+	if sink == "" || sink == SinkAuto {
+		sink = SinkFile
+		if journalAvailable() {
+			sink = SinkJournald
+		}
+	}
+
+	var handler slog.Handler
+	var closer io.Closer = nopCloser{}
+	switch sink {
+	case SinkFile:
+		file, err := openLogFile(opts)
+		if err != nil {
+			return nil, err
+		}
+		handler = slog.NewTextHandler(file, handlerOpts)
+		closer = file
+	case SinkJournald:
+		journal, err := NewJournalHandler(JournalSocket, level)
+		if err != nil {
+			return nil, err
+		}
+		handler = journal
+		closer = journal
+	case SinkStderr:
+		handler = slog.NewTextHandler(os.Stderr, handlerOpts)
+	case SinkNone:
+		handler = slog.DiscardHandler
+	default:
+		return nil, fmt.Errorf("unknown log sink %q", opts.Sink)
+	}
+
+	root.set(NewRedactHandler(handler, opts.LogUsernames))
+	return closer, nil
+}
+
+// openLogFile opens the configured log file, falling back from the default
+// /var/log location to the state directory when the greeter cannot write there
+func openLogFile(opts Options) (*RotatingFile, error) {
+	if opts.Path != "" {
+		return OpenRotatingFile(opts.Path, opts.MaxSize, opts.MaxFiles)
+	}
+	file, err := OpenRotatingFile(DefaultPath, opts.MaxSize, opts.MaxFiles)
+	if err == nil {
+		return file, nil
+	}
+	stateDir, stateErr := StateDir()
+	if stateErr != nil {
+		return nil, err
+	}
+	return OpenRotatingFile(filepath.Join(stateDir, "greeter.log"), opts.MaxSize, opts.MaxFiles)
+}
+
+// StateDir returns $XDG_STATE_HOME/sysc-greet (default ~/.local/state/sysc-greet)
+func StateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "sysc-greet"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %v", err)
+	}
+	return filepath.Join(home, ".local", "state", "sysc-greet"), nil
+}
+
+// journalAvailable reports whether systemd-journald accepts native messages
+func journalAvailable() bool {
+	conn, err := net.Dial("unixgram", JournalSocket)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// switchHandler forwards records to the handler installed last
+// Attributes and groups added with With/WithGroup are replayed on that handler
+type switchHandler struct {
+	current atomic.Pointer[slog.Handler]
+	parent  *switchHandler
+	apply   func(slog.Handler) slog.Handler
+}
+
+func (h *switchHandler) set(handler slog.Handler) {
+	h.current.Store(&handler)
+}
+
+// resolve returns the active handler with this logger's attributes applied
+func (h *switchHandler) resolve() slog.Handler {
+	if h.parent == nil {
+		return *h.current.Load()
+	}
+	return h.apply(h.parent.resolve())
+}
+
+func (h *switchHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.resolve().Enabled(ctx, level)
+}
+
+func (h *switchHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.resolve().Handle(ctx, r)
+}
+
+func (h *switchHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &switchHandler{parent: h, apply: func(next slog.Handler) slog.Handler {
+		return next.WithAttrs(attrs)
+	}}
+}
+
+func (h *switchHandler) WithGroup(name string) slog.Handler {
+	return &switchHandler{parent: h, apply: func(next slog.Handler) slog.Handler {
+		return next.WithGroup(name)
+	}}
+}
@@ -0,0 +1,238 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		logUsernames bool
+		attrs        []any
+		want         []string
+		notWant      []string
+	}{
+		{
+			name:    "secrets",
+			attrs:   []any{"password", "hunter2", "Response", "123456"},
+			want:    []string{"password=" + Redacted, "Response=" + Redacted},
+			notWant: []string{"hunter2", "123456"},
+		},
+		{
+			name:    "usernames hashed",
+			attrs:   []any{"user", "alice"},
+			want:    []string{"user=" + HashUsername("alice")},
+			notWant: []string{"alice"},
+		},
+		{
+			name:         "usernames allowed",
+			logUsernames: true,
+			attrs:        []any{"user", "alice"},
+			want:         []string{"user=alice"},
+		},
+		{
+			name:    "groups",
+			attrs:   []any{slog.Group("auth", "username", "bob", "token", "abc")},
+			want:    []string{"auth.username=" + HashUsername("bob"), "auth.token=" + Redacted},
+			notWant: []string{"bob", "abc"},
+		},
+		{
+			name:  "other attributes",
+			attrs: []any{"session", "sway"},
+			want:  []string{"session=sway"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := NewRedactHandler(slog.NewTextHandler(&buf, nil), tt.logUsernames)
+			slog.New(handler).Info("msg", tt.attrs...)
+
+			out := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output %q does not contain %q", out, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out, notWant) {
+					t.Errorf("output %q leaks %q", out, notWant)
+				}
+			}
+		})
+	}
+
+	// Attributes added with With are redacted too
+	var buf bytes.Buffer
+	slog.New(NewRedactHandler(slog.NewTextHandler(&buf, nil), false)).With("user", "carol").Info("msg")
+	if strings.Contains(buf.String(), "carol") {
+		t.Fatalf("With attribute leaked: %q", buf.String())
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "greeter.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	want := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for file, content := range want {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", filepath.Base(file), data, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 rotated files")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("log file mode = %o, want 600", perm)
+	}
+}
+
+func TestRotatingFileRefusesSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := os.WriteFile(target, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "greeter.log")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := OpenRotatingFile(link, 0, 0); err == nil {
+		f.Close()
+		t.Fatal("expected opening a symlinked log file to fail")
+	}
+}
+
+func TestJournalHandler(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram: %v", err)
+	}
+	defer conn.Close()
+
+	handler, err := NewJournalHandler(socket, slog.LevelDebug)
+	if err != nil {
+		t.Fatalf("NewJournalHandler: %v", err)
+	}
+	defer handler.Close()
+
+	slog.New(handler).With("subsystem", "ipc").Warn("greetd timed out", "op", "read", "detail", "line one\nline two")
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	got := buf[:n]
+
+	for _, field := range []string{
+		"MESSAGE=greetd timed out\n",
+		"PRIORITY=4\n",
+		"SYSLOG_IDENTIFIER=sysc-greet\n",
+		"SUBSYSTEM=ipc\n",
+		"OP=read\n",
+	} {
+		if !bytes.Contains(got, []byte(field)) {
+			t.Errorf("datagram %q does not contain %q", got, field)
+		}
+	}
+
+	// Multi-line values use the length-prefixed binary form
+	value := "line one\nline two"
+	var binaryField bytes.Buffer
+	binaryField.WriteString("DETAIL\n")
+	binary.Write(&binaryField, binary.LittleEndian, uint64(len(value)))
+	binaryField.WriteString(value + "\n")
+	if !bytes.Contains(got, binaryField.Bytes()) {
+		t.Errorf("datagram %q does not contain binary DETAIL field", got)
+	}
+}
+
+func TestJournalFieldName(t *testing.T) {
+	tests := map[string]string{
+		"subsystem":  "SUBSYSTEM",
+		"error_type": "ERROR_TYPE",
+		"auth.user":  "AUTH_USER",
+		"_private":   "PRIVATE",
+		"2fa":        "FIELD_2FA",
+		"caps-lock":  "CAPS_LOCK",
+		"":           "FIELD_",
+	}
+	for key, want := range tests {
+		if got := journalFieldName(key); got != want {
+			t.Errorf("journalFieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestSetupRoutesExistingLoggers(t *testing.T) {
+	logger := For("themes") // Created before Setup, like package-level loggers
+
+	path := filepath.Join(t.TempDir(), "greeter.log")
+	closer, err := Setup(Options{Level: "info", Sink: SinkFile, Path: path})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	t.Cleanup(func() {
+		Setup(Options{Sink: SinkNone})
+		closer.Close()
+	})
+
+	logger.Debug("hidden")
+	logger.Info("loaded theme", "user", "alice")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	if strings.Contains(out, "hidden") {
+		t.Errorf("debug record written at info level: %q", out)
+	}
+	if !strings.Contains(out, "subsystem=themes") || !strings.Contains(out, `msg="loaded theme"`) {
+		t.Errorf("unexpected log output %q", out)
+	}
+	if strings.Contains(out, "alice") {
+		t.Errorf("username leaked: %q", out)
+	}
+
+	if _, err := Setup(Options{Sink: "syslog"}); err == nil {
+		t.Error("expected an error for an unknown sink")
+	}
+	if _, err := Setup(Options{Level: "loud"}); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
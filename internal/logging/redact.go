@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+)
+
+// Redacted replaces the value of secret attributes
+const Redacted = "[REDACTED]"
+
+// secretKeys are attribute keys whose values are never written
+var secretKeys = map[string]bool{
+	"password": true,
+	"passwd":   true,
+	"secret":   true,
+	"response": true, // Answers to PAM prompts
+	"token":    true,
+	"otp":      true,
+	"pin":      true,
+}
+
+// userKeys are attribute keys holding usernames
+var userKeys = map[string]bool{
+	"user":     true,
+	"username": true,
+}
+
+// HashUsername returns a short, stable pseudonym for a username so repeated
+// attempts can be correlated without writing the name itself
+func HashUsername(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return "user-" + hex.EncodeToString(sum[:4])
+}
+
+// RedactHandler scrubs secrets (and, unless allowed, usernames) from attributes
+type RedactHandler struct {
+	next         slog.Handler
+	logUsernames bool
+}
+
+// NewRedactHandler wraps next
+func NewRedactHandler(next slog.Handler, logUsernames bool) *RedactHandler {
+	return &RedactHandler{next: next, logUsernames: logUsernames}
+}
+
+func (h *RedactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	clean := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(h.redact(a))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+func (h *RedactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = h.redact(a)
+	}
+	return &RedactHandler{next: h.next.WithAttrs(clean), logUsernames: h.logUsernames}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{next: h.next.WithGroup(name), logUsernames: h.logUsernames}
+}
+
+// redact returns a with secret or identifying values replaced
+func (h *RedactHandler) redact(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	key := strings.ToLower(a.Key)
+
+	switch {
+	case a.Value.Kind() == slog.KindGroup:
+		group := a.Value.Group()
+		clean := make([]slog.Attr, len(group))
+		for i, member := range group {
+			clean[i] = h.redact(member)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(clean...)}
+	case secretKeys[key]:
+		return slog.String(a.Key, Redacted)
+	case userKeys[key] && !h.logUsernames:
+		return slog.String(a.Key, HashUsername(a.Value.String()))
+	}
+	return a
+}
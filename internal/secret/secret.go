@@ -0,0 +1,133 @@
+// Package secret keeps passwords in memory that can be wiped
+// A Buffer is a fixed-size byte slice that is locked into RAM (so it is never
+// swapped out) when the system allows it, and zeroed as soon as it is no longer
+// needed. Unlike a Go string, its contents are never copied behind our back
+package secret
+
+import (
+	"crypto/subtle"
+	"os"
+	"runtime"
+	"syscall"
+	"unicode/utf8"
+)
+
+// MaxSize is the longest secret a Buffer holds, PAM's PAM_MAX_RESP_SIZE
+const MaxSize = 512
+
+// Buffer is a mutable, wipeable secret
+// The zero value is not usable; create buffers with New
+type Buffer struct {
+	mem     []byte // Fixed allocation, never grown (growing would leave copies behind)
+	mapping []byte // Pages holding mem, nil when mem is on the Go heap
+	n       int    // Bytes in use
+	locked  bool   // mapping is mlocked
+}
+
+// New allocates an empty buffer and locks it into memory if possible
+// Locking fails without CAP_IPC_LOCK once RLIMIT_MEMLOCK is used up; the buffer
+// still works, it may just be swapped out
+// CHANGED 2026-10-16 - Each buffer has pages of its own, mapped outside the Go
+// heap. Locks are per page and not counted, so unlocking a heap slice could
+// unlock another live buffer on the same page
+func New() *Buffer {
+	size := (MaxSize + os.Getpagesize() - 1) &^ (os.Getpagesize() - 1)
+	mapping, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANONYMOUS)
+	if err != nil {
+		return &Buffer{mem: make([]byte, MaxSize)}
+	}
+	b := &Buffer{mem: mapping[:MaxSize], mapping: mapping}
+	b.locked = syscall.Mlock(mapping) == nil
+	// A buffer that is only wiped must not leak its mapping
+	runtime.SetFinalizer(b, (*Buffer).Destroy)
+	return b
+}
+
+// Bytes returns the secret without copying it
+// The slice aliases the buffer: it is only valid until the next change or Wipe
+func (b *Buffer) Bytes() []byte {
+	return b.mem[:b.n]
+}
+
+// Len returns the length of the secret in bytes
+func (b *Buffer) Len() int {
+	return b.n
+}
+
+// RuneCount returns the number of characters in the secret
+func (b *Buffer) RuneCount() int {
+	return utf8.RuneCount(b.Bytes())
+}
+
+// Locked reports whether the buffer is locked into memory
+func (b *Buffer) Locked() bool {
+	return b.locked
+}
+
+// AppendRune adds r to the end of the secret
+// Returns false (leaving the secret unchanged) when the buffer is full
+func (b *Buffer) AppendRune(r rune) bool {
+	if b.n+utf8.RuneLen(r) > len(b.mem) {
+		return false
+	}
+	b.n += utf8.EncodeRune(b.mem[b.n:], r)
+	return true
+}
+
+// Append adds p to the end of the secret
+// Returns false (leaving the secret unchanged) when p does not fit
+func (b *Buffer) Append(p []byte) bool {
+	if b.n+len(p) > len(b.mem) {
+		return false
+	}
+	b.n += copy(b.mem[b.n:], p)
+	return true
+}
+
+// DeleteLastRune removes the last character
+func (b *Buffer) DeleteLastRune() {
+	if b.n == 0 {
+		return
+	}
+	_, size := utf8.DecodeLastRune(b.Bytes())
+	clear(b.mem[b.n-size : b.n])
+	b.n -= size
+}
+
+// Equal reports whether b and o hold the same secret, in constant time
+func (b *Buffer) Equal(o *Buffer) bool {
+	return subtle.ConstantTimeCompare(b.Bytes(), o.Bytes()) == 1
+}
+
+// Clone returns a new buffer holding the same secret
+func (b *Buffer) Clone() *Buffer {
+	c := New()
+	c.Append(b.Bytes())
+	return c
+}
+
+// Wipe zeroes the whole buffer and empties it
+func (b *Buffer) Wipe() {
+	if b == nil {
+		return
+	}
+	clear(b.mem)
+	b.n = 0
+}
+
+// Destroy wipes the buffer and releases its memory
+// A destroyed buffer is empty and holds nothing; nil buffers are ignored
+func (b *Buffer) Destroy() {
+	if b == nil {
+		return
+	}
+	b.Wipe()
+	if b.mapping != nil {
+		// Unmapping also unlocks the pages, which belong to this buffer alone
+		syscall.Munmap(b.mapping)
+		b.mapping = nil
+		runtime.SetFinalizer(b, nil)
+	}
+	b.mem = nil
+	b.locked = false
+}
@@ -0,0 +1,131 @@
+package secret
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestBufferEditing(t *testing.T) {
+	tests := []struct {
+		name  string
+		edit  func(b *Buffer)
+		want  string
+		runes int
+	}{
+		{"append runes", func(b *Buffer) {
+			for _, r := range "hunter2" {
+				b.AppendRune(r)
+			}
+		}, "hunter2", 7},
+		{"multi-byte", func(b *Buffer) {
+			for _, r := range "pässwörd" {
+				b.AppendRune(r)
+			}
+		}, "pässwörd", 8},
+		{"delete last rune", func(b *Buffer) {
+			b.Append([]byte("naïve"))
+			b.DeleteLastRune()
+			b.DeleteLastRune()
+		}, "naï", 3},
+		{"delete from empty", func(b *Buffer) {
+			b.DeleteLastRune()
+		}, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New()
+			defer b.Destroy()
+			tt.edit(b)
+			if got := string(b.Bytes()); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			if b.RuneCount() != tt.runes {
+				t.Errorf("expected %d runes, got %d", tt.runes, b.RuneCount())
+			}
+		})
+	}
+}
+
+func TestBufferLimit(t *testing.T) {
+	b := New()
+	defer b.Destroy()
+	if !b.Append([]byte(strings.Repeat("a", MaxSize-1))) {
+		t.Fatal("expected MaxSize-1 bytes to fit")
+	}
+	if b.AppendRune('é') {
+		t.Fatal("expected a two-byte rune not to fit")
+	}
+	if !b.AppendRune('a') || b.Len() != MaxSize {
+		t.Fatalf("expected buffer to fill up to MaxSize, got %d", b.Len())
+	}
+	if b.Append([]byte("a")) {
+		t.Fatal("expected append to a full buffer to fail")
+	}
+}
+
+func TestWipeZeroesMemory(t *testing.T) {
+	b := New()
+	b.Append([]byte("hunter2"))
+	b.DeleteLastRune()
+	// The deleted byte must not linger past the end of the secret
+	if b.mem[6] != 0 {
+		t.Fatalf("deleted byte left in memory: %q", b.mem[6])
+	}
+
+	mem := b.mem
+	b.Wipe()
+	if b.Len() != 0 {
+		t.Fatalf("expected empty buffer, got %d bytes", b.Len())
+	}
+	if !bytes.Equal(mem, make([]byte, MaxSize)) {
+		t.Fatal("expected memory to be zeroed")
+	}
+
+	b.Append([]byte("again"))
+	b.Destroy()
+	if b.Len() != 0 || b.Locked() || b.Append([]byte("x")) {
+		t.Fatal("expected Destroy to empty and release the buffer")
+	}
+	b.Destroy()
+
+	var nilBuf *Buffer
+	nilBuf.Wipe()
+	nilBuf.Destroy()
+}
+
+func TestCloneAndEqual(t *testing.T) {
+	a := New()
+	a.Append([]byte("hunter2"))
+	c := a.Clone()
+	if !a.Equal(c) {
+		t.Fatal("expected clone to equal the original")
+	}
+	a.Wipe()
+	if string(c.Bytes()) != "hunter2" {
+		t.Fatal("expected clone to be independent of the original")
+	}
+	if a.Equal(c) {
+		t.Fatal("expected wiped buffer to differ")
+	}
+}
+
+func TestBuffersDoNotSharePages(t *testing.T) {
+	page := uintptr(os.Getpagesize())
+	a, b := New(), New()
+	defer b.Destroy()
+	pageOf := func(x *Buffer) uintptr { return uintptr(unsafe.Pointer(&x.mem[0])) / page }
+	if pageOf(a) == pageOf(b) {
+		t.Fatal("expected each buffer on a page of its own")
+	}
+
+	// Destroying one buffer leaves the other locked and usable
+	locked := b.Locked()
+	a.Destroy()
+	if b.Locked() != locked || !b.Append([]byte("hunter2")) || string(b.Bytes()) != "hunter2" {
+		t.Fatalf("expected the other buffer to be untouched, got %q", b.Bytes())
+	}
+}
@@ -0,0 +1,70 @@
+package sessions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SplitCommand splits a command typed in the greeter into argv the way a POSIX
+// shell splits words: 'single quotes' are literal, "double quotes" allow the
+// escapes \" \\ \$ \` and a backslash outside quotes escapes the next character.
+// An unquoted ~ at the start of a word, alone or before a /, becomes home when
+// home is not empty. Nothing else is expanded: no variables, globs or pipes
+func SplitCommand(line, home string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inWord := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			if i+1 == len(line) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			i++
+			cur.WriteByte(line[i])
+			inWord = true
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			cur.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			closed := false
+			for i++; i < len(line); i++ {
+				if line[i] == '"' {
+					closed = true
+					break
+				}
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\"\\$`", line[i+1]) >= 0 {
+					i++
+				}
+				cur.WriteByte(line[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case c == '~' && !inWord && home != "" && (i+1 == len(line) || strings.IndexByte("/ \t\n", line[i+1]) >= 0):
+			cur.WriteString(home)
+			inWord = true
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,58 @@
+package sessions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	const home = "/home/alice"
+
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "zsh", want: []string{"zsh"}},
+		{line: "  dbus-run-session   sway -d ", want: []string{"dbus-run-session", "sway", "-d"}},
+		{line: "startx ~/.xinitrc-debug", want: []string{"startx", "/home/alice/.xinitrc-debug"}},
+		{line: "cd ~", want: []string{"cd", "/home/alice"}},
+		{line: "echo a~b ~bob '~/x' \\~/y", want: []string{"echo", "a~b", "~bob", "~/x", "~/y"}},
+		{line: `sh -c 'exec "$HOME/.xsession"'`, want: []string{"sh", "-c", `exec "$HOME/.xsession"`}},
+		{line: `wm "a b" "\"q\"" "\$VAR" "\n"`, want: []string{"wm", "a b", `"q"`, "$VAR", `\n`}},
+		{line: `wm --title="my wm"x`, want: []string{"wm", "--title=my wmx"}},
+		{line: `wm a\ b ''`, want: []string{"wm", "a b", ""}},
+		{line: "", want: nil},
+		{line: `wm 'open`, wantErr: true},
+		{line: `wm "open`, wantErr: true},
+		{line: `wm \`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := SplitCommand(tt.line, home)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("SplitCommand(%q) = %q, expected an error", tt.line, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SplitCommand(%q): %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitCommand(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	if got, _ := SplitCommand("startx ~/.xinitrc", ""); !reflect.DeepEqual(got, []string{"startx", "~/.xinitrc"}) {
+		t.Errorf("expected ~ to be kept without a home directory, got %q", got)
+	}
+}
+
+func TestTTYEnvironment(t *testing.T) {
+	s := Session{Name: "Shell", Exec: "bash -l", Type: "TTY"}
+	if got := s.Environment(); !reflect.DeepEqual(got, []string{"XDG_SESSION_TYPE=tty"}) {
+		t.Fatalf("Environment() = %q", got)
+	}
+}
@@ -0,0 +1,324 @@
+package sessions
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// desktop.go - freedesktop Desktop Entry parser
+// Implements the parts of the Desktop Entry Specification session files use:
+// groups, comments, escape sequences, localized keys, string lists and Exec quoting
+// https://specifications.freedesktop.org/desktop-entry-spec/latest/
+
+// DesktopEntryGroup is the main group of a desktop file
+// Keys in other groups (e.g. [Desktop Action logout]) never override it
+const DesktopEntryGroup = "Desktop Entry"
+
+// DesktopFile is a parsed desktop file: group name -> key -> raw value
+// Localized keys are stored verbatim, e.g. "Name[de]"
+type DesktopFile struct {
+	Groups map[string]map[string]string
+}
+
+// SyntaxError is a line of a desktop file that cannot be parsed
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// ParseDesktopFile reads a desktop file from r
+// Syntax errors are returned as *SyntaxError
+func ParseDesktopFile(r io.Reader) (*DesktopFile, error) {
+	f := &DesktopFile{Groups: make(map[string]map[string]string)}
+
+	var group map[string]string
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, &SyntaxError{lineNo, fmt.Sprintf("malformed group header %q", line)}
+			}
+			name := line[1 : len(line)-1]
+			if _, exists := f.Groups[name]; exists {
+				return nil, &SyntaxError{lineNo, fmt.Sprintf("duplicate group [%s]", name)}
+			}
+			group = make(map[string]string)
+			f.Groups[name] = group
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, &SyntaxError{lineNo, fmt.Sprintf("expected key=value, got %q", line)}
+		}
+		if group == nil {
+			return nil, &SyntaxError{lineNo, fmt.Sprintf("key %q outside of a group", strings.TrimSpace(key))}
+		}
+		key = strings.TrimSpace(key)
+		if _, exists := group[key]; !exists {
+			// First occurrence wins, like GLib's key file parser
+			group[key] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Value returns the raw value of key in group
+func (f *DesktopFile) Value(group, key string) (string, bool) {
+	g, ok := f.Groups[group]
+	if !ok {
+		return "", false
+	}
+	v, ok := g[key]
+	return v, ok
+}
+
+// Text returns the unescaped value of key in the Desktop Entry group
+func (f *DesktopFile) Text(key string) string {
+	v, _ := f.Value(DesktopEntryGroup, key)
+	return unescapeValue(v)
+}
+
+// LocaleString returns the value of key for locale, falling back as the spec
+// describes: lang_COUNTRY@MODIFIER, lang_COUNTRY, lang@MODIFIER, lang, then key
+func (f *DesktopFile) LocaleString(key, locale string) string {
+	for _, candidate := range localeCandidates(locale) {
+		if v, ok := f.Value(DesktopEntryGroup, key+"["+candidate+"]"); ok {
+			return unescapeValue(v)
+		}
+	}
+	return f.Text(key)
+}
+
+// Bool returns the boolean value of key ("true" or "false")
+func (f *DesktopFile) Bool(key string) bool {
+	v, _ := f.Value(DesktopEntryGroup, key)
+	return v == "true"
+}
+
+// Strings returns the ;-separated list value of key
+func (f *DesktopFile) Strings(key string) []string {
+	v, _ := f.Value(DesktopEntryGroup, key)
+	return splitList(v)
+}
+
+// CurrentLocale returns the message locale from the environment (LC_ALL, LC_MESSAGES, LANG)
+func CurrentLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// localeCandidates returns the localized key suffixes to try for locale, most specific first
+// The encoding part (".UTF-8") is ignored; "C" and "POSIX" have no translations
+func localeCandidates(locale string) []string {
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return nil
+	}
+
+	var modifier string
+	if i := strings.Index(locale, "@"); i >= 0 {
+		locale, modifier = locale[:i], locale[i+1:]
+	}
+	if i := strings.Index(locale, "."); i >= 0 {
+		locale = locale[:i]
+	}
+	lang, country, hasCountry := strings.Cut(locale, "_")
+
+	var candidates []string
+	if hasCountry && modifier != "" {
+		candidates = append(candidates, lang+"_"+country+"@"+modifier)
+	}
+	if hasCountry {
+		candidates = append(candidates, lang+"_"+country)
+	}
+	if modifier != "" {
+		candidates = append(candidates, lang+"@"+modifier)
+	}
+	return append(candidates, lang)
+}
+
+// unescapeValue resolves the \s \n \t \r and \\ escapes of string values
+func unescapeValue(v string) string {
+	if !strings.Contains(v, `\`) {
+		return v
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' || i+1 == len(v) {
+			b.WriteByte(v[i])
+			continue
+		}
+		i++
+		switch v[i] {
+		case 's':
+			b.WriteByte(' ')
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '\\':
+			b.WriteByte('\\')
+		default:
+			// Unknown escapes are kept as-is
+			b.WriteByte('\\')
+			b.WriteByte(v[i])
+		}
+	}
+	return b.String()
+}
+
+// splitList splits a ;-separated list, honouring \; and dropping empty items
+func splitList(v string) []string {
+	var items []string
+	var cur strings.Builder
+	for i := 0; i < len(v); i++ {
+		switch {
+		case v[i] == '\\' && i+1 < len(v) && v[i+1] == ';':
+			cur.WriteByte(';')
+			i++
+		case v[i] == '\\' && i+1 < len(v):
+			// Keep other escapes for unescapeValue
+			cur.WriteByte('\\')
+			cur.WriteByte(v[i+1])
+			i++
+		case v[i] == ';':
+			if s := strings.TrimSpace(unescapeValue(cur.String())); s != "" {
+				items = append(items, s)
+			}
+			cur.Reset()
+		default:
+			cur.WriteByte(v[i])
+		}
+	}
+	if s := strings.TrimSpace(unescapeValue(cur.String())); s != "" {
+		items = append(items, s)
+	}
+	return items
+}
+
+// ExecContext supplies the values for the %c, %k and %i field codes
+type ExecContext struct {
+	Name string // Translated Name= (%c)
+	Icon string // Icon= (%i expands to --icon <Icon>)
+	Path string // Location of the desktop file (%k)
+}
+
+// ParseExec splits an (already unescaped) Exec value into argv
+// Double-quoted arguments may contain spaces and the escapes \" \` \$ \\;
+// file and URL field codes expand to nothing because sessions are started without arguments
+func ParseExec(exec string, ctx ExecContext) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg, quoted := false, false
+
+	for i := 0; i < len(exec); i++ {
+		c := exec[i]
+		switch {
+		case quoted && c == '"':
+			quoted = false
+		case quoted && c == '\\':
+			if i+1 == len(exec) {
+				return nil, fmt.Errorf("unterminated escape in Exec")
+			}
+			i++
+			switch exec[i] {
+			case '"', '`', '$', '\\':
+				cur.WriteByte(exec[i])
+			default:
+				return nil, fmt.Errorf("invalid escape \\%c in quoted Exec argument", exec[i])
+			}
+		case quoted:
+			cur.WriteByte(c)
+		case c == '"':
+			quoted, inArg = true, true
+		case c == ' ' || c == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteByte(c)
+			inArg = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in Exec")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+
+	return expandFieldCodes(args, ctx)
+}
+
+// expandFieldCodes replaces %-codes in argv
+func expandFieldCodes(args []string, ctx ExecContext) ([]string, error) {
+	expanded := make([]string, 0, len(args))
+	for _, arg := range args {
+		// Codes that stand alone as an argument may expand to zero or two arguments
+		switch arg {
+		case "%f", "%F", "%u", "%U", "%d", "%D", "%n", "%N", "%v", "%m":
+			continue
+		case "%i":
+			if ctx.Icon != "" {
+				expanded = append(expanded, "--icon", ctx.Icon)
+			}
+			continue
+		}
+
+		if !strings.Contains(arg, "%") {
+			expanded = append(expanded, arg)
+			continue
+		}
+
+		var b strings.Builder
+		for i := 0; i < len(arg); i++ {
+			if arg[i] != '%' {
+				b.WriteByte(arg[i])
+				continue
+			}
+			if i+1 == len(arg) {
+				return nil, fmt.Errorf("dangling %% in Exec argument %q", arg)
+			}
+			i++
+			switch arg[i] {
+			case '%':
+				b.WriteByte('%')
+			case 'c':
+				b.WriteString(ctx.Name)
+			case 'k':
+				b.WriteString(ctx.Path)
+			case 'f', 'F', 'u', 'U', 'd', 'D', 'n', 'N', 'v', 'm', 'i':
+				// Nothing to substitute inside a larger argument
+			default:
+				return nil, fmt.Errorf("unknown field code %%%c in Exec", arg[i])
+			}
+		}
+		expanded = append(expanded, b.String())
+	}
+	return expanded, nil
+}
@@ -0,0 +1,341 @@
+package sessions
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDesktopFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		key     string
+		locale  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "plain value",
+			input: "[Desktop Entry]\nName=Sway\n",
+			key:   "Name",
+			want:  "Sway",
+		},
+		{
+			name:  "spaces around equals",
+			input: "[Desktop Entry]\nName = Sway \n",
+			key:   "Name",
+			want:  "Sway",
+		},
+		{
+			name:  "comments and blank lines",
+			input: "# header\n\n[Desktop Entry]\n# Name=Commented\nName=Sway\n",
+			key:   "Name",
+			want:  "Sway",
+		},
+		{
+			name:  "action group does not override entry",
+			input: "[Desktop Entry]\nName=Plasma\n[Desktop Action logout]\nName=Log out\n",
+			key:   "Name",
+			want:  "Plasma",
+		},
+		{
+			name:  "keys before entry group in other group ignored",
+			input: "[X-Vendor]\nName=Vendor\n[Desktop Entry]\nName=GNOME\n",
+			key:   "Name",
+			want:  "GNOME",
+		},
+		{
+			name:  "escapes",
+			input: "[Desktop Entry]\nComment=Tab\\there\\sand\\nnewline \\\\ backslash\n",
+			key:   "Comment",
+			want:  "Tab\there and\nnewline \\ backslash",
+		},
+		{
+			name:  "first duplicate key wins",
+			input: "[Desktop Entry]\nName=First\nName=Second\n",
+			key:   "Name",
+			want:  "First",
+		},
+		{
+			name:   "exact locale",
+			input:  "[Desktop Entry]\nName=Session\nName[de_DE]=Sitzung DE\nName[de]=Sitzung\n",
+			key:    "Name",
+			locale: "de_DE.UTF-8",
+			want:   "Sitzung DE",
+		},
+		{
+			name:   "language fallback",
+			input:  "[Desktop Entry]\nName=Session\nName[de]=Sitzung\n",
+			key:    "Name",
+			locale: "de_AT.UTF-8",
+			want:   "Sitzung",
+		},
+		{
+			name:   "modifier",
+			input:  "[Desktop Entry]\nName=Session\nName[sr]=Sesija\nName[sr@latin]=Sesija (latin)\n",
+			key:    "Name",
+			locale: "sr_RS.UTF-8@latin",
+			want:   "Sesija (latin)",
+		},
+		{
+			name:   "untranslated locale",
+			input:  "[Desktop Entry]\nName=Session\nName[de]=Sitzung\n",
+			key:    "Name",
+			locale: "fr_FR.UTF-8",
+			want:   "Session",
+		},
+		{
+			name:   "C locale",
+			input:  "[Desktop Entry]\nName=Session\nName[C]=Wrong\n",
+			key:    "Name",
+			locale: "C",
+			want:   "Session",
+		},
+		{
+			name:    "key outside group",
+			input:   "Name=Sway\n",
+			wantErr: true,
+		},
+		{
+			name:    "malformed group header",
+			input:   "[Desktop Entry\nName=Sway\n",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate group",
+			input:   "[Desktop Entry]\nName=A\n[Desktop Entry]\nName=B\n",
+			wantErr: true,
+		},
+		{
+			name:    "line without equals",
+			input:   "[Desktop Entry]\nName\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			df, err := ParseDesktopFile(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDesktopFile: %v", err)
+			}
+			if got := df.LocaleString(tt.key, tt.locale); got != tt.want {
+				t.Fatalf("%s = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrings(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"sway", []string{"sway"}},
+		{"sway;wlroots;", []string{"sway", "wlroots"}},
+		{"KDE;;Plasma", []string{"KDE", "Plasma"}},
+		{`semi\;colon;next`, []string{"semi;colon", "next"}},
+		{`with\sspace`, []string{"with space"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		df, err := ParseDesktopFile(strings.NewReader("[Desktop Entry]\nDesktopNames=" + tt.value + "\n"))
+		if err != nil {
+			t.Fatalf("ParseDesktopFile: %v", err)
+		}
+		if got := df.Strings("DesktopNames"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Strings(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseExec(t *testing.T) {
+	ctx := ExecContext{Name: "Sway", Icon: "sway", Path: "/usr/share/wayland-sessions/sway.desktop"}
+
+	tests := []struct {
+		exec    string
+		want    []string
+		wantErr bool
+	}{
+		{exec: "sway", want: []string{"sway"}},
+		{exec: "  sway   --debug  ", want: []string{"sway", "--debug"}},
+		{exec: `"/opt/my wm/bin/wm" --flag`, want: []string{"/opt/my wm/bin/wm", "--flag"}},
+		{exec: `sh -c "exec \"$HOME/.xsession\""`, want: []string{"sh", "-c", `exec "$HOME/.xsession"`}},
+		{exec: `wm "back\\slash" "\$VAR" "\` + "`" + `tick\` + "`" + `"`, want: []string{"wm", `back\slash`, "$VAR", "`tick`"}},
+		{exec: `wm --prefix="a b"`, want: []string{"wm", "--prefix=a b"}},
+		{exec: "cage -- %U", want: []string{"cage", "--"}},
+		{exec: "wm %f %F %u %d %D %n %N %v %m", want: []string{"wm"}},
+		{exec: "wm %i", want: []string{"wm", "--icon", "sway"}},
+		{exec: "wm --title=%c --file=%k", want: []string{"wm", "--title=Sway", "--file=/usr/share/wayland-sessions/sway.desktop"}},
+		{exec: "wm --progress=100%%", want: []string{"wm", "--progress=100%"}},
+		{exec: `"`, wantErr: true},
+		{exec: `wm "unterminated`, wantErr: true},
+		{exec: `wm "bad \q escape"`, wantErr: true},
+		{exec: "wm %x", wantErr: true},
+		{exec: "wm 100%", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseExec(tt.exec, ctx)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseExec(%q) = %q, expected an error", tt.exec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseExec(%q): %v", tt.exec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseExec(%q) = %q, want %q", tt.exec, got, tt.want)
+		}
+	}
+}
+
+func TestLoadSessionsFrom(t *testing.T) {
+	sessions, err := LoadSessionsFrom([]string{"testdata/share", "testdata/broken", "testdata/missing"}, "de_DE.UTF-8")
+	if err != nil {
+		t.Fatalf("LoadSessionsFrom: %v", err)
+	}
+
+	byID := make(map[string]Session)
+	for _, s := range sessions {
+		byID[s.ID()] = s
+	}
+
+	tests := []struct {
+		id           string
+		name         string
+		sessionType  string
+		command      []string
+		desktopNames []string
+		comment      string
+	}{
+		{
+			id:           "hyprland",
+			name:         "Hyprland (Deutsch)",
+			sessionType:  "Wayland",
+			command:      []string{"Hyprland"},
+			desktopNames: []string{"Hyprland"},
+			comment:      "Ein dynamischer Tiling-Compositor",
+		},
+		{
+			id:           "sway",
+			name:         "Sway",
+			sessionType:  "Wayland",
+			command:      []string{"/usr/bin/sway", "--config", "/etc/sway/greeter config"},
+			desktopNames: []string{"sway", "wlroots"},
+			comment:      "An i3-compatible Wayland compositor",
+		},
+		{
+			id:          "vendor-kiosk",
+			name:        "Vendor Kiosk",
+			sessionType: "Wayland",
+			command:     []string{"cage", "--"},
+		},
+		{
+			id:           "i3",
+			name:         "i3",
+			sessionType:  "X11",
+			command:      []string{"i3"},
+			desktopNames: []string{"i3"},
+			comment:      "improved dynamic tiling window manager",
+		},
+	}
+
+	for _, tt := range tests {
+		s, ok := byID[tt.id]
+		if !ok {
+			t.Errorf("session %q not loaded", tt.id)
+			continue
+		}
+		if s.Name != tt.name || s.Type != tt.sessionType || s.Comment != tt.comment {
+			t.Errorf("%s: got name=%q type=%q comment=%q", tt.id, s.Name, s.Type, s.Comment)
+		}
+		if !reflect.DeepEqual(s.Argv(), tt.command) {
+			t.Errorf("%s: command = %q, want %q", tt.id, s.Argv(), tt.command)
+		}
+		if !reflect.DeepEqual(s.DesktopNames, tt.desktopNames) {
+			t.Errorf("%s: DesktopNames = %q, want %q", tt.id, s.DesktopNames, tt.desktopNames)
+		}
+	}
+
+	// Hidden, NoDisplay, missing TryExec, non-Application types and broken files are skipped
+	if len(sessions) != len(tests) {
+		var ids []string
+		for _, s := range sessions {
+			ids = append(ids, s.ID())
+		}
+		t.Fatalf("expected %d sessions, got %v", len(tests), ids)
+	}
+}
+
+func TestDataDirs(t *testing.T) {
+	t.Setenv("XDG_DATA_DIRS", "")
+	want := []string{"/usr/local/share", "/usr/share", nixSystemShare}
+	if got := DataDirs(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("DataDirs() = %q, want %q", got, want)
+	}
+
+	t.Setenv("XDG_DATA_DIRS", "/opt/share:"+nixSystemShare+"/")
+	want = []string{"/opt/share", nixSystemShare + "/"}
+	if got := DataDirs(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("DataDirs() = %q, want %q", got, want)
+	}
+}
+
+func TestArgvFallback(t *testing.T) {
+	// Sessions cached by older versions only have Exec
+	s := Session{Name: "Sway", Exec: "sway --debug"}
+	if got := s.Argv(); !reflect.DeepEqual(got, []string{"sway", "--debug"}) {
+		t.Fatalf("Argv() = %q", got)
+	}
+}
+
+func TestCheckSessionsFrom(t *testing.T) {
+	sessions, errs := CheckSessionsFrom([]string{"testdata/override", "testdata/broken"}, "")
+
+	problems := make(map[string]string)
+	for _, err := range errs {
+		var fe *FileError
+		if !errors.As(err, &fe) {
+			t.Fatalf("expected a *FileError, got %T: %v", err, err)
+		}
+		problems[filepath.Base(fe.Path)] = fe.Err.Error()
+	}
+	want := map[string]string{
+		"vendor-kiosk.desktop": "unterminated quote in Exec",
+		"bad-quote.desktop":    "unterminated quote in Exec",
+		"no-exec.desktop":      "Name and Exec are required",
+		"no-group.desktop":     "line 1: key \"Name\" outside of a group",
+	}
+	for file, msg := range want {
+		if !strings.Contains(problems[file], msg) {
+			t.Errorf("%s: expected %q, got %q", file, msg, problems[file])
+		}
+	}
+	if len(errs) != len(want) {
+		t.Errorf("expected %d problems, got %v", len(want), errs)
+	}
+
+	var syntax *SyntaxError
+	for _, err := range errs {
+		if errors.As(err, &syntax) && syntax.Line != 1 {
+			t.Errorf("expected the syntax error on line 1, got %d", syntax.Line)
+		}
+	}
+	if len(sessions) != 1 || sessions[0].Name != "Sway (local build)" {
+		t.Errorf("expected only the working override to load, got %+v", sessions)
+	}
+}
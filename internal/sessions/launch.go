@@ -0,0 +1,184 @@
+package sessions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// X11 launch strategies: desktop files in xsessions/ name the session program
+// (i3, startplasma-x11), which needs an X server that greetd does not start
+const (
+	LaunchStartx  = "startx"  // startx with a generated xinitrc (default)
+	LaunchXorg    = "xorg"    // Xorg started by a session script that runs the session once the server is up
+	LaunchWrapper = "wrapper" // Admin-provided command template
+	LaunchDirect  = "direct"  // Exec as is, for sessions that start X themselves
+)
+
+// LaunchOptions controls how a session's command line becomes the command greetd runs
+type LaunchOptions struct {
+	X11     string   // X11 strategy, LaunchStartx when empty
+	Wrapper string   // Template for LaunchWrapper: %s is the session command, %v the VT argument
+	VT      int      // VT for the X server, 0 leaves the choice to the server
+	Args    []string // Extra arguments inserted after the program (e.g. --unsupported-gpu)
+}
+
+// LaunchCommand returns the command greetd should run to start s with argv
+// Wayland and console sessions run argv directly; X11 sessions are wrapped
+// according to opts.X11
+func LaunchCommand(s Session, argv []string, opts LaunchOptions) ([]string, error) {
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("session %s has no command", s.Name)
+	}
+	argv = InsertArgs(argv, opts.Args)
+	if s.Type != "X11" {
+		return argv, nil
+	}
+
+	vt := ""
+	if opts.VT > 0 {
+		vt = "vt" + strconv.Itoa(opts.VT)
+	}
+
+	switch opts.X11 {
+	case "", LaunchStartx:
+		script, err := startxScript(s.Name, argv, vt)
+		if err != nil {
+			return nil, err
+		}
+		return []string{"/bin/sh", "-c", script, "sysc-greet-x11"}, nil
+
+	case LaunchXorg:
+		return append([]string{"/bin/sh", "-c", xorgScript(vt), "sysc-greet-x11"}, argv...), nil
+
+	case LaunchWrapper:
+		return expandWrapper(opts.Wrapper, argv, vt)
+
+	case LaunchDirect:
+		return argv, nil
+	}
+	return nil, fmt.Errorf("unknown X11 launch strategy %q", opts.X11)
+}
+
+// InsertArgs adds args after the program in argv, skipping those already present
+func InsertArgs(argv, args []string) []string {
+	var missing []string
+	for _, arg := range args {
+		present := false
+		for _, existing := range argv[1:] {
+			if existing == arg {
+				present = true
+				break
+			}
+		}
+		if !present {
+			missing = append(missing, arg)
+		}
+	}
+	if len(missing) == 0 {
+		return argv
+	}
+	out := make([]string, 0, len(argv)+len(missing))
+	out = append(out, argv[0])
+	out = append(out, missing...)
+	return append(out, argv[1:]...)
+}
+
+// profileScript loads what a display manager normally sources before an X session
+const profileScript = `for f in /etc/X11/xinit/xinitrc.d/?*.sh; do [ -x "$f" ] && . "$f"; done
+[ -f /etc/xprofile ] && . /etc/xprofile
+[ -f "$HOME/.xprofile" ] && . "$HOME/.xprofile"
+`
+
+// xinitrcEnd ends the here-document holding the xinitrc in startxScript
+const xinitrcEnd = "SYSC_GREET_XINITRC"
+
+// startxScript writes an xinitrc starting argv and runs startx with it
+// CHANGED 2026-10-16 - The script runs as the user logging in, so the xinitrc
+// is theirs and private; it removes itself before exec'ing the session, where
+// one written by the greeter was left in /tmp world-readable on every login
+func startxScript(name string, argv []string, vt string) (string, error) {
+	xinitrc := "#!/bin/sh\n# Generated by sysc-greet to start " + strings.ReplaceAll(name, "\n", " ") + "\n" +
+		"rm -f -- \"$0\"\n" + profileScript + "exec " + ShellQuote(argv) + "\n"
+	if strings.Contains(xinitrc, "\n"+xinitrcEnd+"\n") {
+		return "", fmt.Errorf("session %s cannot be written to an xinitrc", name)
+	}
+	server := "--"
+	if vt != "" {
+		server += " " + vt
+	}
+	return `f=$(mktemp "${TMPDIR:-/tmp}/sysc-greet-xinitrc-XXXXXX") || exit 1
+cat > "$f" <<'` + xinitrcEnd + `'
+` + xinitrc + xinitrcEnd + `
+chmod 700 "$f"
+exec startx "$f" ` + server + ` -keeptty
+`, nil
+}
+
+// xorgScript starts Xorg on the first free display, runs the session given as
+// the script's arguments once the server accepts connections, and stops the
+// server when the session ends
+func xorgScript(vt string) string {
+	return `d=0
+while [ -e "/tmp/.X$d-lock" ] || [ -e "/tmp/.X11-unix/X$d" ]; do d=$((d+1)); done
+export DISPLAY=":$d"
+export XAUTHORITY="$HOME/.Xauthority"
+touch "$XAUTHORITY"
+xauth -q -f "$XAUTHORITY" add "$DISPLAY" . "$(mcookie)"
+Xorg "$DISPLAY" ` + vt + ` -keeptty -nolisten tcp -auth "$XAUTHORITY" &
+server=$!
+i=0
+while [ ! -S "/tmp/.X11-unix/X$d" ]; do
+	kill -0 "$server" 2>/dev/null || exit 1
+	i=$((i+1))
+	if [ "$i" -gt 100 ]; then kill "$server"; exit 1; fi
+	sleep 0.1
+done
+` + profileScript + `"$@"
+status=$?
+kill "$server"
+wait "$server"
+exit "$status"
+`
+}
+
+// expandWrapper fills in a wrapper template
+// A %s or %v word is replaced by the session's words or the VT argument (none
+// when unknown); inside a larger word they expand to shell-quoted text
+func expandWrapper(template string, argv []string, vt string) ([]string, error) {
+	words, err := SplitCommand(template, "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid X11 wrapper %q: %w", template, err)
+	}
+	if !strings.Contains(template, "%s") {
+		return nil, fmt.Errorf("X11 wrapper %q has no %%s for the session command", template)
+	}
+
+	var cmd []string
+	for _, word := range words {
+		switch word {
+		case "%s":
+			cmd = append(cmd, argv...)
+		case "%v":
+			if vt != "" {
+				cmd = append(cmd, vt)
+			}
+		default:
+			cmd = append(cmd, strings.NewReplacer("%%", "%", "%s", ShellQuote(argv), "%v", vt).Replace(word))
+		}
+	}
+	return cmd, nil
+}
+
+// ShellQuote joins argv into a string a POSIX shell splits back into argv
+func ShellQuote(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,+@") == "" {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,137 @@
+package sessions
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLaunchCommand(t *testing.T) {
+	wayland := Session{Name: "Sway", Type: "Wayland"}
+	x11 := Session{Name: "i3", Type: "X11"}
+	tty := Session{Name: "Shell", Type: "TTY"}
+
+	tests := []struct {
+		name    string
+		session Session
+		argv    []string
+		opts    LaunchOptions
+		want    []string
+		wantErr string
+	}{
+		{"wayland runs directly", wayland, []string{"sway"}, LaunchOptions{X11: LaunchXorg}, []string{"sway"}, ""},
+		{"tty runs directly", tty, []string{"bash", "-l"}, LaunchOptions{}, []string{"bash", "-l"}, ""},
+		{"extra args after program", wayland, []string{"/usr/bin/sway", "-d"},
+			LaunchOptions{Args: []string{"--unsupported-gpu"}}, []string{"/usr/bin/sway", "--unsupported-gpu", "-d"}, ""},
+		{"extra args not duplicated", wayland, []string{"sway", "--unsupported-gpu"},
+			LaunchOptions{Args: []string{"--unsupported-gpu"}}, []string{"sway", "--unsupported-gpu"}, ""},
+		{"direct", x11, []string{"i3"}, LaunchOptions{X11: LaunchDirect, VT: 1}, []string{"i3"}, ""},
+		{"wrapper words", x11, []string{"i3", "-c", "my config"},
+			LaunchOptions{X11: LaunchWrapper, Wrapper: "/usr/lib/x11-session %v -- %s", VT: 7},
+			[]string{"/usr/lib/x11-session", "vt7", "--", "i3", "-c", "my config"}, ""},
+		{"wrapper without vt", x11, []string{"i3"},
+			LaunchOptions{X11: LaunchWrapper, Wrapper: "xinit %s -- %v"}, []string{"xinit", "i3", "--"}, ""},
+		{"wrapper inside word", x11, []string{"i3", "-c", "my config"},
+			LaunchOptions{X11: LaunchWrapper, Wrapper: `sh -c "exec xinit %s -- :1 %v" 100%%`, VT: 2},
+			[]string{"sh", "-c", "exec xinit i3 -c 'my config' -- :1 vt2", "100%"}, ""},
+		{"wrapper without %s", x11, []string{"i3"}, LaunchOptions{X11: LaunchWrapper, Wrapper: "xinit"}, nil, "has no %s"},
+		{"unknown strategy", x11, []string{"i3"}, LaunchOptions{X11: "wayback"}, nil, `unknown X11 launch strategy "wayback"`},
+		{"no command", x11, nil, LaunchOptions{}, nil, "has no command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LaunchCommand(tt.session, tt.argv, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LaunchCommand: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("LaunchCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLaunchCommandStartx(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh to run the script")
+	}
+	s := Session{Name: "i3", Type: "X11"}
+	argv := []string{"i3", "-c", "it's mine"}
+
+	cmd, err := LaunchCommand(s, argv, LaunchOptions{VT: 1})
+	if err != nil {
+		t.Fatalf("LaunchCommand: %v", err)
+	}
+	if len(cmd) != 4 || cmd[0] != "/bin/sh" || cmd[1] != "-c" {
+		t.Fatalf("unexpected startx command %q", cmd)
+	}
+
+	// A startx that keeps a copy of the xinitrc and runs it, and an i3 that
+	// prints its arguments
+	bin, tmp, out := t.TempDir(), t.TempDir(), t.TempDir()
+	writeScript := func(name, body string) {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte("#!"+sh+"\n"+body), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeScript("startx", `echo "$@" > "$OUT/startx"; ls -l "$1" > "$OUT/mode"; cp "$1" "$OUT/xinitrc"; exec "$1"`)
+	writeScript("i3", `printf '%s\n' "$@" > "$OUT/i3"`)
+
+	run := exec.Command(sh, append([]string{"-c", cmd[2]}, cmd[3:]...)...)
+	run.Env = []string{"PATH=" + bin + ":/usr/bin:/bin", "TMPDIR=" + tmp, "HOME=" + out, "OUT=" + out}
+	if output, err := run.CombinedOutput(); err != nil {
+		t.Fatalf("running the script: %v\n%s", err, output)
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatalf("expected %s to run: %v", name, err)
+		}
+		return string(data)
+	}
+	if args := strings.Fields(read("startx")); len(args) != 4 || args[1] != "--" || args[2] != "vt1" || args[3] != "-keeptty" {
+		t.Fatalf("unexpected startx arguments %q", args)
+	}
+	if mode := read("mode"); !strings.HasPrefix(mode, "-rwx------") {
+		t.Fatalf("expected a private xinitrc, got %s", mode)
+	}
+	if script := read("xinitrc"); !strings.Contains(script, `. "$HOME/.xprofile"`) {
+		t.Fatalf("expected the xinitrc to source ~/.xprofile:\n%s", script)
+	}
+	if got := strings.Split(strings.TrimSuffix(read("i3"), "\n"), "\n"); !reflect.DeepEqual(got, argv[1:]) {
+		t.Fatalf("xinitrc runs i3 with %q, want %q", got, argv[1:])
+	}
+	if left, _ := os.ReadDir(tmp); len(left) != 0 {
+		t.Fatalf("expected the xinitrc to remove itself, found %v", left)
+	}
+}
+
+func TestLaunchCommandXorg(t *testing.T) {
+	cmd, err := LaunchCommand(Session{Name: "i3", Type: "X11"}, []string{"i3", "-V"}, LaunchOptions{X11: LaunchXorg, VT: 3})
+	if err != nil {
+		t.Fatalf("LaunchCommand: %v", err)
+	}
+	if len(cmd) != 6 || cmd[0] != "/bin/sh" || cmd[1] != "-c" || !reflect.DeepEqual(cmd[4:], []string{"i3", "-V"}) {
+		t.Fatalf("unexpected xorg command %q", cmd)
+	}
+	if !strings.Contains(cmd[2], `Xorg "$DISPLAY" vt3 -keeptty`) {
+		t.Fatalf("expected the script to start Xorg on vt3:\n%s", cmd[2])
+	}
+	if sh, err := exec.LookPath("sh"); err == nil {
+		if out, err := exec.Command(sh, "-n", "-c", cmd[2]).CombinedOutput(); err != nil {
+			t.Fatalf("script does not parse: %v\n%s", err, out)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package sessions
+
+import (
+	"sort"
+	"strings"
+)
+
+// Session list ordering, selected with [sessions] order in the system config
+const (
+	OrderAlphabetical = "alphabetical" // By name (default)
+	OrderFrequency    = "frequency"    // Most often started first
+	OrderPinned       = "pinned"       // Pinned list from config first, the rest by name
+)
+
+// Arrangement describes how to order the session list
+type Arrangement struct {
+	Order     string         // One of the Order* constants
+	Pinned    []string       // Desktop file IDs or names, for OrderPinned
+	Usage     map[string]int // Start counts by Session.Key, for OrderFrequency
+	Favorites []string       // Session.Key values starred by the user, always on top
+}
+
+// Arrange returns the sessions sorted according to a
+// Favorites come first, each group ordered by a.Order. The input is not modified
+func Arrange(list []Session, a Arrangement) []Session {
+	sorted := append([]Session(nil), list...)
+
+	favorite := make(map[string]bool, len(a.Favorites))
+	for _, key := range a.Favorites {
+		favorite[key] = true
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		si, sj := sorted[i], sorted[j]
+		if fi, fj := favorite[si.Key()], favorite[sj.Key()]; fi != fj {
+			return fi
+		}
+
+		switch a.Order {
+		case OrderFrequency:
+			if ui, uj := a.Usage[si.Key()], a.Usage[sj.Key()]; ui != uj {
+				return ui > uj
+			}
+		case OrderPinned:
+			if pi, pj := pinnedRank(si, a.Pinned), pinnedRank(sj, a.Pinned); pi != pj {
+				return pi < pj
+			}
+		}
+		return lessByName(si, sj)
+	})
+	return sorted
+}
+
+// pinnedRank returns the position of s in pinned, or len(pinned) if it is not pinned
+func pinnedRank(s Session, pinned []string) int {
+	for i, name := range pinned {
+		if s.Matches(name) {
+			return i
+		}
+	}
+	return len(pinned)
+}
+
+// lessByName orders case-insensitively by name, Wayland before X11 for equal names
+func lessByName(a, b Session) bool {
+	na, nb := strings.ToLower(a.Name), strings.ToLower(b.Name)
+	if na != nb {
+		return na < nb
+	}
+	return a.Type < b.Type
+}
@@ -0,0 +1,97 @@
+package sessions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadSessionsPrecedence(t *testing.T) {
+	// override comes first in XDG_DATA_DIRS, so its files win
+	sessions, err := LoadSessionsFrom([]string{"testdata/override", "testdata/share", "testdata/share"}, "")
+	if err != nil {
+		t.Fatalf("LoadSessionsFrom: %v", err)
+	}
+
+	byID := make(map[string]Session)
+	for _, s := range sessions {
+		if _, dup := byID[s.Key()]; dup {
+			t.Fatalf("duplicate session %s", s.Key())
+		}
+		byID[s.Key()] = s
+	}
+
+	if s := byID["Wayland:sway"]; s.Name != "Sway (local build)" {
+		t.Errorf("expected override to win for sway, got %q", s.Name)
+	}
+	if _, ok := byID["Wayland:hyprland"]; ok {
+		t.Error("expected Hidden=true override to mask hyprland")
+	}
+	if s := byID["Wayland:vendor-kiosk"]; s.Name != "Vendor Kiosk" {
+		t.Errorf("expected broken override to fall through, got %q", s.Name)
+	}
+	if _, ok := byID["X11:i3"]; !ok {
+		t.Error("expected i3 from the second directory")
+	}
+	if len(sessions) != 3 {
+		t.Errorf("expected 3 sessions, got %d", len(sessions))
+	}
+}
+
+func TestArrange(t *testing.T) {
+	sway := Session{Name: "Sway", Type: "Wayland", FileID: "sway"}
+	hypr := Session{Name: "Hyprland", Type: "Wayland", FileID: "hyprland"}
+	i3 := Session{Name: "i3", Type: "X11", FileID: "i3"}
+	plasmaX := Session{Name: "Plasma", Type: "X11", FileID: "plasmax11"}
+	plasmaW := Session{Name: "Plasma", Type: "Wayland", FileID: "plasma"}
+	list := []Session{sway, i3, plasmaX, hypr, plasmaW}
+
+	tests := []struct {
+		name string
+		a    Arrangement
+		want []Session
+	}{
+		{
+			name: "alphabetical by default",
+			want: []Session{hypr, i3, plasmaW, plasmaX, sway},
+		},
+		{
+			name: "frequency",
+			a: Arrangement{
+				Order: OrderFrequency,
+				Usage: map[string]int{"Wayland:sway": 5, "X11:i3": 2, "Wayland:hyprland": 2},
+			},
+			want: []Session{sway, hypr, i3, plasmaW, plasmaX},
+		},
+		{
+			name: "pinned by ID and name",
+			a:    Arrangement{Order: OrderPinned, Pinned: []string{"i3", "sway", "unknown"}},
+			want: []Session{i3, sway, hypr, plasmaW, plasmaX},
+		},
+		{
+			name: "favorites on top",
+			a: Arrangement{
+				Order:     OrderPinned,
+				Pinned:    []string{"i3"},
+				Favorites: []string{"Wayland:sway", "X11:plasmax11"},
+			},
+			want: []Session{plasmaX, sway, i3, hypr, plasmaW},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Arrange(list, tt.a)
+			if !reflect.DeepEqual(got, tt.want) {
+				var names []string
+				for _, s := range got {
+					names = append(names, s.Key())
+				}
+				t.Fatalf("unexpected order: %v", names)
+			}
+		})
+	}
+
+	if list[0].Name != "Sway" {
+		t.Fatal("Arrange must not modify its input")
+	}
+}
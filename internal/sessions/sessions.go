@@ -1,17 +1,24 @@
 package sessions
 
 import (
-	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
 type Session struct {
-	Name string
-	Exec string
-	Type string // "X11" or "Wayland"
-	Path string
+	Name         string
+	Exec         string
+	Type         string // "X11", "Wayland" or "TTY" (a command run on the console)
+	Path         string
+	DesktopNames []string // DesktopNames= entries, used for XDG_CURRENT_DESKTOP
+	Comment      string   // Localized Comment=
+	Command      []string // Exec= split into argv with field codes expanded
+	FileID       string   // Desktop file ID, e.g. "hyprland" or "vendor-foo" for vendor/foo.desktop
 }
 
 func (s Session) FilterValue() string {
@@ -30,70 +37,251 @@ func (s Session) String() string {
 	return s.Name
 }
 
+// Argv returns the command line to start the session
+// Sessions cached before Command existed fall back to splitting Exec on whitespace
+func (s Session) Argv() []string {
+	if len(s.Command) > 0 {
+		return append([]string(nil), s.Command...)
+	}
+	return strings.Fields(s.Exec)
+}
+
+// Key identifies a session across restarts: type plus desktop file ID, since
+// xsessions/ and wayland-sessions/ may both ship a file with the same ID
+func (s Session) Key() string {
+	return s.Type + ":" + s.ID()
+}
+
+// Matches reports whether a name from config (desktop file ID or session name) refers to s
+func (s Session) Matches(name string) bool {
+	return strings.EqualFold(name, s.ID()) || strings.EqualFold(name, s.Name)
+}
+
+// ID returns the desktop file ID (file name without .desktop), e.g. "hyprland"
+// Falls back to the lowercased name for sessions not backed by a desktop file
+func (s Session) ID() string {
+	if s.FileID != "" {
+		return s.FileID
+	}
+	if s.Path != "" {
+		return strings.TrimSuffix(filepath.Base(s.Path), ".desktop")
+	}
+	return strings.ToLower(strings.ReplaceAll(s.Name, " ", "-"))
+}
+
+// Environment returns the XDG variables describing this session to the
+// programs started inside it (portals pick their backend from these)
+func (s Session) Environment() []string {
+	// A console command is not a desktop; it sets up anything else itself
+	if s.Type == "TTY" {
+		return []string{"XDG_SESSION_TYPE=tty"}
+	}
+
+	sessionType := "wayland"
+	if s.Type == "X11" {
+		sessionType = "x11"
+	}
+
+	currentDesktop := strings.Join(s.DesktopNames, ":")
+	if currentDesktop == "" {
+		currentDesktop = s.Name
+	}
+
+	id := s.ID()
+	return []string{
+		"XDG_SESSION_TYPE=" + sessionType,
+		"XDG_CURRENT_DESKTOP=" + currentDesktop,
+		"XDG_SESSION_DESKTOP=" + id,
+		"DESKTOP_SESSION=" + id,
+	}
+}
+
+// DataDirs returns the base directories searched for session files:
+// $XDG_DATA_DIRS (default /usr/local/share:/usr/share) plus the NixOS system
+// profile, which greetd's environment does not always include
+func DataDirs() []string {
+	dirs := filepath.SplitList(os.Getenv("XDG_DATA_DIRS"))
+	if len(dirs) == 0 {
+		dirs = []string{"/usr/local/share", "/usr/share"}
+	}
+	for _, dir := range dirs {
+		if filepath.Clean(dir) == nixSystemShare {
+			return dirs
+		}
+	}
+	return append(dirs, nixSystemShare)
+}
+
+const nixSystemShare = "/run/current-system/sw/share"
+
+// LoadSessions returns the sessions installed in the XDG data directories
 func LoadSessions() ([]Session, error) {
+	return LoadSessionsFrom(DataDirs(), CurrentLocale())
+}
+
+// LoadSessionsFrom reads xsessions/ and wayland-sessions/ below each data directory
+// Entries that are hidden, not meant to be shown, or whose TryExec binary is
+// missing are skipped, as are files that fail to parse.
+// Each desktop file ID is loaded once: the file in the earliest data directory
+// wins, and a Hidden=true file there also masks copies in later directories
+func LoadSessionsFrom(dataDirs []string, locale string) ([]Session, error) {
+	var sessions []Session
+	seen := make(map[string]bool)
+
+	err := walkSessionFiles(dataDirs, func(path, id, sessionType string) {
+		key := sessionType + ":" + id
+		if seen[key] {
+			return
+		}
+
+		session, ok, err := loadSessionFile(path, sessionType, locale)
+		if err != nil {
+			// A broken override does not mask a working copy further down
+			return
+		}
+		seen[key] = true
+		if !ok {
+			return
+		}
+		session.FileID = id
+		sessions = append(sessions, session)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// CheckSessionsFrom loads every session file below dataDirs, including the
+// copies LoadSessionsFrom never reaches because an earlier one wins
+// It returns the sessions the files describe and an error for each file that
+// fails to load: a *FileError, or the *fs.PathError when it cannot be read
+func CheckSessionsFrom(dataDirs []string, locale string) ([]Session, []error) {
 	var sessions []Session
+	var errs []error
 
-	// Default paths
-	paths := []string{
-		"/usr/share/xsessions",
-		"/usr/share/wayland-sessions",
-		"/run/current-system/sw/share/xsessions",
-		"/run/current-system/sw/share/wayland-sessions",
+	err := walkSessionFiles(dataDirs, func(path, id, sessionType string) {
+		session, ok, err := loadSessionFile(path, sessionType, locale)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		if ok {
+			session.FileID = id
+			sessions = append(sessions, session)
+		}
+	})
+	if err != nil {
+		errs = append(errs, err)
 	}
+	return sessions, errs
+}
 
-	for _, basePath := range paths {
-		err := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return nil // Skip errors
-			}
-			if !strings.HasSuffix(path, ".desktop") {
+// walkSessionFiles calls fn for each desktop file in xsessions/ and
+// wayland-sessions/ below the data directories, in search order
+func walkSessionFiles(dataDirs []string, fn func(path, id, sessionType string)) error {
+	for _, dataDir := range dataDirs {
+		for _, sub := range []struct {
+			dir         string
+			sessionType string
+		}{
+			{"xsessions", "X11"},
+			{"wayland-sessions", "Wayland"},
+		} {
+			basePath := filepath.Join(dataDir, sub.dir)
+			err := filepath.WalkDir(basePath, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return nil // Skip missing directories and unreadable entries
+				}
+				if d.IsDir() || !strings.HasSuffix(path, ".desktop") {
+					return nil
+				}
+				fn(path, fileID(basePath, path), sub.sessionType)
 				return nil
-			}
-
-			session, err := parseDesktopFile(path)
+			})
 			if err != nil {
-				return nil
+				return err
 			}
-			sessions = append(sessions, session)
-			return nil
-		})
-		if err != nil {
-			return nil, err
 		}
 	}
+	return nil
+}
 
-	return sessions, nil
+// fileID returns the desktop file ID of path below basePath
+// Subdirectories become "-" separated prefixes, as the spec defines
+func fileID(basePath, path string) string {
+	rel, err := filepath.Rel(basePath, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	return strings.TrimSuffix(strings.ReplaceAll(filepath.ToSlash(rel), "/", "-"), ".desktop")
 }
 
-func parseDesktopFile(path string) (Session, error) {
+// FileError is a session file that failed to load
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string { return e.Path + ": " + e.Err.Error() }
+
+func (e *FileError) Unwrap() error { return e.Err }
+
+// loadSessionFile parses one session desktop file
+// ok is false for entries that should not be offered (Hidden, NoDisplay, missing TryExec)
+func loadSessionFile(path, sessionType, locale string) (Session, bool, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return Session{}, err
+		return Session{}, false, err
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	var name, exec, sessionType string
+	df, err := ParseDesktopFile(file)
+	if err != nil {
+		return Session{}, false, &FileError{path, err}
+	}
+	return sessionFromDesktopFile(df, path, sessionType, locale)
+}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(line, "Name=") {
-			name = strings.TrimPrefix(line, "Name=")
-		} else if strings.HasPrefix(line, "Exec=") {
-			exec = strings.TrimPrefix(line, "Exec=")
+// sessionFromDesktopFile builds a Session from a parsed desktop file
+func sessionFromDesktopFile(df *DesktopFile, path, sessionType, locale string) (Session, bool, error) {
+	if _, ok := df.Groups[DesktopEntryGroup]; !ok {
+		return Session{}, false, &FileError{path, fmt.Errorf("missing [%s] group", DesktopEntryGroup)}
+	}
+	switch df.Text("Type") {
+	case "", "Application", "XSession":
+	default:
+		return Session{}, false, nil
+	}
+	if df.Bool("Hidden") || df.Bool("NoDisplay") {
+		return Session{}, false, nil
+	}
+	if tryExec := df.Text("TryExec"); tryExec != "" {
+		if _, err := exec.LookPath(tryExec); err != nil {
+			return Session{}, false, nil
 		}
 	}
 
-	if strings.Contains(path, "xsessions") {
-		sessionType = "X11"
-	} else {
-		sessionType = "Wayland"
+	name := df.LocaleString("Name", locale)
+	execLine := df.Text("Exec")
+	if name == "" || execLine == "" {
+		return Session{}, false, &FileError{path, errors.New("Name and Exec are required")}
+	}
+	command, err := ParseExec(execLine, ExecContext{Name: name, Icon: df.Text("Icon"), Path: path})
+	if err != nil {
+		return Session{}, false, &FileError{path, err}
+	}
+	if len(command) == 0 {
+		return Session{}, false, &FileError{path, errors.New("empty Exec")}
 	}
 
 	return Session{
-		Name: name,
-		Exec: exec,
-		Type: sessionType,
-		Path: path,
-	}, nil
+		Name:         name,
+		Exec:         execLine,
+		Type:         sessionType,
+		Path:         path,
+		DesktopNames: df.Strings("DesktopNames"),
+		Comment:      df.LocaleString("Comment", locale),
+		Command:      command,
+	}, true, nil
 }
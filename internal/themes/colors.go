@@ -8,9 +8,12 @@ import (
 	"strings"
 
 	"github.com/BurntSushi/toml"
+	"github.com/Nomadcxx/sysc-greet/internal/logging"
 	"github.com/charmbracelet/lipgloss/v2"
 )
 
+var logger = logging.For("themes")
+
 // CustomThemes holds loaded custom theme configurations
 var CustomThemes = make(map[string]ThemeColors)
 
@@ -361,13 +364,15 @@ func ScanCustomThemes(dirs []string) []string {
 
 		files, err := filepath.Glob(filepath.Join(dir, "*.toml"))
 		if err != nil {
+			logger.Warn("Failed to list custom themes", "dir", dir, "err", err)
 			continue
 		}
 
 		for _, f := range files {
 			theme, err := loadCustomTheme(f)
 			if err != nil {
-				// Silently skip invalid theme files
+				// Skip invalid theme files, the greeter falls back to built-in themes
+				logger.Warn("Skipping invalid custom theme", "path", f, "err", err)
 				continue
 			}
 
@@ -379,6 +384,7 @@ func ScanCustomThemes(dirs []string) []string {
 			if _, exists := CustomThemes[strings.ToLower(name)]; exists {
 				// Theme with this name already loaded, later one wins (no error, just note)
 				// This allows user themes to override system themes intentionally
+				logger.Debug("Custom theme overrides an earlier one", "theme", name, "path", f)
 			}
 			CustomThemes[strings.ToLower(name)] = theme
 			names = append(names, name)
@@ -387,6 +393,75 @@ func ScanCustomThemes(dirs []string) []string {
 	return names
 }
 
+// FieldError is a color in a custom theme file that is missing or invalid
+type FieldError struct {
+	Field string // Key below [colors]
+	Value string // Empty when the key is missing
+}
+
+func (e *FieldError) Error() string {
+	if strings.TrimSpace(e.Value) == "" {
+		return fmt.Sprintf("missing required field: %s", e.Field)
+	}
+	return fmt.Sprintf("%s: invalid hex color %q (expected #rrggbb)", e.Field, e.Value)
+}
+
+// ValidHexColor reports whether s is a #rrggbb color
+// Palettes are interpolated from theme colors, which only works with this form
+func ValidHexColor(s string) bool {
+	if len(s) != 7 || s[0] != '#' {
+		return false
+	}
+	for _, c := range s[1:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckCustomTheme loads a custom theme file the way ScanCustomThemes does and
+// also returns the keys set in it that no theme setting uses and the colors that
+// are not #rrggbb. The greeter still loads a theme with such colors
+func CheckCustomTheme(path string) (theme ThemeColors, unknown []string, invalid []FieldError, err error) {
+	theme, err = loadCustomTheme(path)
+	if err != nil {
+		return ThemeColors{}, nil, nil, err
+	}
+	var config CustomThemeConfig
+	md, err := toml.DecodeFile(path, &config)
+	if err != nil {
+		return ThemeColors{}, nil, nil, err
+	}
+	for _, key := range md.Undecoded() {
+		unknown = append(unknown, key.String())
+	}
+	for _, f := range requiredColors(config) {
+		if !ValidHexColor(f.Value) {
+			invalid = append(invalid, f)
+		}
+	}
+	return theme, unknown, invalid, nil
+}
+
+// requiredColors returns the colors every custom theme must set, in file order
+func requiredColors(config CustomThemeConfig) []FieldError {
+	c := config.Colors
+	return []FieldError{
+		{"bg_base", c.BgBase},
+		{"bg_active", c.BgActive},
+		{"primary", c.Primary},
+		{"secondary", c.Secondary},
+		{"accent", c.Accent},
+		{"warning", c.Warning},
+		{"danger", c.Danger},
+		{"fg_primary", c.FgPrimary},
+		{"fg_secondary", c.FgSecondary},
+		{"fg_muted", c.FgMuted},
+		{"border_focus", c.BorderFocus},
+	}
+}
+
 // loadCustomTheme loads a single custom theme from a TOML file
 func loadCustomTheme(path string) (ThemeColors, error) {
 	var config CustomThemeConfig
@@ -394,24 +469,12 @@ func loadCustomTheme(path string) (ThemeColors, error) {
 		return ThemeColors{}, err
 	}
 
-	// Validate required color fields are non-empty
-	requiredFields := map[string]string{
-		"bg_base":      config.Colors.BgBase,
-		"bg_active":    config.Colors.BgActive,
-		"primary":      config.Colors.Primary,
-		"secondary":    config.Colors.Secondary,
-		"accent":       config.Colors.Accent,
-		"warning":      config.Colors.Warning,
-		"danger":       config.Colors.Danger,
-		"fg_primary":   config.Colors.FgPrimary,
-		"fg_secondary": config.Colors.FgSecondary,
-		"fg_muted":     config.Colors.FgMuted,
-		"border_focus": config.Colors.BorderFocus,
-	}
-
-	for field, value := range requiredFields {
-		if strings.TrimSpace(value) == "" {
-			return ThemeColors{}, fmt.Errorf("missing required field: %s", field)
+	// Validate required color fields
+	// CHANGED 2026-10-16 - Check in a fixed order; the format is only checked by
+	// CheckCustomTheme so that themes loading before keep loading
+	for _, f := range requiredColors(config) {
+		if strings.TrimSpace(f.Value) == "" {
+			return ThemeColors{}, &FieldError{Field: f.Field}
 		}
 	}
 
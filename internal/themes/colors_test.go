@@ -0,0 +1,66 @@
+package themes
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidHexColor(t *testing.T) {
+	tests := map[string]bool{
+		"#89b4fa":  true,
+		"#89B4FA":  true,
+		"89b4fa":   false,
+		"#fff":     false,
+		"#89b4fz":  false,
+		"#89b4fa ": false,
+		"":         false,
+	}
+	for color, want := range tests {
+		if got := ValidHexColor(color); got != want {
+			t.Errorf("ValidHexColor(%q) = %v, want %v", color, got, want)
+		}
+	}
+}
+
+func TestCheckCustomTheme(t *testing.T) {
+	example, err := os.ReadFile("../../examples/themes/example.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	theme, unknown, invalid, err := CheckCustomTheme(write("extra.toml", string(example)+"\nauthor = \"me\"\n"))
+	if err != nil || theme.Name != "Example" || len(invalid) != 0 {
+		t.Fatalf("expected the example theme to load, got %q, %v, %v", theme.Name, invalid, err)
+	}
+	if !reflect.DeepEqual(unknown, []string{"colors.author"}) {
+		t.Fatalf("expected the unknown key to be reported, got %v", unknown)
+	}
+
+	// A color lipgloss accepts but palettes cannot use is reported, not refused
+	bad := write("bad.toml", strings.Replace(string(example), `"#e94560"`, `"e94560"`, 1))
+	_, _, invalid, err = CheckCustomTheme(bad)
+	if err != nil || !reflect.DeepEqual(invalid, []FieldError{{Field: "primary", Value: "e94560"}}) {
+		t.Fatalf("expected primary to be reported, got %v, %v", invalid, err)
+	}
+	if theme, err := loadCustomTheme(bad); err != nil || theme.Name != "Example" {
+		t.Fatalf("expected the greeter to still load the theme, got %v", err)
+	}
+
+	_, _, _, err = CheckCustomTheme(write("missing.toml", strings.Replace(string(example), "danger =", "# danger =", 1)))
+	var fe *FieldError
+	if !errors.As(err, &fe) || err.Error() != "missing required field: danger" {
+		t.Fatalf("expected the missing color to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,232 @@
+// Package users lists the accounts offered by the greeter's user picker
+package users
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default locations of the account database and its UID range
+const (
+	DefaultPasswdPath    = "/etc/passwd"
+	DefaultLoginDefsPath = "/etc/login.defs"
+)
+
+// User is a login account
+type User struct {
+	Name     string
+	UID      int
+	GID      int    // Primary group
+	RealName string // First GECOS field, e.g. "Alice Liddell"
+	Home     string
+	Shell    string
+}
+
+// DisplayName returns "Real Name (name)", or just the name without a real name
+func (u User) DisplayName() string {
+	if u.RealName == "" || u.RealName == u.Name {
+		return u.Name
+	}
+	return u.RealName + " (" + u.Name + ")"
+}
+
+// Source provides the accounts to choose from
+// FileSource reads /etc/passwd; other sources (e.g. a static list or a
+// directory service) only need to implement Users
+type Source interface {
+	Users() ([]User, error)
+}
+
+// Filter selects which accounts are offered
+type Filter struct {
+	UIDMin int
+	UIDMax int
+	Hide   []string // Usernames never listed
+}
+
+// FileSource reads accounts from a passwd(5) file, filtered by UID range and shell
+type FileSource struct {
+	PasswdPath    string // Defaults to /etc/passwd
+	LoginDefsPath string // UID_MIN/UID_MAX, defaults to /etc/login.defs
+	Hide          []string
+}
+
+// Users returns the login accounts, sorted by display name
+func (s FileSource) Users() ([]User, error) {
+	passwdPath := s.PasswdPath
+	if passwdPath == "" {
+		passwdPath = DefaultPasswdPath
+	}
+	loginDefsPath := s.LoginDefsPath
+	if loginDefsPath == "" {
+		loginDefsPath = DefaultLoginDefsPath
+	}
+
+	f, err := os.Open(passwdPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", passwdPath, err)
+	}
+	defer f.Close()
+
+	all, err := ParsePasswd(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", passwdPath, err)
+	}
+
+	filter := LoadUIDRange(loginDefsPath)
+	filter.Hide = s.Hide
+	return filter.Apply(all), nil
+}
+
+// ParsePasswd parses passwd(5) lines: name:password:UID:GID:GECOS:home:shell
+// Comments, blank lines and NIS "+"/"-" entries are skipped
+func ParsePasswd(r io.Reader) ([]User, error) {
+	return parsePasswd(r, nil)
+}
+
+// ParsePasswdLenient parses passwd(5) lines like ParsePasswd, but skips malformed
+// lines instead of failing; each one is passed to skipped
+func ParsePasswdLenient(r io.Reader, skipped func(error)) ([]User, error) {
+	return parsePasswd(r, skipped)
+}
+
+// parsePasswd stops at the first malformed line when skipped is nil
+func parsePasswd(r io.Reader, skipped func(error)) ([]User, error) {
+	var users []User
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			continue
+		}
+
+		u, err := parsePasswdLine(line)
+		if err != nil {
+			err = fmt.Errorf("line %d: %w", lineNo, err)
+			if skipped == nil {
+				return nil, err
+			}
+			skipped(err)
+			continue
+		}
+		users = append(users, u)
+	}
+	return users, scanner.Err()
+}
+
+// parsePasswdLine parses one passwd(5) entry
+func parsePasswdLine(line string) (User, error) {
+	fields := strings.Split(line, ":")
+	if len(fields) != 7 {
+		return User{}, fmt.Errorf("expected 7 fields, got %d", len(fields))
+	}
+	uid, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return User{}, fmt.Errorf("invalid UID %q", fields[2])
+	}
+	gid, err := strconv.Atoi(fields[3])
+	if err != nil {
+		return User{}, fmt.Errorf("invalid GID %q", fields[3])
+	}
+
+	realName, _, _ := strings.Cut(fields[4], ",")
+	return User{
+		Name:     fields[0],
+		UID:      uid,
+		GID:      gid,
+		RealName: strings.TrimSpace(realName),
+		Home:     fields[5],
+		Shell:    fields[6],
+	}, nil
+}
+
+// LoadUIDRange reads UID_MIN and UID_MAX from a login.defs file
+// Missing files or keys fall back to the shadow-utils defaults 1000 and 60000
+func LoadUIDRange(path string) Filter {
+	filter := Filter{UIDMin: 1000, UIDMax: 60000}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return filter
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		value, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		switch fields[0] {
+		case "UID_MIN":
+			filter.UIDMin = value
+		case "UID_MAX":
+			filter.UIDMax = value
+		}
+	}
+	return filter
+}
+
+// Apply returns the users inside the UID range with a login shell that are not hidden,
+// sorted by display name
+func (f Filter) Apply(all []User) []User {
+	hidden := make(map[string]bool, len(f.Hide))
+	for _, name := range f.Hide {
+		hidden[name] = true
+	}
+
+	var users []User
+	for _, u := range all {
+		if u.UID < f.UIDMin || u.UID > f.UIDMax || hidden[u.Name] || !HasLoginShell(u) {
+			continue
+		}
+		users = append(users, u)
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		return strings.ToLower(users[i].DisplayName()) < strings.ToLower(users[j].DisplayName())
+	})
+	return users
+}
+
+// HasLoginShell reports whether u can log in interactively
+// An empty shell means /bin/sh; nologin and false reject logins
+func HasLoginShell(u User) bool {
+	switch filepath.Base(u.Shell) {
+	case "nologin", "false":
+		return false
+	}
+	return true
+}
+
+// Match returns the users whose name or real name starts with prefix (case-insensitive)
+// A user whose name is exactly prefix comes first
+func Match(users []User, prefix string) []User {
+	if prefix == "" {
+		return users
+	}
+	lower := strings.ToLower(prefix)
+
+	var matched []User
+	for _, u := range users {
+		switch {
+		case u.Name == prefix:
+			matched = append([]User{u}, matched...)
+		case strings.HasPrefix(strings.ToLower(u.Name), lower) || strings.HasPrefix(strings.ToLower(u.RealName), lower):
+			matched = append(matched, u)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,117 @@
+package users
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func names(list []User) []string {
+	var out []string
+	for _, u := range list {
+		out = append(out, u.Name)
+	}
+	return out
+}
+
+func TestFileSource(t *testing.T) {
+	src := FileSource{
+		PasswdPath:    "testdata/passwd",
+		LoginDefsPath: "testdata/login.defs",
+		Hide:          []string{"guest"},
+	}
+	list, err := src.Users()
+	if err != nil {
+		t.Fatalf("Users: %v", err)
+	}
+
+	// Sorted by display name; system, nologin/false and hidden accounts are excluded
+	want := []string{"alice", "bob", "dave"}
+	if got := names(list); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Users() = %v, want %v", got, want)
+	}
+	if list[0].DisplayName() != "Alice Liddell (alice)" {
+		t.Fatalf("unexpected display name %q", list[0].DisplayName())
+	}
+	if list[1].DisplayName() != "bob" {
+		t.Fatalf("unexpected display name %q", list[1].DisplayName())
+	}
+}
+
+func TestLoadUIDRange(t *testing.T) {
+	tests := []struct {
+		path string
+		want Filter
+	}{
+		{"testdata/login.defs", Filter{UIDMin: 1000, UIDMax: 60000}},
+		{"testdata/missing", Filter{UIDMin: 1000, UIDMax: 60000}},
+	}
+	for _, tt := range tests {
+		if got := LoadUIDRange(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LoadUIDRange(%s) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParsePasswdErrors(t *testing.T) {
+	tests := []string{
+		"alice:x:1000:1000:Alice:/home/alice\n",
+		"alice:x:abc:1000:Alice:/home/alice:/bin/sh\n",
+	}
+	for _, input := range tests {
+		if _, err := ParsePasswd(strings.NewReader(input)); err == nil {
+			t.Errorf("ParsePasswd(%q): expected an error", input)
+		}
+	}
+}
+
+func TestParsePasswdLenient(t *testing.T) {
+	input := "alice:x:1000:1000:Alice:/home/alice\nbob:x:1001:1001:Bob:/home/bob:/bin/sh\ncarol:x:abc:1000::/home/carol:/bin/sh\n"
+	var skipped []string
+	all, err := ParsePasswdLenient(strings.NewReader(input), func(err error) { skipped = append(skipped, err.Error()) })
+	if err != nil || len(all) != 1 || all[0].Name != "bob" {
+		t.Fatalf("expected only bob, got %+v, %v", all, err)
+	}
+	want := []string{"line 1: expected 7 fields, got 6", `line 3: invalid UID "abc"`}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Fatalf("skipped = %q, want %q", skipped, want)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	all := []User{
+		{Name: "root", UID: 0, Shell: "/bin/bash"},
+		{Name: "zed", UID: 2000, Shell: "/bin/bash"},
+		{Name: "amy", UID: 2001, Shell: "/sbin/nologin"},
+		{Name: "ben", UID: 2002, Shell: "/bin/fish"},
+		{Name: "high", UID: 70000, Shell: "/bin/bash"},
+	}
+	got := Filter{UIDMin: 1000, UIDMax: 60000}.Apply(all)
+	if want := []string{"ben", "zed"}; !reflect.DeepEqual(names(got), want) {
+		t.Fatalf("Apply() = %v, want %v", names(got), want)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	list := []User{
+		{Name: "al", RealName: "Al Bundy"},
+		{Name: "alice", RealName: "Alice Liddell"},
+		{Name: "bob", RealName: "Robert"},
+	}
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{"al", "alice", "bob"}},
+		{"ALI", []string{"alice"}},
+		{"rob", []string{"bob"}},
+		{"alice", []string{"alice"}},
+		{"al", []string{"al", "alice"}},
+		{"x", nil},
+	}
+	for _, tt := range tests {
+		if got := names(Match(list, tt.prefix)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Match(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -7,8 +7,12 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/Nomadcxx/sysc-greet/internal/logging"
 )
 
+var logger = logging.For("wallpaper")
+
 // GSlapperSocket is the path to the greeter's gSlapper IPC socket
 const GSlapperSocket = "/tmp/sysc-greet-wallpaper.sock"
 
@@ -42,7 +46,9 @@ func SendCommand(cmd string) (string, error) {
 		return "", fmt.Errorf("failed to read response: %w", err)
 	}
 
-	return strings.TrimSpace(string(buf[:n])), nil
+	reply := strings.TrimSpace(string(buf[:n]))
+	logger.Debug("gSlapper command", "command", cmd, "reply", reply)
+	return reply, nil
 }
 
 // isOKResponse checks if a gSlapper response indicates success
@@ -65,9 +71,11 @@ func ChangeWallpaper(path string) error {
 	// Set fade transition (best effort - don't fail if these don't work)
 	if _, err := SendCommand("set-transition fade"); err != nil {
 		// Log but continue - transition settings are optional
+		logger.Debug("Failed to set transition", "err", err)
 	}
 	if _, err := SendCommand("set-transition-duration 0.5"); err != nil {
 		// Log but continue - transition settings are optional
+		logger.Debug("Failed to set transition duration", "err", err)
 	}
 
 	// Change wallpaper
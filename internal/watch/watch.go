@@ -0,0 +1,174 @@
+// Package watch reports changes to files in a set of directories, using inotify
+// Changes come in batches: an editor saving a file, or a package manager
+// replacing a directory, produces one batch rather than an event per syscall
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Settle is how long a batch stays open for more changes after the last one
+const Settle = 150 * time.Millisecond
+
+// ErrClosed is returned by Next once the watcher is closed
+var ErrClosed = errors.New("watcher closed")
+
+// Events that mean a file now has new contents, or is gone
+const mask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_MOVED_FROM | unix.IN_CREATE | unix.IN_DELETE |
+	unix.IN_DELETE_SELF | unix.IN_MOVE_SELF | unix.IN_ONLYDIR
+
+// Watcher watches the files directly in a set of directories
+// A nil *Watcher is closed and never reports changes
+type Watcher struct {
+	fd     int            // inotify instance
+	wake   [2]int         // Pipe written by Close to interrupt Next
+	dirs   map[int]string // Watch descriptor to directory
+	wanted map[string]bool
+	closed atomic.Bool
+}
+
+// New watches dirs. Directories that do not exist yet are picked up when they
+// are created inside a directory that is watched (config.d in /etc/sysc-greet)
+func New(dirs []string) (*Watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+	w := &Watcher{fd: fd, dirs: make(map[int]string), wanted: make(map[string]bool)}
+	if err := unix.Pipe2(w.wake[:], unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("pipe: %w", err)
+	}
+
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		w.wanted[dir] = true
+		if err := w.add(dir); err != nil && !errors.Is(err, unix.ENOENT) && !errors.Is(err, unix.ENOTDIR) {
+			w.Close()
+			return nil, fmt.Errorf("watch %s: %w", dir, err)
+		}
+	}
+	return w, nil
+}
+
+func (w *Watcher) add(dir string) error {
+	wd, err := unix.InotifyAddWatch(w.fd, dir, mask)
+	if err != nil {
+		return err
+	}
+	w.dirs[wd] = dir
+	return nil
+}
+
+// Next blocks until files change and returns their paths, sorted and without
+// duplicates. A directory that was created, removed or overflowed the event
+// queue is reported as the directory itself
+func (w *Watcher) Next() ([]string, error) {
+	if w == nil {
+		return nil, ErrClosed
+	}
+	var changed []string
+	timeout := -1 // Block until the first change
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+
+	for !w.closed.Load() {
+		fds := []unix.PollFd{{Fd: int32(w.fd), Events: unix.POLLIN}, {Fd: int32(w.wake[0]), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, timeout)
+		if w.closed.Load() {
+			return nil, ErrClosed
+		}
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("poll: %w", err)
+		}
+		if n == 0 {
+			slices.Sort(changed)
+			return slices.Compact(changed), nil
+		}
+
+		for {
+			n, err := unix.Read(w.fd, buf)
+			if errors.Is(err, unix.EAGAIN) {
+				break
+			}
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("read inotify events: %w", err)
+			}
+			changed = w.parse(buf[:n], changed)
+		}
+		if len(changed) > 0 {
+			timeout = int(Settle / time.Millisecond)
+		}
+	}
+	return nil, ErrClosed
+}
+
+// parse appends the paths named by the events in buf to changed
+func (w *Watcher) parse(buf []byte, changed []string) []string {
+	for off := 0; off+unix.SizeofInotifyEvent <= len(buf); {
+		ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+		nameBytes := buf[off+unix.SizeofInotifyEvent : off+unix.SizeofInotifyEvent+int(ev.Len)]
+		off += unix.SizeofInotifyEvent + int(ev.Len)
+
+		if ev.Mask&unix.IN_Q_OVERFLOW != 0 {
+			// Events were lost, everything may have changed
+			for _, dir := range w.dirs {
+				changed = append(changed, dir)
+			}
+			continue
+		}
+		dir, ok := w.dirs[int(ev.Wd)]
+		if !ok {
+			continue
+		}
+		if ev.Mask&unix.IN_IGNORED != 0 {
+			// The directory itself was removed
+			delete(w.dirs, int(ev.Wd))
+			changed = append(changed, dir)
+			continue
+		}
+		if ev.Mask&(unix.IN_DELETE_SELF|unix.IN_MOVE_SELF) != 0 {
+			changed = append(changed, dir)
+			continue
+		}
+
+		name := string(nameBytes)
+		if i := slices.Index(nameBytes, 0); i >= 0 {
+			name = string(nameBytes[:i])
+		}
+		path := filepath.Join(dir, name)
+		if ev.Mask&unix.IN_ISDIR != 0 {
+			// Only directories asked for matter, and only once they exist
+			if w.wanted[path] && ev.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 && w.add(path) == nil {
+				changed = append(changed, path)
+			}
+			continue
+		}
+		changed = append(changed, path)
+	}
+	return changed
+}
+
+// Close stops watching and makes a pending Next return ErrClosed
+func (w *Watcher) Close() error {
+	if w == nil || w.closed.Swap(true) {
+		return nil
+	}
+	unix.Write(w.wake[1], []byte{0})
+	unix.Close(w.wake[0])
+	unix.Close(w.wake[1])
+	return unix.Close(w.fd)
+}
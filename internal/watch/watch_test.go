@@ -0,0 +1,103 @@
+package watch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// next runs w.Next with a deadline
+func next(t *testing.T, w *Watcher) []string {
+	t.Helper()
+	type result struct {
+		paths []string
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		paths, err := w.Next()
+		done <- result{paths, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("Next: %v", r.err)
+		}
+		return r.paths
+	case <-time.After(5 * time.Second):
+		t.Fatal("Next did not return")
+		return nil
+	}
+}
+
+func TestWatcherBatchesChanges(t *testing.T) {
+	dir := t.TempDir()
+	w, err := New([]string{dir, filepath.Join(dir, "config.d"), filepath.Join(dir, "missing", "themes")})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer w.Close()
+
+	// An editor writing a temporary file and renaming it over the original
+	os.WriteFile(filepath.Join(dir, "config.toml"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(dir, ".config.toml.swp"), []byte("b"), 0644)
+	os.Rename(filepath.Join(dir, ".config.toml.swp"), filepath.Join(dir, "config.toml"))
+	os.WriteFile(filepath.Join(dir, "screensaver.conf"), []byte("c"), 0644)
+
+	want := []string{
+		filepath.Join(dir, ".config.toml.swp"),
+		filepath.Join(dir, "config.toml"),
+		filepath.Join(dir, "screensaver.conf"),
+	}
+	if got := next(t, w); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// A wanted directory created later is watched from then on
+	os.Mkdir(filepath.Join(dir, "config.d"), 0755)
+	if got := next(t, w); !reflect.DeepEqual(got, []string{filepath.Join(dir, "config.d")}) {
+		t.Fatalf("expected the new directory to be reported, got %v", got)
+	}
+	os.WriteFile(filepath.Join(dir, "config.d", "10-site.toml"), []byte("d"), 0644)
+	if got := next(t, w); !reflect.DeepEqual(got, []string{filepath.Join(dir, "config.d", "10-site.toml")}) {
+		t.Fatalf("expected the drop-in to be reported, got %v", got)
+	}
+
+	// Other directories are not
+	os.Mkdir(filepath.Join(dir, "other"), 0755)
+	os.Remove(filepath.Join(dir, "screensaver.conf"))
+	if got := next(t, w); !reflect.DeepEqual(got, []string{filepath.Join(dir, "screensaver.conf")}) {
+		t.Fatalf("expected only the removed file, got %v", got)
+	}
+}
+
+func TestWatcherClose(t *testing.T) {
+	w, err := New([]string{t.TempDir()})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := w.Next()
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	w.Close()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not interrupt Next")
+	}
+
+	var nilWatcher *Watcher
+	if _, err := nilWatcher.Next(); !errors.Is(err, ErrClosed) || nilWatcher.Close() != nil {
+		t.Fatal("expected a nil watcher to be closed")
+	}
+}